@@ -1,23 +1,141 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/millbj92/nuboverflow-users/internal/autocomplete"
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"github.com/millbj92/nuboverflow-users/internal/cache"
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/github"
 	"github.com/millbj92/nuboverflow-users/internal/repository"
 	"github.com/millbj92/nuboverflow-users/internal/transport/http"
+	usr "github.com/millbj92/nuboverflow-users/internal/user"
 	user "github.com/millbj92/nuboverflow-users/internal/user/service"
 )
 
 func Run() error {
-	userStore, err := repository.New()
+	providerRules, err := boolFromEnv("EMAIL_PROVIDER_RULES", false)
 	if err != nil {
 		return err
 	}
+	userStore, err := repository.New(repository.WithEmailRules(canonical.EmailRules{ProviderRules: providerRules}))
+	if err != nil {
+		return err
+	}
+
+	cacheTTL, err := durationFromEnv("CACHE_TTL", cache.DefaultTTL)
+	if err != nil {
+		return err
+	}
+	// Redis expires keys by the millisecond, and rejects a TTL of 0.
+	if cacheTTL < time.Millisecond {
+		return fmt.Errorf("invalid CACHE_TTL: %s is less than a millisecond", cacheTTL)
+	}
+	cacheSize, err := intFromEnv("CACHE_SIZE", 10000)
+	if err != nil {
+		return err
+	}
+	lru, err := cache.NewLRU(cacheSize)
+	if err != nil {
+		return fmt.Errorf("invalid CACHE_SIZE: %w", err)
+	}
+	var cacheBackend cache.Backend = lru
+	var autocompleteOptions []autocomplete.Option
+	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
+		redis := cache.NewRedis(addr, 16)
+		defer redis.Close()
+		// Other instances invalidate through Redis only, so keep local copies briefly.
+		cacheBackend = cache.Tiered(cacheBackend, redis, 5*time.Second)
+
+		// Nor does the autocomplete index see their writes, so rebuild it.
+		refresh, err := durationFromEnv("AUTOCOMPLETE_REFRESH_INTERVAL", time.Minute)
+		if err != nil {
+			return err
+		}
+		if refresh <= 0 {
+			return fmt.Errorf("invalid AUTOCOMPLETE_REFRESH_INTERVAL: %s is not positive", refresh)
+		}
+		autocompleteOptions = append(autocompleteOptions, autocomplete.WithRefresh(refresh))
+	}
+	cacheMetrics := &cache.Metrics{}
+	userStore = cache.NewStore(userStore, cacheBackend, cache.WithTTL(cacheTTL), cache.WithMetrics(cacheMetrics))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	userStore, err = autocomplete.NewStore(ctx, userStore, autocompleteOptions...)
+	if err != nil {
+		return err
+	}
+
+	restoreWindow, err := durationFromEnv("USER_RESTORE_WINDOW", user.DefaultRestoreWindow)
+	if err != nil {
+		return err
+	}
+	purgeInterval, err := durationFromEnv("USER_PURGE_INTERVAL", time.Hour)
+	if err != nil {
+		return err
+	}
+	if purgeInterval <= 0 {
+		return fmt.Errorf("invalid USER_PURGE_INTERVAL: %s is not positive", purgeInterval)
+	}
+	renameCooldown, err := durationFromEnv("USERNAME_CHANGE_COOLDOWN", user.DefaultRenameCooldown)
+	if err != nil {
+		return err
+	}
+	userNameReservation, err := durationFromEnv("USERNAME_RESERVATION", user.DefaultUserNameReservation)
+	if err != nil {
+		return err
+	}
+
+	privileges := usr.DefaultPrivileges
+	if raw := os.Getenv("PRIVILEGES"); raw != "" {
+		if privileges, err = usr.ParsePrivileges(raw); err != nil {
+			return fmt.Errorf("invalid PRIVILEGES: %w", err)
+		}
+	}
+
+	timeouts := http.DefaultTimeouts()
+	timeouts.Default, err = durationFromEnv("REQUEST_TIMEOUT", timeouts.Default)
+	if err != nil {
+		return err
+	}
+
+	avatarDir := os.Getenv("AVATAR_DIR")
+	if avatarDir == "" {
+		avatarDir = "avatars"
+	}
+	avatarStore, err := avatar.NewLocalStore(avatarDir)
+	if err != nil {
+		return fmt.Errorf("invalid AVATAR_DIR: %w", err)
+	}
+
+	var githubOptions []github.Option
+	if url := os.Getenv("GITHUB_API_URL"); url != "" {
+		githubOptions = append(githubOptions, github.WithBaseURL(url))
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		githubOptions = append(githubOptions, github.WithToken(token))
+	}
 
+	userService := user.NewService(userStore,
+		user.WithRestoreWindow(restoreWindow),
+		user.WithRenameCooldown(renameCooldown),
+		user.WithUserNameReservation(userNameReservation),
+		user.WithPrivileges(privileges),
+		user.WithAvatarStore(avatarStore),
+		user.WithGithubVerifier(github.NewVerifier(nil, githubOptions...)),
+	)
+	go user.RunPurgeJob(ctx, userService, purgeInterval)
 
-	userService := user.NewService(userStore)
-	app := http.CreateRoutes(userService, validator.New())
+	app := http.CreateRoutes(userService, validator.New(), http.WithTimeouts(timeouts), http.WithCacheMetrics(cacheMetrics))
 	if err != nil {
 		return err
 	}
@@ -29,7 +147,44 @@ func Run() error {
 	return nil
 }
 
+// durationFromEnv parses the environment variable key as a time.Duration, returning def when it is unset.
+func durationFromEnv(key string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return d, nil
+}
 
+// intFromEnv parses the environment variable key as an int, returning def when it is unset.
+func intFromEnv(key string, def int) (int, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	i, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return i, nil
+}
+
+// boolFromEnv parses the environment variable key as a bool, returning def when it is unset.
+func boolFromEnv(key string, def bool) (bool, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
 
 func main() {
 	if err := Run(); err != nil {
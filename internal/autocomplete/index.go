@@ -0,0 +1,280 @@
+package autocomplete
+
+import (
+	"sync"
+
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// MaxSuggestions is the most suggestions the index returns for one prefix.
+const MaxSuggestions = 50
+
+// node is a trie node for one byte of a username key.
+type node struct {
+	children map[byte]*node
+	// here are the users whose key ends at this node.
+	here []user.Suggestion
+	// top are the best ranked users with keys under this node, at most
+	// MaxSuggestions of them, best first.
+	top []user.Suggestion
+}
+
+// Index is an in-memory username prefix index. Usernames are keyed by their
+// canonical form, so a prefix matches regardless of case and lookalike
+// characters, just as usernames are compared for uniqueness. It is a trie
+// whose nodes keep the top ranked users below them, so a lookup only walks
+// the prefix, and a write only re-ranks the nodes on its path up to the
+// first one it does not affect. It is safe for concurrent use.
+type Index struct {
+	mu   sync.RWMutex
+	root *node
+	keys map[int]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		root: &node{},
+		keys: map[int]string{},
+	}
+}
+
+// Load replaces the contents of the index with users.
+func (idx *Index) Load(users []user.User) {
+	fresh := NewIndex()
+	for _, u := range users {
+		fresh.put(u)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.root = fresh.root
+	idx.keys = fresh.keys
+}
+
+// Put adds u to the index, replacing any previous entry for the same ID. A
+// user whose username has no canonical key is left out.
+func (idx *Index) Put(u user.User) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.put(u)
+}
+
+func (idx *Index) put(u user.User) {
+	key, err := canonical.UserNameKey(u.UserName)
+	if err != nil {
+		idx.remove(u.ID)
+		return
+	}
+	if old, ok := idx.keys[u.ID]; ok && old != key {
+		idx.remove(u.ID)
+	}
+	suggestion := user.Suggestion{
+		ID:        u.ID,
+		UserName:  u.UserName,
+		UserScore: u.UserScore,
+	}
+	path := idx.path(key, true)
+	last := path[len(path)-1]
+	last.here = append(without(last.here, u.ID), suggestion)
+	idx.keys[u.ID] = key
+	rerank(path, suggestion, true)
+}
+
+// Remove drops the entry for the user with the given ID, if any.
+func (idx *Index) Remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id int) {
+	key, ok := idx.keys[id]
+	if !ok {
+		return
+	}
+	delete(idx.keys, id)
+	path := idx.path(key, false)
+	last := path[len(path)-1]
+	last.here = without(last.here, id)
+	rerank(path, user.Suggestion{ID: id}, false)
+	// Prune the nodes left without users.
+	for i := len(path) - 1; i > 0 && len(path[i].top) == 0; i-- {
+		delete(path[i-1].children, key[i-1])
+	}
+}
+
+// path returns the nodes from the root to the one for key, creating missing
+// ones if create is set. Otherwise it stops at the last existing node.
+func (idx *Index) path(key string, create bool) []*node {
+	path := make([]*node, 1, len(key)+1)
+	path[0] = idx.root
+	n := idx.root
+	for i := 0; i < len(key); i++ {
+		child, ok := n.children[key[i]]
+		if !ok {
+			if !create {
+				break
+			}
+			if n.children == nil {
+				n.children = map[byte]*node{}
+			}
+			child = &node{}
+			n.children[key[i]] = child
+		}
+		path = append(path, child)
+		n = child
+	}
+	return path
+}
+
+// Suggest returns up to limit users whose username starts with prefix,
+// ignoring case and lookalike characters, ordered by descending UserScore.
+// limit is capped at MaxSuggestions.
+func (idx *Index) Suggest(prefix string, limit int) []user.Suggestion {
+	if limit > MaxSuggestions {
+		limit = MaxSuggestions
+	}
+	if limit < 1 {
+		return []user.Suggestion{}
+	}
+	key, err := canonical.UserNameKey(prefix)
+	if err != nil {
+		return []user.Suggestion{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	n := idx.root
+	for i := 0; i < len(key); i++ {
+		if n = n.children[key[i]]; n == nil {
+			return []user.Suggestion{}
+		}
+	}
+	if len(n.top) < limit {
+		limit = len(n.top)
+	}
+	top := make([]user.Suggestion, limit)
+	copy(top, n.top)
+	return top
+}
+
+// rerank updates the top users of the nodes on path, deepest first, after s
+// was put under the last of them, or removed from it if present is false.
+// It stops at the first node whose top is unaffected, as then so are the
+// tops above it.
+func rerank(path []*node, s user.Suggestion, present bool) {
+	for i := len(path) - 1; i >= 0; i-- {
+		if !path[i].rerank(s, present) {
+			return
+		}
+	}
+}
+
+// rerank updates the top users of n for s and reports whether they changed.
+// Users not in a full top rank after its last one, so s only needs the
+// children merged again when it could be overtaken by one of them.
+func (n *node) rerank(s user.Suggestion, present bool) bool {
+	full := len(n.top) == MaxSuggestions
+	i := indexOf(n.top, s.ID)
+	if i < 0 {
+		if !present || (full && !ranksBefore(s, n.top[len(n.top)-1])) {
+			return false
+		}
+		n.top = insert(n.top, s)
+		if len(n.top) > MaxSuggestions {
+			n.top = n.top[:MaxSuggestions]
+		}
+		return true
+	}
+
+	wasLast := i == len(n.top)-1
+	n.top = append(n.top[:i], n.top[i+1:]...)
+	switch {
+	case !full && present:
+		n.top = insert(n.top, s)
+	case !full:
+	case present && !wasLast && ranksBefore(s, n.top[len(n.top)-1]):
+		n.top = insert(n.top, s)
+	default:
+		n.top = n.best()
+	}
+	return true
+}
+
+// best merges the users ending at n with the tops of its children, each
+// already ranked, into the top MaxSuggestions users under n.
+func (n *node) best() []user.Suggestion {
+	here := append([]user.Suggestion(nil), n.here...)
+	sortSuggestions(here)
+	lists := make([][]user.Suggestion, 0, len(n.children)+1)
+	lists = append(lists, here)
+	for _, child := range n.children {
+		lists = append(lists, child.top)
+	}
+
+	top := make([]user.Suggestion, 0, MaxSuggestions)
+	for len(top) < MaxSuggestions {
+		next := -1
+		for i, list := range lists {
+			if len(list) > 0 && (next < 0 || ranksBefore(list[0], lists[next][0])) {
+				next = i
+			}
+		}
+		if next < 0 {
+			break
+		}
+		top = append(top, lists[next][0])
+		lists[next] = lists[next][1:]
+	}
+	return top
+}
+
+// sortSuggestions ranks the few users sharing a key with an insertion sort.
+func sortSuggestions(s []user.Suggestion) {
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && ranksBefore(s[j], s[j-1]); j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+}
+
+// insert adds s to the ranked suggestions top in its place.
+func insert(top []user.Suggestion, s user.Suggestion) []user.Suggestion {
+	i := len(top)
+	for i > 0 && ranksBefore(s, top[i-1]) {
+		i--
+	}
+	top = append(top, user.Suggestion{})
+	copy(top[i+1:], top[i:])
+	top[i] = s
+	return top
+}
+
+func indexOf(suggestions []user.Suggestion, id int) int {
+	for i, s := range suggestions {
+		if s.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// without returns suggestions without the one for the user with id.
+func without(suggestions []user.Suggestion, id int) []user.Suggestion {
+	if i := indexOf(suggestions, id); i >= 0 {
+		return append(suggestions[:i], suggestions[i+1:]...)
+	}
+	return suggestions
+}
+
+// ranksBefore orders suggestions by descending score, then by username and ID.
+func ranksBefore(a, b user.Suggestion) bool {
+	if a.UserScore != b.UserScore {
+		return a.UserScore > b.UserScore
+	}
+	if a.UserName != b.UserName {
+		return a.UserName < b.UserName
+	}
+	return a.ID < b.ID
+}
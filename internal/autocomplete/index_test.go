@@ -0,0 +1,277 @@
+package autocomplete
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIndex(t *testing.T) {
+	t.Run("Tests suggestions are ordered by score", func(t *testing.T) {
+		idx := NewIndex()
+		idx.Load([]user.User{
+			{ID: 1, UserName: "gopher", UserScore: 10},
+			{ID: 2, UserName: "Gordon", UserScore: 500},
+			{ID: 3, UserName: "goku", UserScore: 50},
+			{ID: 4, UserName: "alice", UserScore: 1000},
+		})
+
+		suggestions := idx.Suggest("GO", 10)
+		assert.Equal(t, []user.Suggestion{
+			{ID: 2, UserName: "Gordon", UserScore: 500},
+			{ID: 3, UserName: "goku", UserScore: 50},
+			{ID: 1, UserName: "gopher", UserScore: 10},
+		}, suggestions)
+	})
+
+	t.Run("Tests suggestions are limited to the top N", func(t *testing.T) {
+		idx := NewIndex()
+		for i := 1; i <= 20; i++ {
+			idx.Put(user.User{ID: i, UserName: fmt.Sprintf("user%02d", i), UserScore: i})
+		}
+
+		suggestions := idx.Suggest("user", 3)
+		assert.Len(t, suggestions, 3)
+		assert.Equal(t, 20, suggestions[0].ID)
+		assert.Equal(t, 19, suggestions[1].ID)
+		assert.Equal(t, 18, suggestions[2].ID)
+	})
+
+	t.Run("Tests put replaces a renamed user", func(t *testing.T) {
+		idx := NewIndex()
+		idx.Put(user.User{ID: 1, UserName: "bob"})
+		idx.Put(user.User{ID: 1, UserName: "robert"})
+
+		assert.Empty(t, idx.Suggest("bob", 10))
+		assert.Len(t, idx.Suggest("rob", 10), 1)
+	})
+
+	t.Run("Tests remove drops a user", func(t *testing.T) {
+		idx := NewIndex()
+		idx.Put(user.User{ID: 1, UserName: "bob"})
+		idx.Put(user.User{ID: 2, UserName: "bob"})
+		idx.Remove(1)
+
+		assert.Equal(t, []user.Suggestion{{ID: 2, UserName: "bob"}}, idx.Suggest("b", 10))
+	})
+
+	t.Run("Tests prefixes match by canonical key", func(t *testing.T) {
+		idx := NewIndex()
+		idx.Put(user.User{ID: 1, UserName: "Gopher"})
+		idx.Put(user.User{ID: 2, UserName: "mike"})
+
+		assert.Len(t, idx.Suggest("ＧＯ", 10), 1)
+		assert.Len(t, idx.Suggest("g0ph", 10), 1)
+		assert.Len(t, idx.Suggest("rni", 10), 1)
+	})
+
+	t.Run("Tests suggestions agree with a full scan after many writes", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		idx := NewIndex()
+		users := map[int]user.User{}
+		for i := 0; i < 5000; i++ {
+			id := r.Intn(300) + 1
+			if r.Intn(5) == 0 {
+				idx.Remove(id)
+				delete(users, id)
+				continue
+			}
+			u := user.User{ID: id, UserName: randomName(r, 1+r.Intn(4), "ab"), UserScore: r.Intn(20)}
+			idx.Put(u)
+			users[id] = u
+		}
+
+		for _, prefix := range []string{"a", "b", "aa", "ab", "ba", "aba", "bbb"} {
+			var want []user.Suggestion
+			for _, u := range users {
+				key, _ := canonical.UserNameKey(u.UserName)
+				if strings.HasPrefix(key, prefix) {
+					want = append(want, user.Suggestion{ID: u.ID, UserName: u.UserName, UserScore: u.UserScore})
+				}
+			}
+			sort.Slice(want, func(i, j int) bool {
+				return ranksBefore(want[i], want[j])
+			})
+			if len(want) > MaxSuggestions {
+				want = want[:MaxSuggestions]
+			}
+			assert.Equal(t, want, idx.Suggest(prefix, MaxSuggestions), prefix)
+		}
+	})
+}
+
+func TestStore(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("Tests writes keep the index in sync", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		baseMock.
+			EXPECT().
+			GetAllUsers(gomock.Any()).
+			Return([]user.User{{ID: 1, UserName: "alice"}}, nil)
+
+		s, err := NewStore(context.Background(), baseMock)
+		assert.NoError(t, err)
+
+		created := &user.User{ID: 2, UserName: "alfred", UserScore: 5}
+		baseMock.EXPECT().CreateUser(gomock.Any(), created).Return(created, nil)
+		_, err = s.CreateUser(context.Background(), created)
+		assert.NoError(t, err)
+
+		suggestions, err := s.SuggestUsers(context.Background(), "al", 10)
+		assert.NoError(t, err)
+		assert.Len(t, suggestions, 2)
+		assert.Equal(t, 2, suggestions[0].ID)
+
+		update := user.User{ID: 1, UserName: "zed"}
+		baseMock.EXPECT().UpdateUser(gomock.Any(), update).Return(user.User{ID: 1, UserName: "zed", UserScore: 3}, nil)
+		_, err = s.UpdateUser(context.Background(), update)
+		assert.NoError(t, err)
+
+		suggestions, err = s.SuggestUsers(context.Background(), "z", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []user.Suggestion{{ID: 1, UserName: "zed", UserScore: 3}}, suggestions)
+
+		baseMock.EXPECT().DeleteUser(gomock.Any(), 2).Return(nil)
+		assert.NoError(t, s.DeleteUser(context.Background(), 2))
+
+		suggestions, err = s.SuggestUsers(context.Background(), "al", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, suggestions)
+
+		baseMock.EXPECT().RenameUser(gomock.Any(), 1, "amy").Return(user.User{ID: 1, UserName: "amy", UserScore: 3}, nil)
+		_, err = s.RenameUser(context.Background(), 1, "amy")
+		assert.NoError(t, err)
+
+		suggestions, err = s.SuggestUsers(context.Background(), "z", 10)
+		assert.NoError(t, err)
+		assert.Empty(t, suggestions)
+		suggestions, err = s.SuggestUsers(context.Background(), "a", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []user.Suggestion{{ID: 1, UserName: "amy", UserScore: 3}}, suggestions)
+	})
+
+	t.Run("Tests reputation changes re-rank users", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		baseMock.
+			EXPECT().
+			GetAllUsers(gomock.Any()).
+			Return([]user.User{{ID: 1, UserName: "alice", UserScore: 10}, {ID: 2, UserName: "alfred", UserScore: 5}}, nil)
+
+		s, err := NewStore(context.Background(), baseMock)
+		assert.NoError(t, err)
+
+		event := user.ReputationEvent{UserID: 2, Source: "questions", IdempotencyKey: "vote-1", Delta: 10}
+		baseMock.EXPECT().AddReputationEvent(gomock.Any(), event).Return(event, true, nil)
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{2}).Return([]user.User{{ID: 2, UserName: "alfred", UserScore: 15}}, nil)
+		_, _, err = s.AddReputationEvent(context.Background(), event)
+		assert.NoError(t, err)
+
+		// Replays change nothing, so there is nothing to reload.
+		baseMock.EXPECT().AddReputationEvent(gomock.Any(), event).Return(event, false, nil)
+		_, _, err = s.AddReputationEvent(context.Background(), event)
+		assert.NoError(t, err)
+
+		suggestions, err := s.SuggestUsers(context.Background(), "al", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, suggestions[0].ID)
+
+		baseMock.EXPECT().RecalculateUserScores(gomock.Any()).Return([]int{1}, nil)
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1}).Return([]user.User{{ID: 1, UserName: "alice", UserScore: 20}}, nil)
+		_, err = s.RecalculateUserScores(context.Background())
+		assert.NoError(t, err)
+
+		suggestions, err = s.SuggestUsers(context.Background(), "al", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, suggestions[0].ID)
+	})
+
+	t.Run("Tests rebuilds pick up other writes and keep their own", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		baseMock.
+			EXPECT().
+			GetAllUsers(gomock.Any()).
+			Return([]user.User{{ID: 1, UserName: "alice"}}, nil)
+
+		ctx := context.Background()
+		repo, err := NewStore(ctx, baseMock)
+		assert.NoError(t, err)
+		s := repo.(*store)
+
+		// Another instance created alfred, and this one renames alice while
+		// the rebuild reads the users from before the rename.
+		baseMock.EXPECT().RenameUser(gomock.Any(), 1, "amy").Return(user.User{ID: 1, UserName: "amy"}, nil)
+		baseMock.
+			EXPECT().
+			GetAllUsers(gomock.Any()).
+			DoAndReturn(func(ctx context.Context) ([]user.User, error) {
+				_, err := s.RenameUser(ctx, 1, "amy")
+				assert.NoError(t, err)
+				return []user.User{{ID: 1, UserName: "alice"}, {ID: 2, UserName: "alfred"}}, nil
+			})
+		assert.NoError(t, s.rebuild(ctx))
+
+		suggestions, err := s.SuggestUsers(ctx, "a", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, []user.Suggestion{{ID: 2, UserName: "alfred"}, {ID: 1, UserName: "amy"}}, suggestions)
+	})
+}
+
+func randomName(r *rand.Rand, n int, letters string) string {
+	name := make([]byte, n)
+	for j := range name {
+		name[j] = letters[r.Intn(len(letters))]
+	}
+	return string(name)
+}
+
+func benchmarkIndex(n int) *Index {
+	r := rand.New(rand.NewSource(1))
+	users := make([]user.User, n)
+	for i := range users {
+		name := randomName(r, 6+r.Intn(8), "abcdefghijklmnopqrstuvwxyz")
+		users[i] = user.User{ID: i + 1, UserName: name, UserScore: r.Intn(100000)}
+	}
+	idx := NewIndex()
+	idx.Load(users)
+	return idx
+}
+
+func BenchmarkSuggest(b *testing.B) {
+	idx := benchmarkIndex(100000)
+	for _, prefix := range []string{"a", "ab", "abc"} {
+		b.Run(prefix, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				idx.Suggest(prefix, 10)
+			}
+		})
+	}
+}
+
+func BenchmarkPutScoreChange(b *testing.B) {
+	idx := benchmarkIndex(100000)
+	u := user.User{ID: 1, UserName: "benchmark"}
+	idx.Put(u)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		u.UserScore = i
+		idx.Put(u)
+	}
+}
+
+func BenchmarkPutRename(b *testing.B) {
+	idx := benchmarkIndex(100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Put(user.User{ID: i%1000 + 1, UserName: fmt.Sprintf("bench%d", i), UserScore: i})
+	}
+}
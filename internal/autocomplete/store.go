@@ -0,0 +1,215 @@
+//go:generate mockgen -destination=store_mocks_test.go -package=autocomplete github.com/millbj92/nuboverflow-users/internal/repository Store
+package autocomplete
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// store decorates a repository.Store, answering SuggestUsers from an
+// in-memory Index that it keeps in sync with every write going through it.
+// Writes made by other processes are only seen once the index is rebuilt,
+// which WithRefresh does periodically.
+type store struct {
+	repository.Store
+	index *Index
+
+	// mu orders writes to the index against rebuilds. While a rebuild reads
+	// every user, pending records the users written meanwhile, with nil for
+	// removed ones, so the rebuild does not undo them with what it read.
+	mu      sync.Mutex
+	pending map[int]*user.User
+}
+
+// Option configures optional behaviour of the autocomplete store.
+type Option func(*options)
+
+type options struct {
+	refresh time.Duration
+}
+
+// WithRefresh rebuilds the index from the wrapped Store every interval, to
+// pick up writes made by other instances of the service.
+func WithRefresh(interval time.Duration) Option {
+	return func(o *options) {
+		o.refresh = interval
+	}
+}
+
+// NewStore loads every user from base into a new Index and returns a Store
+// that serves username suggestions from it. Rebuilds set up by WithRefresh
+// run until ctx is done.
+func NewStore(ctx context.Context, base repository.Store, opts ...Option) (repository.Store, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	users, err := base.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	index := NewIndex()
+	index.Load(users)
+	log.Printf("Autocomplete index loaded with %d users.", len(users))
+	s := &store{
+		Store: base,
+		index: index,
+	}
+	if o.refresh > 0 {
+		go s.runRefresh(ctx, o.refresh)
+	}
+	return s, nil
+}
+
+// runRefresh rebuilds the index every interval until ctx is done.
+func (s *store) runRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.rebuild(ctx); err != nil {
+				log.Printf("Autocomplete rebuild failed: %s", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// rebuild reloads the index with every user in the wrapped Store, keeping
+// the writes made through this store while they were read.
+func (s *store) rebuild(ctx context.Context) error {
+	s.mu.Lock()
+	s.pending = map[int]*user.User{}
+	s.mu.Unlock()
+
+	users, err := s.Store.GetAllUsers(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.pending
+	s.pending = nil
+	if err != nil {
+		return err
+	}
+	current := make([]user.User, 0, len(users)+len(pending))
+	for _, u := range users {
+		if _, ok := pending[u.ID]; !ok {
+			current = append(current, u)
+		}
+	}
+	for _, u := range pending {
+		if u != nil {
+			current = append(current, *u)
+		}
+	}
+	s.index.Load(current)
+	return nil
+}
+
+// put indexes u, which was just written.
+func (s *store) put(u user.User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != nil {
+		s.pending[u.ID] = &u
+	}
+	s.index.Put(u)
+}
+
+// remove drops the user with id, who was just deleted.
+func (s *store) remove(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != nil {
+		s.pending[id] = nil
+	}
+	s.index.Remove(id)
+}
+
+func (s *store) SuggestUsers(ctx context.Context, prefix string, limit int) ([]user.Suggestion, error) {
+	return s.index.Suggest(prefix, limit), nil
+}
+
+func (s *store) CreateUser(ctx context.Context, usr *user.User) (*user.User, error) {
+	created, err := s.Store.CreateUser(ctx, usr)
+	if err != nil {
+		return nil, err
+	}
+	s.put(*created)
+	return created, nil
+}
+
+func (s *store) UpdateUser(ctx context.Context, usr user.User) (user.User, error) {
+	updated, err := s.Store.UpdateUser(ctx, usr)
+	if err != nil {
+		return user.User{}, err
+	}
+	s.put(updated)
+	return updated, nil
+}
+
+func (s *store) DeleteUser(ctx context.Context, id int) error {
+	if err := s.Store.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *store) RestoreUser(ctx context.Context, id int) (user.User, error) {
+	restored, err := s.Store.RestoreUser(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	s.put(restored)
+	return restored, nil
+}
+
+func (s *store) RenameUser(ctx context.Context, id int, name string) (user.User, error) {
+	renamed, err := s.Store.RenameUser(ctx, id, name)
+	if err != nil {
+		return user.User{}, err
+	}
+	s.put(renamed)
+	return renamed, nil
+}
+
+// AddReputationEvent re-ranks the user, whose UserScore has changed.
+func (s *store) AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	stored, added, err := s.Store.AddReputationEvent(ctx, event)
+	if err != nil || !added {
+		return stored, added, err
+	}
+	s.reload(ctx, event.UserID)
+	return stored, added, nil
+}
+
+func (s *store) RecalculateUserScores(ctx context.Context) ([]int, error) {
+	fixed, err := s.Store.RecalculateUserScores(ctx)
+	s.reload(ctx, fixed...)
+	return fixed, err
+}
+
+// reload puts the current state of the users with ids into the index. The
+// writes that changed them already succeeded, so failing to read them back
+// only leaves their suggestions stale until the next rebuild.
+func (s *store) reload(ctx context.Context, ids ...int) {
+	if len(ids) == 0 {
+		return
+	}
+	users, err := s.Store.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("AUTOCOMPLETE ERROR: %s", err.Error())
+		return
+	}
+	for _, u := range users {
+		s.put(u)
+	}
+}
@@ -0,0 +1,258 @@
+// Package avatar turns uploaded profile pictures into square variants of a
+// few sizes and formats, stores them, and draws identicons for users who
+// have not uploaded one.
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"sort"
+	"strings"
+
+	// Uploads may also be GIFs; only their first frame is kept.
+	_ "image/gif"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// Format is an encoding variants are stored and served in.
+type Format string
+
+const (
+	PNG  Format = "png"
+	JPEG Format = "jpeg"
+	WebP Format = "webp"
+)
+
+// Formats lists the formats every variant is stored in.
+var Formats = []Format{PNG, JPEG, WebP}
+
+// ParseFormat returns the Format named s, accepting "jpg" for JPEG.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "png":
+		return PNG, nil
+	case "jpeg", "jpg":
+		return JPEG, nil
+	case "webp":
+		return WebP, nil
+	}
+	return "", domainerr.Validation("avatar format must be png, jpeg or webp")
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	return "image/" + string(f)
+}
+
+// Ext returns the file extension of the format, without the dot.
+func (f Format) Ext() string {
+	if f == JPEG {
+		return "jpg"
+	}
+	return string(f)
+}
+
+const (
+	// DefaultMaxBytes is the largest upload a Processor accepts when no other
+	// limit is configured.
+	DefaultMaxBytes = 2 << 20
+	// DefaultMaxPixels is the most pixels an upload may decode to when no
+	// other limit is configured. It is checked before decoding, so small
+	// files that expand into huge images are turned away cheaply.
+	DefaultMaxPixels = 4096 * 4096
+)
+
+// DefaultSizes are the edge lengths, in pixels, of the variants made of each
+// avatar when no others are configured.
+var DefaultSizes = []int{32, 64, 128, 256}
+
+// ErrUnsupportedType is returned for uploads that are not PNG, JPEG or GIF
+// images.
+var ErrUnsupportedType = domainerr.Validation("avatar must be a PNG, JPEG or GIF image")
+
+// Avatar is an uploaded picture processed into its variants.
+type Avatar struct {
+	// Hash identifies the picture: the hex SHA-256 of the uploaded file. It
+	// names the variants' files, so a new picture never reuses the name of
+	// one that clients may have cached.
+	Hash     string
+	Variants []Variant
+}
+
+// Variant is an avatar, or identicon, at one size in one format.
+type Variant struct {
+	Size   int
+	Format Format
+	Data   []byte
+}
+
+// Key returns the BlobStore key of the variant of the avatar with hash that
+// belongs to the user with id.
+func Key(userID int, hash string, size int, format Format) string {
+	return fmt.Sprintf("%s%d.%s", KeyPrefix(userID, hash), size, format.Ext())
+}
+
+// KeyPrefix returns the prefix of the keys of every variant of the avatar
+// with hash that belongs to the user with id, whatever sizes it was made in.
+func KeyPrefix(userID int, hash string) string {
+	return fmt.Sprintf("%d/%s-", userID, hash)
+}
+
+// Processor validates uploads and makes their variants.
+type Processor struct {
+	sizes     []int
+	maxBytes  int
+	maxPixels int
+}
+
+// Option configures optional behaviour of a Processor.
+type Option func(*Processor)
+
+// WithSizes sets the sizes variants are made in, replacing DefaultSizes.
+func WithSizes(sizes ...int) Option {
+	return func(p *Processor) {
+		p.sizes = append([]int{}, sizes...)
+		sort.Ints(p.sizes)
+	}
+}
+
+// WithMaxBytes sets the largest upload accepted, replacing DefaultMaxBytes.
+func WithMaxBytes(max int) Option {
+	return func(p *Processor) {
+		p.maxBytes = max
+	}
+}
+
+// WithMaxPixels sets the most pixels an upload may decode to, replacing
+// DefaultMaxPixels.
+func WithMaxPixels(max int) Option {
+	return func(p *Processor) {
+		p.maxPixels = max
+	}
+}
+
+// NewProcessor returns a Processor configured by opts.
+func NewProcessor(opts ...Option) *Processor {
+	p := &Processor{
+		sizes:     DefaultSizes,
+		maxBytes:  DefaultMaxBytes,
+		maxPixels: DefaultMaxPixels,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Sizes returns the sizes variants are made in, smallest first.
+func (p *Processor) Sizes() []int {
+	return append([]int{}, p.sizes...)
+}
+
+// MaxBytes returns the largest upload accepted.
+func (p *Processor) MaxBytes() int {
+	return p.maxBytes
+}
+
+// Fit returns the smallest variant size at least size, or the largest one if
+// size is larger than all of them. A size of 0 or less asks for the largest.
+func (p *Processor) Fit(size int) int {
+	largest := p.sizes[len(p.sizes)-1]
+	if size <= 0 {
+		return largest
+	}
+	for _, s := range p.sizes {
+		if s >= size {
+			return s
+		}
+	}
+	return largest
+}
+
+// Process checks that data is an image small enough to accept and makes its
+// variants: cropped to the centre square, turned upright and scaled to each
+// size, in each of Formats. Variants are encoded from pixels alone, so EXIF
+// and other metadata in the upload, such as where a photo was taken, is
+// dropped.
+func (p *Processor) Process(data []byte) (Avatar, error) {
+	if len(data) > p.maxBytes {
+		return Avatar{}, domainerr.Validation("avatar must be at most %d bytes", p.maxBytes)
+	}
+	switch http.DetectContentType(data) {
+	case "image/png", "image/jpeg", "image/gif":
+	default:
+		return Avatar{}, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Avatar{}, domainerr.Wrap(domainerr.KindValidation, err, "avatar could not be decoded")
+	}
+	if config.Width*config.Height > p.maxPixels {
+		return Avatar{}, domainerr.Validation("avatar must be at most %d pixels", p.maxPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Avatar{}, domainerr.Wrap(domainerr.KindValidation, err, "avatar could not be decoded")
+	}
+	square := cropSquare(orient(toRGBA(img), orientation(data)))
+
+	sum := sha256.Sum256(data)
+	avatar := Avatar{Hash: hex.EncodeToString(sum[:])}
+	for _, size := range p.sizes {
+		scaled := resize(square, size)
+		for _, format := range Formats {
+			encoded, err := Encode(scaled, format)
+			if err != nil {
+				return Avatar{}, err
+			}
+			avatar.Variants = append(avatar.Variants, Variant{Size: size, Format: format, Data: encoded})
+		}
+	}
+	return avatar, nil
+}
+
+// Encode encodes img in format. JPEG has no transparency, so transparent
+// parts of img are put on white.
+func Encode(img image.Image, format Format) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case PNG:
+		err = png.Encode(&buf, img)
+	case JPEG:
+		err = jpeg.Encode(&buf, onWhite(img), &jpeg.Options{Quality: 90})
+	case WebP:
+		err = encodeWebP(&buf, img)
+	default:
+		return nil, fmt.Errorf("unknown avatar format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// toRGBA returns img as an *image.RGBA with bounds starting at (0, 0).
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+	return rgba
+}
+
+// onWhite returns img drawn over a white background.
+func onWhite(img image.Image) image.Image {
+	b := img.Bounds()
+	flat := image.NewRGBA(b)
+	draw.Draw(flat, b, image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, b, img, b.Min, draw.Over)
+	return flat
+}
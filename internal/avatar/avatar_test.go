@@ -0,0 +1,180 @@
+package avatar
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodedPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xFF})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// withOrientation returns a JPEG of img carrying an EXIF orientation.
+func withOrientation(t *testing.T, img image.Image, orientation uint16) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, orientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	tiff = append(append(tiff, entry...), 0, 0, 0, 0)
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(len(app1)+2))
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), append(segment, app1...)...), data[2:]...)
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("Tests uploads are cropped and scaled into every size and format", func(t *testing.T) {
+		processor := NewProcessor(WithSizes(64, 16))
+		upload := encodedPNG(t, 120, 80)
+
+		avatar, err := processor.Process(upload)
+		require.NoError(t, err)
+		assert.Len(t, avatar.Hash, 64)
+		assert.Len(t, avatar.Variants, 6)
+		for _, v := range avatar.Variants {
+			switch v.Format {
+			case PNG, JPEG:
+				img, _, err := image.Decode(bytes.NewReader(v.Data))
+				require.NoError(t, err)
+				assert.Equal(t, image.Rect(0, 0, v.Size, v.Size), img.Bounds())
+			case WebP:
+				assert.Equal(t, "RIFF", string(v.Data[:4]))
+				assert.Equal(t, "WEBPVP8L", string(v.Data[8:16]))
+				assert.Equal(t, len(v.Data)-8, int(binary.LittleEndian.Uint32(v.Data[4:])))
+			}
+		}
+		assert.Equal(t, []int{16, 64}, []int{avatar.Variants[0].Size, avatar.Variants[3].Size})
+
+		again, err := processor.Process(upload)
+		require.NoError(t, err)
+		assert.Equal(t, avatar.Hash, again.Hash)
+	})
+
+	t.Run("Tests uploads that are too large or not images are rejected", func(t *testing.T) {
+		_, err := NewProcessor(WithMaxBytes(100)).Process(encodedPNG(t, 64, 64))
+		assert.True(t, errors.Is(err, domainerr.ErrValidation))
+
+		_, err = NewProcessor().Process([]byte("<svg xmlns='http://www.w3.org/2000/svg'/>"))
+		assert.Equal(t, ErrUnsupportedType, err)
+
+		_, err = NewProcessor(WithMaxPixels(1000)).Process(encodedPNG(t, 64, 64))
+		assert.True(t, errors.Is(err, domainerr.ErrValidation))
+	})
+
+	t.Run("Tests photos are turned upright by their EXIF orientation", func(t *testing.T) {
+		// A wide photo taken with the camera turned needs rotating clockwise.
+		img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				img.Set(x, y, color.White)
+			}
+		}
+		upload := withOrientation(t, img, 6)
+		assert.Equal(t, 6, orientation(upload))
+
+		upright := orient(toRGBA(img), 6)
+		assert.Equal(t, image.Rect(0, 0, 16, 32), upright.Bounds())
+		// The white left half is now the top half.
+		assert.Equal(t, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}, upright.RGBAAt(8, 4))
+		assert.Equal(t, color.RGBA{}, upright.RGBAAt(8, 28))
+
+		_, err := NewProcessor().Process(upload)
+		assert.NoError(t, err)
+	})
+}
+
+func TestResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				img.SetRGBA(x, y, color.RGBA{R: 200, A: 0xFF})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{B: 100, A: 0xFF})
+			}
+		}
+	}
+	assert.Equal(t, color.RGBA{R: 100, B: 50, A: 0xFF}, resize(img, 1).RGBAAt(0, 0))
+	half := resize(img, 2)
+	assert.Equal(t, color.RGBA{R: 200, A: 0xFF}, half.RGBAAt(0, 1))
+	assert.Equal(t, color.RGBA{B: 100, A: 0xFF}, half.RGBAAt(1, 0))
+	assert.Equal(t, color.RGBA{R: 200, A: 0xFF}, resize(img, 8).RGBAAt(3, 7))
+}
+
+func TestIdenticon(t *testing.T) {
+	assert.Equal(t, "0bc83cb571cd1c50ba6f3e8a78ef1346", EmailHash(" MyEmailAddress@example.com "))
+
+	a := Identicon("bob@example.com", 60)
+	assert.Equal(t, a, Identicon("Bob@Example.com", 60))
+	assert.NotEqual(t, a, Identicon("alice@example.com", 60))
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 60; x++ {
+			assert.Equal(t, a.RGBAAt(x, y), a.RGBAAt(59-x, y), "not symmetric at %d,%d", x, y)
+		}
+	}
+}
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	key := Key(7, "abc", 64, JPEG)
+	assert.Equal(t, "7/abc-64.jpg", key)
+	_, err = store.Get(ctx, key)
+	assert.Equal(t, ErrBlobNotFound, err)
+
+	require.NoError(t, store.Put(ctx, key, []byte("one")))
+	require.NoError(t, store.Put(ctx, key, []byte("two")))
+	data, err := store.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, "two", string(data))
+
+	require.NoError(t, store.Delete(ctx, key))
+	require.NoError(t, store.Delete(ctx, key))
+	_, err = store.Get(ctx, key)
+	assert.Equal(t, ErrBlobNotFound, err)
+
+	for _, bad := range []string{"../escape", "/etc/passwd", "a/../../b", ""} {
+		assert.Error(t, store.Put(ctx, bad, []byte("x")), bad)
+	}
+
+	require.NoError(t, store.DeletePrefix(ctx, KeyPrefix(8, "abc")))
+	for _, k := range []string{Key(7, "abc", 64, JPEG), Key(7, "abc", 256, WebP), Key(7, "abcd", 64, JPEG)} {
+		require.NoError(t, store.Put(ctx, k, []byte("x")))
+	}
+	require.NoError(t, store.DeletePrefix(ctx, KeyPrefix(7, "abc")))
+	for _, k := range []string{Key(7, "abc", 64, JPEG), Key(7, "abc", 256, WebP)} {
+		_, err = store.Get(ctx, k)
+		assert.Equal(t, ErrBlobNotFound, err, k)
+	}
+	_, err = store.Get(ctx, Key(7, "abcd", 64, JPEG))
+	assert.NoError(t, err)
+	for _, bad := range []string{"../escape-", "7/", "/etc/"} {
+		assert.Error(t, store.DeletePrefix(ctx, bad), bad)
+	}
+}
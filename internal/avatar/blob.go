@@ -0,0 +1,134 @@
+package avatar
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// ErrBlobNotFound is returned by BlobStore.Get for keys with nothing stored.
+var ErrBlobNotFound = domainerr.NotFound("avatar file not found")
+
+// BlobStore stores the files of avatars by key. Keys are slash-separated
+// relative paths, as made by Key. Implementations must be safe for
+// concurrent use.
+type BlobStore interface {
+	// Put stores data under key, replacing anything already there.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns what is stored under key, or ErrBlobNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes what is stored under key. Deleting a key with nothing
+	// stored is not an error.
+	Delete(ctx context.Context, key string) error
+	// DeletePrefix removes what is stored under every key starting with
+	// prefix, as made by KeyPrefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// LocalStore is a BlobStore keeping each key in a file under a directory.
+type LocalStore struct {
+	dir string
+}
+
+// NewLocalStore returns a LocalStore keeping files under dir, which is
+// created if it does not exist.
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{dir: dir}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file and rename it into place, so that readers
+	// never see half a file.
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".put-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) ([]byte, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	return data, err
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// DeletePrefix removes the files in the directory of prefix whose names
+// start with the rest of it. prefix must not end in a slash.
+func (s *LocalStore) DeletePrefix(ctx context.Context, prefix string) error {
+	dir, err := s.path(path.Dir(prefix))
+	if err != nil {
+		return err
+	}
+	base := path.Base(prefix)
+	if strings.HasSuffix(prefix, "/") || base == "." || base == ".." {
+		return domainerr.Validation("invalid avatar key prefix %q", prefix)
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), base) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path returns the file key is kept in, refusing keys that would lead out
+// of the store's directory.
+func (s *LocalStore) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || key == ".." || strings.HasPrefix(key, "../") || strings.Contains(key, "\\") {
+		return "", domainerr.Validation("invalid avatar key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
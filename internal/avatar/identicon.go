@@ -0,0 +1,87 @@
+package avatar
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// EmailHash returns the Gravatar hash of email: the hex MD5 of it trimmed and
+// lowercased.
+func EmailHash(email string) string {
+	sum := emailSum(email)
+	return hex.EncodeToString(sum[:])
+}
+
+func emailSum(email string) [md5.Size]byte {
+	return md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+}
+
+// identiconGrid is how many cells an identicon is across.
+const identiconGrid = 5
+
+// Identicon draws the avatar of a user who has not uploaded one: a
+// size×size, left-right symmetric pattern of 5×5 cells in a colour, both
+// taken from the Gravatar hash of their email, on a light background. The
+// same email always gets the same identicon.
+func Identicon(email string, size int) *image.RGBA {
+	sum := emailSum(email)
+	fg := hueColor(float64(uint16(sum[12])<<8|uint16(sum[13])) / 65536)
+	bg := color.RGBA{R: 0xF0, G: 0xF0, B: 0xF0, A: 0xFF}
+
+	// Half a cell of margin on every side.
+	cell := float64(size) / (identiconGrid + 1)
+	margin := cell / 2
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			c := bg
+			col := int((float64(x) + 0.5 - margin) / cell)
+			row := int((float64(y) + 0.5 - margin) / cell)
+			inside := float64(x)+0.5 >= margin && float64(y)+0.5 >= margin &&
+				col < identiconGrid && row < identiconGrid
+			if inside {
+				// Columns 3 and 4 mirror 1 and 0.
+				if col > identiconGrid/2 {
+					col = identiconGrid - 1 - col
+				}
+				// Each of the 15 distinct cells is on if its byte of the hash is odd.
+				if sum[row*3+col]&1 == 1 {
+					c = fg
+				}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// hueColor returns a saturated, mid-light colour of hue h, from 0 to 1.
+func hueColor(h float64) color.RGBA {
+	const s, l = 0.55, 0.5
+	q := l + s - l*s
+	p := 2*l - q
+	channel := func(t float64) uint8 {
+		switch {
+		case t < 0:
+			t++
+		case t > 1:
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 1.0/2:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return clamp(v * 255)
+	}
+	return color.RGBA{R: channel(h + 1.0/3), G: channel(h), B: channel(h - 1.0/3), A: 0xFF}
+}
@@ -0,0 +1,139 @@
+package avatar
+
+import (
+	"image"
+)
+
+// cropSquare returns the largest square centred in img.
+func cropSquare(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return img.SubImage(image.Rect(x, y, x+side, y+side)).(*image.RGBA)
+}
+
+// resize scales the square img to size×size. Each pixel is the average of
+// the source pixels it covers, weighted by how much of each it covers, which
+// keeps downscaled photos from aliasing. Averaging premultiplied colours
+// keeps transparent pixels from darkening the edges of opaque ones.
+func resize(img *image.RGBA, size int) *image.RGBA {
+	b := img.Bounds()
+	cols := coverage(b.Dx(), size)
+	rows := coverage(b.Dy(), size)
+
+	// Scale rows first, into a size×height buffer of channel sums.
+	wide := make([]float64, size*b.Dy()*4)
+	for y := 0; y < b.Dy(); y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x, spans := range cols {
+			dst := wide[(y*size+x)*4:]
+			for _, s := range spans {
+				for c := 0; c < 4; c++ {
+					dst[c] += float64(src[s.index*4+c]) * s.weight
+				}
+			}
+		}
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y, spans := range rows {
+		for x := 0; x < size; x++ {
+			var sum [4]float64
+			for _, s := range spans {
+				src := wide[(s.index*size+x)*4:]
+				for c := 0; c < 4; c++ {
+					sum[c] += src[c] * s.weight
+				}
+			}
+			dst := out.Pix[out.PixOffset(x, y):]
+			for c := 0; c < 4; c++ {
+				dst[c] = clamp(sum[c])
+			}
+		}
+	}
+	return out
+}
+
+// span is a source pixel and the share of a destination pixel it covers.
+type span struct {
+	index  int
+	weight float64
+}
+
+// coverage returns, for each of to destination pixels along an edge of from
+// source pixels, the source pixels it covers and their weights, which sum to
+// 1.
+func coverage(from, to int) [][]span {
+	scale := float64(from) / float64(to)
+	spans := make([][]span, to)
+	for i := range spans {
+		start, end := float64(i)*scale, float64(i+1)*scale
+		for j := int(start); j < from && float64(j) < end; j++ {
+			lo, hi := float64(j), float64(j+1)
+			if lo < start {
+				lo = start
+			}
+			if hi > end {
+				hi = end
+			}
+			if hi > lo {
+				spans[i] = append(spans[i], span{index: j, weight: (hi - lo) / scale})
+			}
+		}
+	}
+	return spans
+}
+
+func clamp(v float64) uint8 {
+	switch {
+	case v <= 0:
+		return 0
+	case v >= 255:
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// orient returns img turned upright according to an EXIF orientation, which
+// says how the stored pixels have to be flipped and rotated to display the
+// photo as it was taken.
+func orient(img *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	// Orientations 5 to 8 swap width and height.
+	ow, oh := w, h
+	if orientation >= 5 {
+		ow, oh = h, w
+	}
+	out := image.NewRGBA(image.Rect(0, 0, ow, oh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored
+				dx, dy = w-1-x, y
+			case 3: // rotated 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // mirrored along the main diagonal
+				dx, dy = y, x
+			case 6: // needs rotating 90° clockwise
+				dx, dy = h-1-y, x
+			case 7: // mirrored along the anti-diagonal
+				dx, dy = h-1-y, w-1-x
+			case 8: // needs rotating 90° counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(out.Pix[out.PixOffset(dx, dy):out.PixOffset(dx, dy)+4], img.Pix[img.PixOffset(b.Min.X+x, b.Min.Y+y):])
+		}
+	}
+	return out
+}
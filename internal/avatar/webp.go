@@ -0,0 +1,340 @@
+package avatar
+
+import (
+	"container/heap"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+)
+
+// encodeWebP writes img as a lossless WebP (VP8L) image. Avatars are small
+// enough that a simple encoder does well: it applies the subtract-green
+// transform and Huffman-codes every pixel as a literal, without backward
+// references or a colour cache.
+//
+// See https://developers.google.com/speed/webp/docs/webp_lossless_bitstream_specification.
+func encodeWebP(out io.Writer, img image.Image) error {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w < 1 || h < 1 || w > 1<<14 || h > 1<<14 {
+		return fmt.Errorf("webp: cannot encode a %dx%d image", w, h)
+	}
+
+	argb := make([][4]uint8, 0, w*h)
+	alpha := false
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			alpha = alpha || c.A != 0xFF
+			// Subtract green: red and blue are stored less green, which
+			// makes them cluster around 0 in most images.
+			argb = append(argb, [4]uint8{c.G, c.R - c.G, c.B - c.G, c.A})
+		}
+	}
+
+	var counts [4][]int
+	for i, size := range []int{256 + 24, 256, 256, 256} {
+		counts[i] = make([]int, size)
+	}
+	for _, p := range argb {
+		for i, v := range p {
+			counts[i][v]++
+		}
+	}
+	var codes [5]prefixCode
+	for i := range counts {
+		codes[i] = newPrefixCode(counts[i], 15)
+	}
+	// The distance code is never used, but is always part of the stream.
+	codes[4] = newPrefixCode(make([]int, 40), 15)
+
+	bw := &bitWriter{}
+	bw.write(0x2F, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	if alpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3)
+	// One transform, subtract green, then no more.
+	bw.write(1, 1)
+	bw.write(2, 2)
+	bw.write(0, 1)
+	// No colour cache and a single group of prefix codes for the whole image.
+	bw.write(0, 1)
+	bw.write(0, 1)
+	for _, code := range codes {
+		code.writeHeader(bw)
+	}
+	for _, p := range argb {
+		for i, v := range p {
+			codes[i].writeSymbol(bw, int(v))
+		}
+	}
+	payload := bw.bytes()
+
+	pad := len(payload) % 2
+	header := make([]byte, 20)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(4+8+len(payload)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(payload)))
+	if _, err := out.Write(header); err != nil {
+		return err
+	}
+	if _, err := out.Write(payload); err != nil {
+		return err
+	}
+	if pad == 1 {
+		_, err := out.Write([]byte{0})
+		return err
+	}
+	return nil
+}
+
+// bitWriter packs values into bytes least significant bit first, as VP8L
+// reads them.
+type bitWriter struct {
+	buf  []byte
+	acc  uint64
+	bits uint
+}
+
+func (w *bitWriter) write(v uint32, n uint) {
+	w.acc |= uint64(v) << w.bits
+	w.bits += n
+	for w.bits >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.bits -= 8
+	}
+}
+
+// bytes returns what was written, padding the last byte with zeros.
+func (w *bitWriter) bytes() []byte {
+	if w.bits > 0 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc, w.bits = 0, 0
+	}
+	return w.buf
+}
+
+// codeLengthOrder is the order the lengths of the code that codes code
+// lengths are written in.
+var codeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// prefixCode is a canonical Huffman code over an alphabet.
+type prefixCode struct {
+	lengths []int
+	// codes are bit-reversed, since they are read a bit at a time from the
+	// least significant end.
+	codes []uint32
+	// used lists the symbols with a non-zero count.
+	used []int
+}
+
+// newPrefixCode returns a code for symbols occurring counts times, no longer
+// than maxLength bits.
+func newPrefixCode(counts []int, maxLength int) prefixCode {
+	code := prefixCode{lengths: huffmanLengths(counts, maxLength)}
+	for sym, n := range counts {
+		if n > 0 {
+			code.used = append(code.used, sym)
+		}
+	}
+	code.codes = canonicalCodes(code.lengths)
+	return code
+}
+
+// writeHeader writes the code itself, so that a decoder can rebuild it.
+func (c prefixCode) writeHeader(w *bitWriter) {
+	if len(c.used) <= 2 && (len(c.used) == 0 || c.used[len(c.used)-1] < 256) {
+		c.writeSimpleHeader(w)
+		return
+	}
+	w.write(0, 1)
+
+	// The lengths are themselves Huffman-coded, with a code whose lengths are
+	// written as 3-bit numbers.
+	lengthCounts := make([]int, 19)
+	for _, l := range c.lengths {
+		lengthCounts[l]++
+	}
+	lengthCode := newPrefixCode(lengthCounts, 7)
+	if len(lengthCode.used) == 1 {
+		// A one-symbol code takes no bits to write, which VP8L only allows in
+		// simple codes, so pair the only length with one that is never used.
+		other := 0
+		if lengthCode.used[0] == 0 {
+			other = 1
+		}
+		lengthCode.lengths[other] = 1
+		lengthCode.lengths[lengthCode.used[0]] = 1
+		lengthCode.used = append(lengthCode.used, other)
+		lengthCode.codes = canonicalCodes(lengthCode.lengths)
+	}
+	n := 4
+	for i, sym := range codeLengthOrder {
+		if lengthCode.lengths[sym] > 0 && i+1 > n {
+			n = i + 1
+		}
+	}
+	w.write(uint32(n-4), 4)
+	for _, sym := range codeLengthOrder[:n] {
+		w.write(uint32(lengthCode.lengths[sym]), 3)
+	}
+	// Every symbol's length follows, rather than only up to a maximum.
+	w.write(0, 1)
+	for _, l := range c.lengths {
+		lengthCode.writeSymbol(w, l)
+	}
+}
+
+// writeSimpleHeader writes a code of at most two symbols below 256 by
+// listing them.
+func (c prefixCode) writeSimpleHeader(w *bitWriter) {
+	symbols := c.used
+	if len(symbols) == 0 {
+		symbols = []int{0}
+	}
+	w.write(1, 1)
+	w.write(uint32(len(symbols)-1), 1)
+	if symbols[0] < 2 {
+		w.write(0, 1)
+		w.write(uint32(symbols[0]), 1)
+	} else {
+		w.write(1, 1)
+		w.write(uint32(symbols[0]), 8)
+	}
+	if len(symbols) == 2 {
+		w.write(uint32(symbols[1]), 8)
+	}
+}
+
+// writeSymbol writes the code of sym. The only symbol of a one-symbol code
+// takes no bits.
+func (c prefixCode) writeSymbol(w *bitWriter, sym int) {
+	if len(c.used) > 1 {
+		w.write(c.codes[sym], uint(c.lengths[sym]))
+	}
+}
+
+// huffmanLengths returns the lengths of a Huffman code for symbols occurring
+// counts times, leaving unused symbols at 0. If the code would be longer than
+// maxLength, rare symbols are made more common until it is not.
+func huffmanLengths(counts []int, maxLength int) []int {
+	lengths := make([]int, len(counts))
+	floor := 1
+	for {
+		h := &nodeHeap{}
+		for sym, n := range counts {
+			if n > 0 {
+				if n < floor {
+					n = floor
+				}
+				*h = append(*h, &huffmanNode{count: n, symbol: sym, order: sym})
+			}
+		}
+		if h.Len() < 2 {
+			for _, n := range *h {
+				lengths[n.symbol] = 1
+			}
+			return lengths
+		}
+		heap.Init(h)
+		order := len(counts)
+		for h.Len() > 1 {
+			a := heap.Pop(h).(*huffmanNode)
+			b := heap.Pop(h).(*huffmanNode)
+			heap.Push(h, &huffmanNode{count: a.count + b.count, symbol: -1, order: order, children: [2]*huffmanNode{a, b}})
+			order++
+		}
+		if depth := assignLengths((*h)[0], 0, lengths); depth <= maxLength {
+			return lengths
+		}
+		floor *= 2
+	}
+}
+
+type huffmanNode struct {
+	count int
+	// symbol is -1 for nodes merged from two others.
+	symbol int
+	// order breaks ties between nodes of the same count.
+	order    int
+	children [2]*huffmanNode
+}
+
+// assignLengths sets the lengths of the symbols under n, at depth, and
+// returns the greatest.
+func assignLengths(n *huffmanNode, depth int, lengths []int) int {
+	if n.symbol >= 0 {
+		lengths[n.symbol] = depth
+		return depth
+	}
+	left := assignLengths(n.children[0], depth+1, lengths)
+	right := assignLengths(n.children[1], depth+1, lengths)
+	if left > right {
+		return left
+	}
+	return right
+}
+
+// nodeHeap orders nodes by count, then leaves by symbol before merged nodes
+// by when they were merged, so that the code is deterministic.
+type nodeHeap []*huffmanNode
+
+func (h nodeHeap) Len() int { return len(h) }
+func (h nodeHeap) Less(i, j int) bool {
+	if h[i].count != h[j].count {
+		return h[i].count < h[j].count
+	}
+	return h[i].order < h[j].order
+}
+func (h nodeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *nodeHeap) Push(x interface{}) { *h = append(*h, x.(*huffmanNode)) }
+func (h *nodeHeap) Pop() interface{} {
+	old := *h
+	n := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return n
+}
+
+// canonicalCodes assigns codes to lengths the way decoders rebuild them:
+// shorter codes first, and codes of the same length in symbol order.
+func canonicalCodes(lengths []int) []uint32 {
+	var perLength [16]uint32
+	for _, l := range lengths {
+		if l > 0 {
+			perLength[l]++
+		}
+	}
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l < 16; l++ {
+		code = (code + perLength[l-1]) << 1
+		next[l] = code
+	}
+	codes := make([]uint32, len(lengths))
+	for sym, l := range lengths {
+		if l == 0 {
+			continue
+		}
+		codes[sym] = reverseBits(next[l], l)
+		next[l]++
+	}
+	return codes
+}
+
+func reverseBits(v uint32, n int) uint32 {
+	var r uint32
+	for i := 0; i < n; i++ {
+		r = r<<1 | v&1
+		v >>= 1
+	}
+	return r
+}
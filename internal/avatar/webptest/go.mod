@@ -0,0 +1,13 @@
+// Module webptest checks the WebP encoder against a reference decoder. It is
+// a module of its own so that the decoder stays out of the service's
+// dependencies; run it with `go test` from this directory.
+module github.com/millbj92/nuboverflow-users/internal/avatar/webptest
+
+go 1.17
+
+require (
+	github.com/millbj92/nuboverflow-users v0.0.0
+	golang.org/x/image v0.18.0
+)
+
+replace github.com/millbj92/nuboverflow-users => ../../..
@@ -0,0 +1,67 @@
+package webptest
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	gradient := image.NewNRGBA(image.Rect(0, 0, 96, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 96; x++ {
+			gradient.SetNRGBA(x, y, color.NRGBA{R: uint8(x * 2), G: uint8(y * 3), B: 0x80, A: 0xFF})
+		}
+	}
+	noise := image.NewNRGBA(image.Rect(0, 0, 37, 23))
+	rand.New(rand.NewSource(1)).Read(noise.Pix)
+	flat := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	for i := range flat.Pix {
+		flat.Pix[i] = 0x42
+	}
+	offset := image.NewNRGBA(image.Rect(5, 7, 21, 19))
+	rand.New(rand.NewSource(2)).Read(offset.Pix)
+	for i := 3; i < len(offset.Pix); i += 4 {
+		offset.Pix[i] = 0xFF
+	}
+	dot := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+	dot.SetNRGBA(0, 0, color.NRGBA{R: 1, G: 2, B: 3, A: 4})
+
+	for name, img := range map[string]*image.NRGBA{
+		"gradient": gradient,
+		"noise":    noise,
+		"flat":     flat,
+		"offset":   offset,
+		"dot":      dot,
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := avatar.Encode(img, avatar.WebP)
+			if err != nil {
+				t.Fatalf("encoding: %s", err)
+			}
+			decoded, err := webp.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decoding: %s", err)
+			}
+			b := img.Bounds()
+			if got := decoded.Bounds(); got.Dx() != b.Dx() || got.Dy() != b.Dy() {
+				t.Fatalf("decoded a %v image, want %dx%d", got, b.Dx(), b.Dy())
+			}
+			origin := decoded.Bounds().Min
+			for y := 0; y < b.Dy(); y++ {
+				for x := 0; x < b.Dx(); x++ {
+					want := img.NRGBAAt(b.Min.X+x, b.Min.Y+y)
+					got := color.NRGBAModel.Convert(decoded.At(origin.X+x, origin.Y+y)).(color.NRGBA)
+					if got != want {
+						t.Fatalf("pixel (%d, %d) is %v, want %v", x, y, got, want)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package badges decides which badges users earn as things happen to them.
+package badges
+
+import (
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// Event is something that happened to a user that may earn them badges. User
+// is the user after it happened; Reputation is set if it was a reputation
+// event and nil if the user's profile changed.
+type Event struct {
+	User       user.User
+	Reputation *user.ReputationEvent
+}
+
+// Rule awards Badge for the events Match accepts. For repeatable badges,
+// Match also returns the key that tells repeat awards apart, such as the
+// answer the badge was earned for; one-time badges ignore it.
+type Rule struct {
+	Badge user.Badge
+	Match func(Event) (key string, ok bool)
+}
+
+// Engine evaluates events against a set of rules.
+type Engine struct {
+	rules []Rule
+}
+
+// NewEngine returns an Engine awarding badges by rules.
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Badges returns the badges the Engine can award.
+func (e *Engine) Badges() []user.Badge {
+	badges := make([]user.Badge, len(e.rules))
+	for i, rule := range e.rules {
+		badges[i] = rule.Badge
+	}
+	return badges
+}
+
+// Evaluate returns the awards event earns. It doesn't know what the user was
+// awarded before, so storing them has to skip those already held.
+func (e *Engine) Evaluate(event Event) []user.Award {
+	var awards []user.Award
+	for _, rule := range e.rules {
+		key, ok := rule.Match(event)
+		if !ok {
+			continue
+		}
+		if !rule.Badge.Repeatable {
+			key = ""
+		}
+		awards = append(awards, user.Award{
+			UserID: event.User.ID,
+			Badge:  rule.Badge.Name,
+			Class:  rule.Badge.Class,
+			Key:    key,
+		})
+	}
+	return awards
+}
@@ -0,0 +1,52 @@
+package badges
+
+import (
+	"testing"
+
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine(t *testing.T) {
+	engine := NewEngine(DefaultRules...)
+
+	t.Run("Tests Autobiographer needs the whole profile", func(t *testing.T) {
+		partial := user.User{ID: 1, Bio: "Gopher", Profession: "Engineer"}
+		assert.Empty(t, engine.Evaluate(Event{User: partial}))
+
+		complete := partial
+		complete.WorkPlace = "Nuboverflow"
+		assert.Equal(t, []user.Award{{UserID: 1, Badge: "Autobiographer", Class: user.Bronze}}, engine.Evaluate(Event{User: complete}))
+	})
+
+	t.Run("Tests repeatable badges are keyed by what earned them", func(t *testing.T) {
+		accepted := func(answer int) Event {
+			return Event{
+				User:       user.User{ID: 2, UserScore: 30},
+				Reputation: &user.ReputationEvent{UserID: 2, Reason: ReasonAnswerAccepted, Delta: 15, ReferenceType: "answer", ReferenceID: answer},
+			}
+		}
+		assert.Equal(t, "answer:7", engine.Evaluate(accepted(7))[0].Key)
+		assert.Equal(t, "answer:8", engine.Evaluate(accepted(8))[0].Key)
+
+		// Undoing an accept doesn't earn anything.
+		undone := accepted(7)
+		undone.Reputation.Delta = -15
+		assert.Empty(t, engine.Evaluate(undone))
+	})
+
+	t.Run("Tests reputation milestones are only checked on reputation events", func(t *testing.T) {
+		rich := user.User{ID: 3, UserScore: 25000}
+		assert.Empty(t, engine.Evaluate(Event{User: rich}))
+
+		awards := engine.Evaluate(Event{User: rich, Reputation: &user.ReputationEvent{UserID: 3, Reason: "answer_upvoted", Delta: 10}})
+		assert.Equal(t, []user.Award{
+			{UserID: 3, Badge: "Established", Class: user.Silver},
+			{UserID: 3, Badge: "Trusted", Class: user.Gold},
+		}, awards)
+	})
+
+	t.Run("Tests badges lists every rule's badge", func(t *testing.T) {
+		assert.Len(t, engine.Badges(), len(DefaultRules))
+	})
+}
@@ -0,0 +1,75 @@
+package badges
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// ReasonAnswerAccepted is the reputation event reason other services use when
+// an answer is accepted.
+const ReasonAnswerAccepted = "answer_accepted"
+
+// DefaultRules are the badges Nuboverflow awards.
+var DefaultRules = []Rule{
+	{
+		Badge: user.Badge{
+			Name:        "Autobiographer",
+			Description: "Filled in bio, profession and workplace",
+			Class:       user.Bronze,
+		},
+		Match: profileComplete,
+	},
+	{
+		Badge: user.Badge{
+			Name:        "Accepted",
+			Description: "Answer was accepted",
+			Class:       user.Bronze,
+			Repeatable:  true,
+		},
+		Match: answerAccepted,
+	},
+	{
+		Badge: user.Badge{
+			Name:        "Established",
+			Description: "Earned 1,000 reputation",
+			Class:       user.Silver,
+		},
+		Match: reputationReached(1000),
+	},
+	{
+		Badge: user.Badge{
+			Name:        "Trusted",
+			Description: "Earned 20,000 reputation",
+			Class:       user.Gold,
+		},
+		Match: reputationReached(20000),
+	},
+}
+
+func profileComplete(event Event) (string, bool) {
+	u := event.User
+	return "", strings.TrimSpace(u.Bio) != "" &&
+		strings.TrimSpace(u.Profession) != "" &&
+		strings.TrimSpace(u.WorkPlace) != ""
+}
+
+func answerAccepted(event Event) (string, bool) {
+	rep := event.Reputation
+	if rep == nil || rep.Reason != ReasonAnswerAccepted || rep.Delta <= 0 {
+		return "", false
+	}
+	if rep.ReferenceID != 0 {
+		return fmt.Sprintf("%s:%d", rep.ReferenceType, rep.ReferenceID), true
+	}
+	return rep.Source + ":" + rep.IdempotencyKey, true
+}
+
+// reputationReached matches reputation events leaving the user with at
+// least score reputation.
+func reputationReached(score int) func(Event) (string, bool) {
+	return func(event Event) (string, bool) {
+		return "", event.Reputation != nil && event.User.UserScore >= score
+	}
+}
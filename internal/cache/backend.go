@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend stores cached values by key. Implementations must be safe for
+// concurrent use. A Get of a missing or expired key reports ok == false.
+type Backend interface {
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// tiered checks a fast local Backend before a shared one, copying shared
+// hits into the local one. Writes and deletes go to both.
+type tiered struct {
+	local, shared Backend
+	localTTL      time.Duration
+}
+
+// Tiered returns a Backend that puts local in front of shared. Entries are
+// kept in local for at most localTTL, which bounds how long a process can
+// serve a value another process has since invalidated in shared.
+func Tiered(local, shared Backend, localTTL time.Duration) Backend {
+	return &tiered{
+		local:    local,
+		shared:   shared,
+		localTTL: localTTL,
+	}
+}
+
+func (t *tiered) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, ok, err := t.local.Get(ctx, key); err == nil && ok {
+		return value, true, nil
+	}
+	value, ok, err := t.shared.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	return value, true, t.local.Set(ctx, key, value, t.localTTL)
+}
+
+func (t *tiered) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	localTTL := t.localTTL
+	if ttl < localTTL {
+		localTTL = ttl
+	}
+	if err := t.local.Set(ctx, key, value, localTTL); err != nil {
+		return err
+	}
+	return t.shared.Set(ctx, key, value, ttl)
+}
+
+func (t *tiered) Delete(ctx context.Context, keys ...string) error {
+	if err := t.local.Delete(ctx, keys...); err != nil {
+		return err
+	}
+	return t.shared.Delete(ctx, keys...)
+}
@@ -0,0 +1,431 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gomock "github.com/golang/mock/gomock"
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// newLRU returns an LRU holding at most capacity entries.
+func newLRU(t *testing.T, capacity int) *LRU {
+	lru, err := NewLRU(capacity)
+	if err != nil {
+		t.Fatalf("creating LRU: %s", err)
+	}
+	return lru
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Tests the capacity must be positive", func(t *testing.T) {
+		for _, capacity := range []int{0, -1} {
+			_, err := NewLRU(capacity)
+			assert.Error(t, err, capacity)
+		}
+	})
+
+	t.Run("Tests the least recently used entry is evicted", func(t *testing.T) {
+		lru := newLRU(t, 2)
+		lru.Set(ctx, "a", []byte("1"), time.Minute)
+		lru.Set(ctx, "b", []byte("2"), time.Minute)
+		lru.Get(ctx, "a")
+		lru.Set(ctx, "c", []byte("3"), time.Minute)
+
+		_, ok, _ := lru.Get(ctx, "b")
+		assert.False(t, ok)
+		value, ok, _ := lru.Get(ctx, "a")
+		assert.True(t, ok)
+		assert.Equal(t, []byte("1"), value)
+		assert.Equal(t, 2, lru.Len())
+	})
+
+	t.Run("Tests expired entries are misses", func(t *testing.T) {
+		lru := newLRU(t, 2)
+		lru.Set(ctx, "a", []byte("1"), -time.Second)
+		_, ok, _ := lru.Get(ctx, "a")
+		assert.False(t, ok)
+		assert.Equal(t, 0, lru.Len())
+	})
+}
+
+func TestStore(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	ctx := context.Background()
+
+	t.Run("Tests lookups are cached until the user is updated", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		metrics := &Metrics{}
+		s := NewStore(baseMock, newLRU(t, 10), WithMetrics(metrics))
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1, UserName: "bob", Password: "hash"}, nil)
+		for i := 0; i < 3; i++ {
+			usr, err := s.GetUserByID(ctx, 1)
+			assert.NoError(t, err)
+			assert.Equal(t, "bob", usr.UserName)
+			assert.Equal(t, "hash", usr.Password)
+		}
+		assert.Equal(t, Stats{Hits: 2, Misses: 1, HitRatio: 2.0 / 3}, metrics.Stats())
+
+		baseMock.EXPECT().UpdateUser(gomock.Any(), user.User{ID: 1, Bio: "hi"}).Return(user.User{ID: 1, UserName: "bob", Bio: "hi"}, nil)
+		_, err := s.UpdateUser(ctx, user.User{ID: 1, Bio: "hi"})
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1, UserName: "bob", Bio: "hi"}, nil)
+		usr, err := s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "hi", usr.Bio)
+	})
+
+	t.Run("Tests deleted users are invalidated", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1}, nil)
+		_, err := s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().DeleteUser(gomock.Any(), 1).Return(nil)
+		assert.NoError(t, s.DeleteUser(ctx, 1))
+
+		notFound := errors.New("user not found")
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{}, notFound)
+		_, err = s.GetUserByID(ctx, 1)
+		assert.Equal(t, notFound, err)
+	})
+
+	t.Run("Tests concurrent misses share one load", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		release := make(chan struct{})
+		baseMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 1).
+			DoAndReturn(func(context.Context, int) (user.User, error) {
+				<-release
+				return user.User{ID: 1, UserName: "bob"}, nil
+			}).
+			Times(1)
+
+		const lookups = 50
+		var started, done sync.WaitGroup
+		started.Add(lookups)
+		done.Add(lookups)
+		for i := 0; i < lookups; i++ {
+			go func() {
+				defer done.Done()
+				started.Done()
+				usr, err := s.GetUserByID(ctx, 1)
+				assert.NoError(t, err)
+				assert.Equal(t, "bob", usr.UserName)
+			}()
+		}
+		started.Wait()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		done.Wait()
+	})
+
+	t.Run("Tests a shared load outlives the caller that started it", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		loading, release := make(chan struct{}), make(chan struct{})
+		baseMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 1).
+			DoAndReturn(func(ctx context.Context, _ int) (user.User, error) {
+				close(loading)
+				<-release
+				if err := ctx.Err(); err != nil {
+					return user.User{}, err
+				}
+				return user.User{ID: 1, UserName: "bob"}, nil
+			}).
+			Times(1)
+
+		firstCtx, cancel := context.WithCancel(ctx)
+		first := make(chan error)
+		go func() {
+			_, err := s.GetUserByID(firstCtx, 1)
+			first <- err
+		}()
+		<-loading
+
+		second := make(chan user.User)
+		go func() {
+			usr, err := s.GetUserByID(ctx, 1)
+			assert.NoError(t, err)
+			second <- usr
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		assert.Equal(t, context.Canceled, <-first)
+
+		close(release)
+		assert.Equal(t, "bob", (<-second).UserName)
+	})
+
+	t.Run("Tests a load racing an update is not cached", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		loading, release := make(chan struct{}), make(chan struct{})
+		baseMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 1).
+			DoAndReturn(func(context.Context, int) (user.User, error) {
+				close(loading)
+				<-release
+				return user.User{ID: 1, Bio: "old"}, nil
+			})
+		go func() {
+			<-loading
+			baseMock.EXPECT().UpdateUser(gomock.Any(), gomock.Any()).Return(user.User{ID: 1, Bio: "new"}, nil)
+			s.UpdateUser(ctx, user.User{ID: 1, Bio: "new"})
+			close(release)
+		}()
+		usr, err := s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "old", usr.Bio)
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1, Bio: "new"}, nil)
+		usr, err = s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "new", usr.Bio)
+	})
+
+	t.Run("Tests batch lookups only load the users not cached", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1}, nil)
+		_, err := s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{2, 3}).Return([]user.User{{ID: 2}}, nil)
+		users, err := s.GetUsersByIDs(ctx, []int{1, 2, 3})
+		assert.NoError(t, err)
+		assert.Equal(t, []user.User{{ID: 1}, {ID: 2}}, users)
+
+		users, err = s.GetUsersByIDs(ctx, []int{2, 1})
+		assert.NoError(t, err)
+		assert.Equal(t, []user.User{{ID: 2}, {ID: 1}}, users)
+	})
+
+	t.Run("Tests lookups of some fields use cached users but don't fill the cache", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+		partial := repository.WithFields(ctx, "user_name")
+
+		baseMock.EXPECT().GetUserByID(partial, 1).Return(user.User{ID: 1, UserName: "bob"}, nil).Times(2)
+		baseMock.EXPECT().GetUsersByIDs(partial, []int{1}).Return([]user.User{{ID: 1, UserName: "bob"}}, nil)
+		for i := 0; i < 2; i++ {
+			_, err := s.GetUserByID(partial, 1)
+			assert.NoError(t, err)
+		}
+		_, err := s.GetUsersByIDs(partial, []int{1})
+		assert.NoError(t, err)
+
+		// Full loads are shared, so they run with a context of their own.
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1, UserName: "bob", Bio: "hi"}, nil)
+		_, err = s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+		usr, err := s.GetUserByID(partial, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "hi", usr.Bio)
+	})
+
+	t.Run("Tests reputation changes invalidate the users", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2}).Return([]user.User{{ID: 1}, {ID: 2}}, nil)
+		_, err := s.GetUsersByIDs(ctx, []int{1, 2})
+		assert.NoError(t, err)
+
+		event := user.ReputationEvent{UserID: 1, Delta: 5}
+		baseMock.EXPECT().AddReputationEvent(gomock.Any(), event).Return(event, true, nil)
+		_, _, err = s.AddReputationEvent(ctx, event)
+		assert.NoError(t, err)
+		baseMock.EXPECT().RecalculateUserScores(gomock.Any()).Return([]int{2}, nil)
+		_, err = s.RecalculateUserScores(ctx)
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2}).Return([]user.User{{ID: 1, UserScore: 5}, {ID: 2, UserScore: 3}}, nil)
+		users, err := s.GetUsersByIDs(ctx, []int{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []user.User{{ID: 1, UserScore: 5}, {ID: 2, UserScore: 3}}, users)
+	})
+
+	t.Run("Tests follows invalidate both users", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2}).Return([]user.User{{ID: 1}, {ID: 2}}, nil)
+		_, err := s.GetUsersByIDs(ctx, []int{1, 2})
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().Follow(gomock.Any(), 1, 2).Return(true, nil)
+		_, err = s.Follow(ctx, 1, 2)
+		assert.NoError(t, err)
+
+		followed := []user.User{{ID: 1, FollowingCount: 1}, {ID: 2, FollowerCount: 1}}
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2}).Return(followed, nil)
+		users, err := s.GetUsersByIDs(ctx, []int{1, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, followed, users)
+	})
+
+	t.Run("Tests profile field changes invalidate the users holding values", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		s := NewStore(baseMock, newLRU(t, 10))
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2, 3}).Return([]user.User{{ID: 1}, {ID: 2}, {ID: 3}}, nil)
+		_, err := s.GetUsersByIDs(ctx, []int{1, 2, 3})
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().SetProfileFieldValues(gomock.Any(), 1, map[string]string{"pronouns": "she/her"}).Return(user.User{ID: 1}, nil)
+		_, err = s.SetProfileFieldValues(ctx, 1, map[string]string{"pronouns": "she/her"})
+		assert.NoError(t, err)
+		baseMock.EXPECT().DeleteProfileField(gomock.Any(), "years").Return([]int{2}, nil)
+		_, err = s.DeleteProfileField(ctx, "years")
+		assert.NoError(t, err)
+
+		baseMock.EXPECT().GetUsersByIDs(gomock.Any(), []int{1, 2}).Return([]user.User{{ID: 1}, {ID: 2}}, nil)
+		_, err = s.GetUsersByIDs(ctx, []int{1, 2, 3})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Tests backend failures fall through to the database", func(t *testing.T) {
+		baseMock := NewMockStore(mockCtrl)
+		metrics := &Metrics{}
+		s := NewStore(baseMock, NewRedis("127.0.0.1:1", 1), WithMetrics(metrics))
+
+		baseMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(user.User{ID: 1}, nil)
+		usr, err := s.GetUserByID(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, usr.ID)
+		assert.Equal(t, uint64(2), metrics.Stats().Errors)
+	})
+}
+
+// fakeRedis serves GET, SET and DEL from a map, speaking just enough RESP for Redis.
+func fakeRedis(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var mu sync.Mutex
+	data := map[string]string{}
+	serve := func(conn net.Conn) {
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			header, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+			args := make([]string, n)
+			for i := range args {
+				size, _ := r.ReadString('\n')
+				n, _ := strconv.Atoi(strings.TrimSpace(size[1:]))
+				arg := make([]byte, n+2)
+				io.ReadFull(r, arg)
+				args[i] = string(arg[:n])
+			}
+			mu.Lock()
+			switch strings.ToUpper(args[0]) {
+			case "GET":
+				if value, ok := data[args[1]]; ok {
+					conn.Write([]byte("$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"))
+				} else {
+					conn.Write([]byte("$-1\r\n"))
+				}
+			case "SET":
+				data[args[1]] = args[2]
+				conn.Write([]byte("+OK\r\n"))
+			case "DEL":
+				deleted := 0
+				for _, key := range args[1:] {
+					if _, ok := data[key]; ok {
+						delete(data, key)
+						deleted++
+					}
+				}
+				conn.Write([]byte(":" + strconv.Itoa(deleted) + "\r\n"))
+			default:
+				conn.Write([]byte("-ERR unknown command\r\n"))
+			}
+			mu.Unlock()
+		}
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	r := NewRedis(fakeRedis(t), 2)
+	defer r.Close()
+
+	_, ok, err := r.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, r.Set(ctx, "a", []byte("binary\r\nvalue"), time.Minute))
+	value, ok, err := r.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("binary\r\nvalue"), value)
+
+	assert.NoError(t, r.Delete(ctx, "a", "b"))
+	_, ok, err = r.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = r.do(ctx, "FLUSHALL")
+	assert.EqualError(t, err, "redis: ERR unknown command")
+}
+
+func TestTiered(t *testing.T) {
+	ctx := context.Background()
+	local, shared := newLRU(t, 10), newLRU(t, 10)
+	tiers := Tiered(local, shared, time.Minute)
+
+	shared.Set(ctx, "a", []byte("1"), time.Minute)
+	value, ok, err := tiers.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+	assert.Equal(t, 1, local.Len())
+
+	assert.NoError(t, tiers.Delete(ctx, "a"))
+	assert.Equal(t, 0, local.Len())
+	assert.Equal(t, 0, shared.Len())
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// loadTimeout bounds a shared load, which no single caller can cancel.
+const loadTimeout = 5 * time.Second
+
+// call is a database load in flight, shared by every lookup of its key that
+// misses while it runs.
+type call struct {
+	done chan struct{}
+	usr  user.User
+	err  error
+}
+
+// flightGroup de-duplicates concurrent loads of the same key.
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+// do runs load once for all concurrent callers with the same key and gives
+// each of them its result. The load runs with a context detached from the
+// caller that started it, so it is not cut short for the others when that
+// caller goes away, and is bounded by loadTimeout instead. Each caller stops
+// waiting when its own ctx is done.
+func (g *flightGroup) do(ctx context.Context, key string, load func(ctx context.Context) (user.User, error)) (user.User, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call{}
+	}
+	c, ok := g.calls[key]
+	if !ok {
+		c = &call{done: make(chan struct{})}
+		g.calls[key] = c
+		go g.run(ctx, key, c, load)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.usr, c.err
+	case <-ctx.Done():
+		return user.User{}, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(ctx context.Context, key string, c *call, load func(ctx context.Context) (user.User, error)) {
+	ctx, cancel := context.WithTimeout(detached{ctx}, loadTimeout)
+	defer cancel()
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+	c.usr, c.err = load(ctx)
+}
+
+// detached is a context with the values of its parent but none of its
+// deadline or cancellation.
+type detached struct {
+	parent context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detached) Done() <-chan struct{} { return nil }
+
+func (detached) Err() error { return nil }
+
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
@@ -0,0 +1,36 @@
+package cache
+
+import "sync/atomic"
+
+// Stats is a snapshot of cache Metrics.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Errors counts backend failures. Lookups that fail count as misses too,
+	// since they fall through to the database.
+	Errors uint64 `json:"errors"`
+	// HitRatio is Hits / (Hits + Misses), or 0 before any lookup.
+	HitRatio float64 `json:"hitRatio"`
+}
+
+// Metrics counts cache lookups. It is safe for concurrent use.
+type Metrics struct {
+	hits, misses, errors uint64
+}
+
+func (m *Metrics) hit()     { atomic.AddUint64(&m.hits, 1) }
+func (m *Metrics) miss()    { atomic.AddUint64(&m.misses, 1) }
+func (m *Metrics) failure() { atomic.AddUint64(&m.errors, 1) }
+
+// Stats returns the counts so far.
+func (m *Metrics) Stats() Stats {
+	stats := Stats{
+		Hits:   atomic.LoadUint64(&m.hits),
+		Misses: atomic.LoadUint64(&m.misses),
+		Errors: atomic.LoadUint64(&m.errors),
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
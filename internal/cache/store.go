@@ -0,0 +1,279 @@
+//go:generate mockgen -destination=store_mocks_test.go -package=cache github.com/millbj92/nuboverflow-users/internal/repository Store
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// DefaultTTL is how long a user stays cached when no other TTL is configured.
+const DefaultTTL = 5 * time.Minute
+
+// store decorates a repository.Store, answering GetUserByID from a Backend
+// and falling through to the wrapped Store on a miss. Every write through it
+// invalidates the users it touches.
+type store struct {
+	repository.Store
+	backend Backend
+	ttl     time.Duration
+	metrics *Metrics
+	flight  flightGroup
+
+	// generation counts invalidations. Loads only fill the cache if it did
+	// not change while they ran, since what they read may predate a write.
+	// mu is held for writing while it changes and for reading while filling.
+	mu         sync.RWMutex
+	generation uint64
+}
+
+// Option configures optional behaviour of the caching store.
+type Option func(*store)
+
+// WithTTL sets how long a user stays cached.
+func WithTTL(ttl time.Duration) Option {
+	return func(s *store) {
+		s.ttl = ttl
+	}
+}
+
+// WithMetrics makes the store count its hits and misses in metrics.
+func WithMetrics(metrics *Metrics) Option {
+	return func(s *store) {
+		s.metrics = metrics
+	}
+}
+
+// NewStore returns a Store that caches the users base returns from GetUserByID in backend.
+func NewStore(base repository.Store, backend Backend, opts ...Option) repository.Store {
+	s := &store{
+		Store:   base,
+		backend: backend,
+		ttl:     DefaultTTL,
+		metrics: &Metrics{},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func userKey(id int) string {
+	return "users:id:" + strconv.Itoa(id)
+}
+
+// GetUserByID answers from the cache when it can. Concurrent misses for the
+// same user share one database load, made with the values of whichever
+// caller missed first but not its cancellation.
+//
+// A cached user has every field, so it also answers lookups limited with
+// repository.WithFields; those load only part of a user on a miss, which is
+// neither cached nor shared.
+func (s *store) GetUserByID(ctx context.Context, id int) (user.User, error) {
+	key := userKey(id)
+	if usr, ok := s.lookup(ctx, key); ok {
+		s.metrics.hit()
+		return usr, nil
+	}
+	s.metrics.miss()
+	if repository.FieldsFrom(ctx) != nil {
+		return s.Store.GetUserByID(ctx, id)
+	}
+	return s.flight.do(ctx, key, func(ctx context.Context) (user.User, error) {
+		generation := s.currentGeneration()
+		usr, err := s.Store.GetUserByID(ctx, id)
+		if err != nil {
+			return user.User{}, err
+		}
+		s.fill(ctx, generation, usr)
+		return usr, nil
+	})
+}
+
+// GetUsersByIDs answers what it can from the cache and loads the rest from
+// the database in one query.
+func (s *store) GetUsersByIDs(ctx context.Context, ids []int) ([]user.User, error) {
+	users := make([]user.User, 0, len(ids))
+	var missing []int
+	for _, id := range ids {
+		if usr, ok := s.lookup(ctx, userKey(id)); ok {
+			s.metrics.hit()
+			users = append(users, usr)
+		} else {
+			s.metrics.miss()
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return users, nil
+	}
+	generation := s.currentGeneration()
+	loaded, err := s.Store.GetUsersByIDs(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	if repository.FieldsFrom(ctx) == nil {
+		s.fill(ctx, generation, loaded...)
+	}
+	return append(users, loaded...), nil
+}
+
+func (s *store) UpdateUser(ctx context.Context, usr user.User) (user.User, error) {
+	defer s.invalidate(ctx, usr.ID)
+	return s.Store.UpdateUser(ctx, usr)
+}
+
+func (s *store) DeleteUser(ctx context.Context, id int) error {
+	defer s.invalidate(ctx, id)
+	return s.Store.DeleteUser(ctx, id)
+}
+
+func (s *store) RestoreUser(ctx context.Context, id int) (user.User, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.RestoreUser(ctx, id)
+}
+
+func (s *store) UpdatePrivacy(ctx context.Context, id int, privacy user.Privacy) (user.User, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.UpdatePrivacy(ctx, id, privacy)
+}
+
+func (s *store) UpdateAvatar(ctx context.Context, id int, hash string) (user.User, string, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.UpdateAvatar(ctx, id, hash)
+}
+
+func (s *store) VerifyGithub(ctx context.Context, id int, handle string) (user.User, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.VerifyGithub(ctx, id, handle)
+}
+
+func (s *store) SetProfileFieldValues(ctx context.Context, id int, values map[string]string) (user.User, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.SetProfileFieldValues(ctx, id, values)
+}
+
+// SaveProfileField invalidates the users holding values of the field if
+// whether others see them changed.
+func (s *store) SaveProfileField(ctx context.Context, field user.ProfileField) (user.ProfileField, []int, error) {
+	saved, affected, err := s.Store.SaveProfileField(ctx, field)
+	for _, id := range affected {
+		s.invalidate(ctx, id)
+	}
+	return saved, affected, err
+}
+
+func (s *store) DeleteProfileField(ctx context.Context, name string) ([]int, error) {
+	affected, err := s.Store.DeleteProfileField(ctx, name)
+	for _, id := range affected {
+		s.invalidate(ctx, id)
+	}
+	return affected, err
+}
+
+func (s *store) RenameUser(ctx context.Context, id int, name string) (user.User, error) {
+	defer s.invalidate(ctx, id)
+	return s.Store.RenameUser(ctx, id, name)
+}
+
+func (s *store) AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	defer s.invalidate(ctx, event.UserID)
+	return s.Store.AddReputationEvent(ctx, event)
+}
+
+func (s *store) RecalculateUserScores(ctx context.Context) ([]int, error) {
+	fixed, err := s.Store.RecalculateUserScores(ctx)
+	for _, id := range fixed {
+		s.invalidate(ctx, id)
+	}
+	return fixed, err
+}
+
+func (s *store) Follow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	defer s.invalidate(ctx, followerID)
+	defer s.invalidate(ctx, followeeID)
+	return s.Store.Follow(ctx, followerID, followeeID)
+}
+
+func (s *store) Unfollow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	defer s.invalidate(ctx, followerID)
+	defer s.invalidate(ctx, followeeID)
+	return s.Store.Unfollow(ctx, followerID, followeeID)
+}
+
+// BlockUser invalidates both users, whose follows of each other it removes.
+func (s *store) BlockUser(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	defer s.invalidate(ctx, blockerID)
+	defer s.invalidate(ctx, blockedID)
+	return s.Store.BlockUser(ctx, blockerID, blockedID)
+}
+
+// lookup returns the cached user under key. Backend failures are counted
+// and treated as misses so the database can still answer.
+func (s *store) lookup(ctx context.Context, key string) (user.User, bool) {
+	value, ok, err := s.backend.Get(ctx, key)
+	if err != nil {
+		s.metrics.failure()
+		log.Printf("CACHE ERROR: %s", err.Error())
+		return user.User{}, false
+	}
+	if !ok {
+		return user.User{}, false
+	}
+	var usr user.User
+	// gob rather than JSON, which would drop the fields tagged json:"-".
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&usr); err != nil {
+		s.metrics.failure()
+		log.Printf("CACHE ERROR: decoding %s: %s", key, err.Error())
+		return user.User{}, false
+	}
+	return usr, true
+}
+
+func (s *store) currentGeneration() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.generation
+}
+
+// fill caches users loaded since generation was read, unless something was
+// invalidated in the meantime.
+func (s *store) fill(ctx context.Context, generation uint64, users ...user.User) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.generation != generation {
+		return
+	}
+	for _, usr := range users {
+		key := userKey(usr.ID)
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(usr); err != nil {
+			log.Printf("CACHE ERROR: encoding %s: %s", key, err.Error())
+			continue
+		}
+		if err := s.backend.Set(ctx, key, buf.Bytes(), s.ttl); err != nil {
+			s.metrics.failure()
+			log.Printf("CACHE ERROR: %s", err.Error())
+		}
+	}
+}
+
+// invalidate drops the cached user with id. It runs whether or not the write
+// succeeded, since a failed write may still have reached the database.
+func (s *store) invalidate(ctx context.Context, id int) {
+	s.mu.Lock()
+	s.generation++
+	s.mu.Unlock()
+	key := userKey(id)
+	if err := s.backend.Delete(ctx, key); err != nil {
+		s.metrics.failure()
+		log.Printf("CACHE ERROR: invalidating %s: %s", key, err.Error())
+	}
+}
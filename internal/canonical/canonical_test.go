@@ -0,0 +1,157 @@
+package canonical
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmail(t *testing.T) {
+	t.Run("Tests emails are case-folded and trimmed", func(t *testing.T) {
+		email, err := EmailRules{}.Email("  Bob@Example.COM ")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob@example.com", email)
+	})
+
+	t.Run("Tests international domains are converted to ASCII", func(t *testing.T) {
+		email, err := EmailRules{}.Email("hans@Bücher.example")
+		assert.NoError(t, err)
+		assert.Equal(t, "hans@xn--bcher-kva.example", email)
+	})
+
+	t.Run("Tests provider rules only apply when enabled", func(t *testing.T) {
+		email, err := EmailRules{}.Email("Bob.Smith+news@googlemail.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob.smith+news@googlemail.com", email)
+
+		email, err = EmailRules{ProviderRules: true}.Email("Bob.Smith+news@googlemail.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "bobsmith@gmail.com", email)
+
+		email, err = EmailRules{ProviderRules: true}.Email("bob.smith+news@outlook.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob.smith@outlook.com", email)
+
+		email, err = EmailRules{ProviderRules: true}.Email("bob.smith+news@example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob.smith+news@example.com", email)
+	})
+
+	t.Run("Tests malformed emails are rejected", func(t *testing.T) {
+		for _, address := range []string{"", "bob", "@example.com", "bob@", "bob@exa mple.com"} {
+			_, err := EmailRules{}.Email(address)
+			assert.ErrorIs(t, err, ErrInvalidEmail, address)
+		}
+	})
+}
+
+func TestUserName(t *testing.T) {
+	t.Run("Tests usernames are stored in NFKC", func(t *testing.T) {
+		name, err := UserName(" ｂｏｂ ")
+		assert.NoError(t, err)
+		assert.Equal(t, "bob", name)
+	})
+
+	t.Run("Tests invisible characters are rejected", func(t *testing.T) {
+		for _, name := range []string{"", "   ", "bo\u200bb", "bob\x00"} {
+			_, err := UserName(name)
+			assert.ErrorIs(t, err, ErrInvalidUserName, name)
+		}
+	})
+
+	t.Run("Tests lookalike usernames share a key", func(t *testing.T) {
+		key, err := UserNameKey("paypal")
+		assert.NoError(t, err)
+		for _, name := range []string{"PayPal", "pаypаl", "PAYPA1", "ｐａｙｐａｌ"} {
+			other, err := UserNameKey(name)
+			assert.NoError(t, err)
+			assert.Equal(t, key, other, name)
+		}
+
+		other, err := UserNameKey("paypals")
+		assert.NoError(t, err)
+		assert.NotEqual(t, key, other)
+	})
+
+	t.Run("Tests reserved names and their lookalikes are reserved", func(t *testing.T) {
+		for _, name := range []string{"admin", "ADMIN", "аdmin", "adrnin", "r00t", "Support", "deleted-42"} {
+			assert.True(t, IsReserved(name), name)
+		}
+		for _, name := range []string{"administrators", "gopher", "rooted", ""} {
+			assert.False(t, IsReserved(name), name)
+		}
+	})
+}
+
+func TestLinks(t *testing.T) {
+	t.Run("Tests Github links are reduced to the lowercased username", func(t *testing.T) {
+		for _, link := range []string{
+			"OctoCat", "@octocat", " octocat ", "github.com/octocat", "https://github.com/OctoCat/",
+			"http://www.github.com/octocat?tab=repositories", "https://github.com/octocat#readme",
+		} {
+			handle, err := GithubHandle(link)
+			assert.NoError(t, err, link)
+			assert.Equal(t, "octocat", handle, link)
+		}
+	})
+
+	t.Run("Tests malformed Github links are rejected", func(t *testing.T) {
+		for _, link := range []string{
+			"", "-octocat", "octocat-", "octo--cat", "octo_cat", "octo.cat", "ｏｃｔｏ",
+			"a123456789012345678901234567890123456789", "https://github.com/octocat/hello-world",
+			"https://gitlab.com/octocat", "https://github.com.evil.example/octocat", "ftp://github.com/octocat",
+			"https://user@github.com/octocat",
+		} {
+			_, err := GithubHandle(link)
+			assert.ErrorIs(t, err, ErrInvalidGithub, link)
+		}
+	})
+
+	t.Run("Tests Linkedin links are reduced to the lowercased profile name", func(t *testing.T) {
+		for _, link := range []string{
+			"Jane-Doe-1234", "in/jane-doe-1234", "linkedin.com/in/jane-doe-1234",
+			"https://www.linkedin.com/in/Jane-Doe-1234/", "https://uk.linkedin.com/in/jane-doe-1234?trk=profile",
+		} {
+			name, err := LinkedinProfile(link)
+			assert.NoError(t, err, link)
+			assert.Equal(t, "jane-doe-1234", name, link)
+		}
+	})
+
+	t.Run("Tests malformed Linkedin links are rejected", func(t *testing.T) {
+		for _, link := range []string{
+			"", "jd", "jane doe", "jane_doe", "https://www.linkedin.com/company/nuboverflow",
+			"https://www.linkedin.com/in/", "https://linkedin.example/in/jane-doe", "https://notlinkedin.com/in/jane-doe",
+		} {
+			_, err := LinkedinProfile(link)
+			assert.ErrorIs(t, err, ErrInvalidLinkedin, link)
+		}
+	})
+}
+
+func TestTag(t *testing.T) {
+	t.Run("Tests tags are lowercased with hyphens between words", func(t *testing.T) {
+		for name, want := range map[string]string{
+			"Go":                 "go",
+			" Machine  Learning": "machine-learning",
+			"C#":                 "c#",
+			"C++":                "c++",
+			"Node.js":            "node.js",
+			".NET":               ".net",
+		} {
+			tag, err := Tag(name)
+			assert.NoError(t, err, name)
+			assert.Equal(t, want, tag, name)
+		}
+	})
+
+	t.Run("Tests malformed tags are rejected", func(t *testing.T) {
+		for _, name := range []string{
+			"", "   ", "-go", "go-", "++", "go/rust", "gö", "<script>",
+			"a-tag-that-is-far-too-long-to-be-one",
+		} {
+			_, err := Tag(name)
+			assert.ErrorIs(t, err, ErrInvalidTag, name)
+		}
+	})
+}
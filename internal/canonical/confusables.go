@@ -0,0 +1,45 @@
+package canonical
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusables maps characters to the lookalike Latin text they are commonly
+// mistaken for. It is the subset of the Unicode confusables data (UTS #39)
+// covering lowercase Latin letters and digits, which is what usernames are
+// matched on after case folding.
+var confusables = map[rune]string{
+	// Digits and punctuation.
+	'0': "o", '1': "l", '|': "l",
+	// Latin letter sequences.
+	'm': "rn", 'w': "vv",
+	// Latin.
+	'ı': "i", 'ȷ': "j", 'ℓ': "l", 'ɑ': "a", 'ɡ': "g", 'ɩ': "i", 'ʋ': "u",
+	// Greek.
+	'α': "a", 'γ': "y", 'ο': "o", 'ι': "i", 'κ': "k", 'ν': "v", 'ρ': "p",
+	'τ': "t", 'υ': "u", 'χ': "x", 'ϲ': "c", 'ϳ': "j",
+	// Cyrillic.
+	'а': "a", 'в': "b", 'г': "r", 'е': "e", 'к': "k", 'н': "h", 'о': "o",
+	'п': "n", 'р': "p", 'с': "c", 'т': "t", 'у': "y", 'х': "x", 'ѕ': "s",
+	'і': "i", 'ј': "j", 'ԁ': "d", 'һ': "h", 'ӏ': "l", 'ԛ': "q", 'ԝ': "vv",
+	'ь': "b",
+	// Armenian.
+	'օ': "o", 'ս': "u", 'ց': "g", 'հ': "h", 'ո': "n",
+}
+
+// Skeleton returns the UTS #39 skeleton of s: strings that look alike share
+// a skeleton. s is decomposed, each character replaced by its prototype, and
+// the result decomposed again.
+func Skeleton(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		if prototype, ok := confusables[r]; ok {
+			b.WriteString(prototype)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFD.String(b.String())
+}
@@ -0,0 +1,81 @@
+// Package canonical computes the canonical forms of emails and usernames that
+// decide whether two of them belong to the same user.
+package canonical
+
+import (
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"golang.org/x/net/idna"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Version identifies the rules canonical forms are computed by. It is bumped
+// whenever they change, so that forms stored under older rules are
+// recomputed.
+const Version = 1
+
+// ErrInvalidEmail is returned for an address without a local part or a valid domain.
+var ErrInvalidEmail = domainerr.Validation("email is not a valid address")
+
+// EmailRules configures how emails are canonicalized.
+type EmailRules struct {
+	// ProviderRules applies the addressing rules of well-known mail providers,
+	// such as Gmail ignoring dots and anything after a "+". Addresses that
+	// only differ by those then count as the same email.
+	ProviderRules bool
+}
+
+// provider describes which parts of a local part a mail provider ignores.
+type provider struct {
+	// domain is the provider's primary domain, for providers with aliases.
+	domain     string
+	ignoreDots bool
+	plusTags   bool
+}
+
+var providers = map[string]provider{
+	"gmail.com":      {domain: "gmail.com", ignoreDots: true, plusTags: true},
+	"googlemail.com": {domain: "gmail.com", ignoreDots: true, plusTags: true},
+	"outlook.com":    {plusTags: true},
+	"hotmail.com":    {plusTags: true},
+	"live.com":       {plusTags: true},
+	"icloud.com":     {plusTags: true},
+	"fastmail.com":   {plusTags: true},
+	"protonmail.com": {plusTags: true},
+	"proton.me":      {plusTags: true},
+}
+
+// Email returns the canonical form of address: the local part in NFKC and
+// case-folded, the domain in lowercase ASCII (punycode for international
+// domains), and the provider rules applied if enabled.
+func (r EmailRules) Email(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	at := strings.LastIndex(address, "@")
+	if at < 1 || at == len(address)-1 {
+		return "", ErrInvalidEmail
+	}
+
+	domain, err := idna.Lookup.ToASCII(strings.TrimSuffix(address[at+1:], "."))
+	if err != nil || domain == "" {
+		return "", ErrInvalidEmail
+	}
+	domain = strings.ToLower(domain)
+	local := cases.Fold().String(norm.NFKC.String(address[:at]))
+
+	if p, ok := providers[domain]; ok && r.ProviderRules {
+		if p.plusTags {
+			if plus := strings.Index(local, "+"); plus > 0 {
+				local = local[:plus]
+			}
+		}
+		if p.ignoreDots {
+			local = strings.ReplaceAll(local, ".", "")
+		}
+		if p.domain != "" {
+			domain = p.domain
+		}
+	}
+	return local + "@" + domain, nil
+}
@@ -0,0 +1,98 @@
+package canonical
+
+import (
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+var (
+	// ErrInvalidGithub is returned for a Github link that is neither a valid
+	// Github username nor the URL of a Github profile.
+	ErrInvalidGithub = domainerr.Validation("github must be a Github username or profile URL")
+	// ErrInvalidLinkedin is returned for a Linkedin link that is neither the
+	// name of a Linkedin profile nor its URL.
+	ErrInvalidLinkedin = domainerr.Validation("linkedin must be a Linkedin profile URL or the name after /in/ in it")
+)
+
+// GithubHandle returns the Github username a link names, lowercased, since
+// Github usernames are case-insensitive. The link can be the username,
+// with or without an @, or the URL of the profile, such as
+// https://github.com/octocat.
+func GithubHandle(link string) (string, error) {
+	link = strings.TrimPrefix(strings.TrimSpace(link), "@")
+	handle := link
+	if strings.ContainsAny(link, "./") {
+		segments, ok := profilePath(link, func(host string) bool { return host == "github.com" })
+		if !ok || len(segments) != 1 {
+			return "", ErrInvalidGithub
+		}
+		handle = segments[0]
+	}
+	// Github usernames are up to 39 letters, digits and single hyphens,
+	// neither starting nor ending with one.
+	if handle == "" || len(handle) > 39 || strings.HasPrefix(handle, "-") ||
+		strings.HasSuffix(handle, "-") || strings.Contains(handle, "--") {
+		return "", ErrInvalidGithub
+	}
+	for _, r := range handle {
+		if !(r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) || r == '-') {
+			return "", ErrInvalidGithub
+		}
+	}
+	return strings.ToLower(handle), nil
+}
+
+// LinkedinProfile returns the name of the Linkedin profile a link names,
+// lowercased. The link can be the profile's URL, such as
+// https://www.linkedin.com/in/jane-doe-1234/, or the name after /in/ in it.
+func LinkedinProfile(link string) (string, error) {
+	link = strings.TrimPrefix(strings.TrimSpace(link), "in/")
+	name := link
+	if strings.ContainsAny(link, "./") {
+		segments, ok := profilePath(link, func(host string) bool {
+			// Including country sites such as uk.linkedin.com.
+			return host == "linkedin.com" || strings.HasSuffix(host, ".linkedin.com")
+		})
+		if !ok || len(segments) != 2 || segments[0] != "in" {
+			return "", ErrInvalidLinkedin
+		}
+		name = segments[1]
+	}
+	// Profile names are 3 to 100 letters, digits and hyphens.
+	if n := len([]rune(name)); n < 3 || n > 100 {
+		return "", ErrInvalidLinkedin
+	}
+	for _, r := range name {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-') {
+			return "", ErrInvalidLinkedin
+		}
+	}
+	return strings.ToLower(name), nil
+}
+
+// profilePath parses link as an http(s) URL, with or without its scheme, on
+// a host allowed accepts, and returns the segments of its path. A leading
+// www. is ignored, as are the query and fragment.
+func profilePath(link string, allowed func(host string) bool) ([]string, bool) {
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil || u.Port() != "" {
+		return nil, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if !allowed(host) {
+		return nil, false
+	}
+	var segments []string
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return segments, true
+}
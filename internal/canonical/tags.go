@@ -0,0 +1,38 @@
+package canonical
+
+import (
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// MaxTagLength is the longest a tag can be.
+const MaxTagLength = 35
+
+// ErrInvalidTag is returned for a tag that is empty, too long or has
+// characters tags cannot have.
+var ErrInvalidTag = domainerr.Validation("tags are 1 to 35 letters, digits and the characters + # . -, such as go, c# or node.js")
+
+// Tag returns the form a tag naming a skill or topic is stored and matched
+// in: lowercased, with the spaces between words turned into hyphens, so that
+// "Machine Learning" and machine-learning are the same tag.
+func Tag(name string) (string, error) {
+	tag := strings.ToLower(strings.Join(strings.Fields(name), "-"))
+	if tag == "" || len(tag) > MaxTagLength || strings.HasPrefix(tag, "-") || strings.HasSuffix(tag, "-") {
+		return "", ErrInvalidTag
+	}
+	alphanumeric := false
+	for _, r := range tag {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			alphanumeric = true
+		case r == '+', r == '#', r == '.', r == '-':
+		default:
+			return "", ErrInvalidTag
+		}
+	}
+	if !alphanumeric {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
@@ -0,0 +1,78 @@
+package canonical
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// ErrInvalidUserName is returned for a username that is empty or contains
+// control or invisible formatting characters.
+var ErrInvalidUserName = domainerr.Validation("username contains invisible or control characters")
+
+// UserName returns name as it should be stored and displayed: trimmed and in
+// Unicode NFKC, which folds compatibility characters such as fullwidth
+// letters and ligatures into their ordinary forms.
+func UserName(name string) (string, error) {
+	name = norm.NFKC.String(strings.TrimSpace(name))
+	if name == "" {
+		return "", ErrInvalidUserName
+	}
+	for _, r := range name {
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return "", ErrInvalidUserName
+		}
+	}
+	return name, nil
+}
+
+// UserNameKey returns the form of name compared for uniqueness: the
+// confusable skeleton of its case-folded NFKC form. Usernames that only
+// differ by case or by lookalike characters share a key.
+func UserNameKey(name string) (string, error) {
+	name, err := UserName(name)
+	if err != nil {
+		return "", err
+	}
+	return Skeleton(cases.Fold().String(name)), nil
+}
+
+// reservedNames cannot be registered, nor can anything confusable with them.
+var reservedNames = []string{
+	"admin", "administrator", "root", "support", "help", "moderator", "mod",
+	"staff", "system", "security", "nuboverflow", "api", "null", "undefined",
+	"anonymous",
+}
+
+// reservedPrefixes cannot start a username. Purged users are renamed to
+// "deleted-<id>", which nobody should be able to impersonate.
+var reservedPrefixes = []string{"deleted-"}
+
+var reservedKeys = func() map[string]bool {
+	keys := make(map[string]bool, len(reservedNames))
+	for _, name := range reservedNames {
+		key, _ := UserNameKey(name)
+		keys[key] = true
+	}
+	return keys
+}()
+
+// IsReserved reports whether name is, or is confusable with, a reserved name.
+func IsReserved(name string) bool {
+	key, err := UserNameKey(name)
+	if err != nil {
+		return false
+	}
+	if reservedKeys[key] {
+		return true
+	}
+	for _, prefix := range reservedPrefixes {
+		if strings.HasPrefix(key, Skeleton(prefix)) {
+			return true
+		}
+	}
+	return false
+}
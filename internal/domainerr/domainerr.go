@@ -0,0 +1,122 @@
+// Package domainerr defines the errors the users service reports to its
+// callers. Each error has a Kind that transports map to a status code, so
+// lower layers never need to know about HTTP.
+package domainerr
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Kind int
+
+const (
+	KindNotFound Kind = iota + 1
+	KindConflict
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindPreconditionFailed
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return "not found"
+	case KindConflict:
+		return "conflict"
+	case KindValidation:
+		return "validation failed"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindForbidden:
+		return "forbidden"
+	case KindPreconditionFailed:
+		return "precondition failed"
+	default:
+		return "unknown"
+	}
+}
+
+// Error is a domain error of a given Kind, optionally wrapping its cause.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+// Sentinels for matching any error of a kind with errors.Is.
+var (
+	ErrNotFound           = &Error{Kind: KindNotFound}
+	ErrConflict           = &Error{Kind: KindConflict}
+	ErrValidation         = &Error{Kind: KindValidation}
+	ErrUnauthorized       = &Error{Kind: KindUnauthorized}
+	ErrForbidden          = &Error{Kind: KindForbidden}
+	ErrPreconditionFailed = &Error{Kind: KindPreconditionFailed}
+)
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Kind.String()
+	}
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is makes errors.Is(err, ErrNotFound) and friends match every error of that
+// kind. Errors carrying their own message only match themselves.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Message == "" && t.Err == nil {
+		return t.Kind == e.Kind
+	}
+	return t == e
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+func Validation(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+func Unauthorized(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+func PreconditionFailed(format string, args ...interface{}) *Error {
+	return &Error{Kind: KindPreconditionFailed, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap returns a domain error of the given kind that wraps err.
+func Wrap(kind Kind, err error, message string) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// KindOf returns the Kind of the first domain error in err's chain, or 0 if there is none.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+package domainerr
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestError(t *testing.T) {
+	t.Run("Tests kind sentinels match any error of that kind", func(t *testing.T) {
+		err := fmt.Errorf("loading profile: %w", NotFound("user %d not found", 4))
+		assert.ErrorIs(t, err, ErrNotFound)
+		assert.NotErrorIs(t, err, ErrConflict)
+		assert.Equal(t, KindNotFound, KindOf(err))
+	})
+
+	t.Run("Tests specific errors only match themselves", func(t *testing.T) {
+		errWindow := Conflict("restore window has passed")
+		assert.ErrorIs(t, errWindow, errWindow)
+		assert.ErrorIs(t, errWindow, ErrConflict)
+		assert.NotErrorIs(t, Conflict("user exists"), errWindow)
+	})
+
+	t.Run("Tests wrapped causes stay reachable", func(t *testing.T) {
+		cause := errors.New("duplicate entry")
+		err := Wrap(KindConflict, cause, "user already exists")
+		assert.ErrorIs(t, err, cause)
+		assert.Equal(t, "user already exists: duplicate entry", err.Error())
+	})
+
+	t.Run("Tests errors without a domain kind", func(t *testing.T) {
+		assert.Equal(t, Kind(0), KindOf(errors.New("boom")))
+	})
+}
@@ -0,0 +1,159 @@
+// Package github checks that users own the Github accounts they link to, by
+// looking for a token they were given in the account's public gists.
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Github REST API.
+const DefaultBaseURL = "https://api.github.com"
+
+const (
+	// gistsChecked is how many of the newest gists are looked at. Users are
+	// asked to create one, so the token is in the newest unless they wait.
+	gistsChecked = 10
+	// maxFileSize is the largest gist file read when looking for the token.
+	maxFileSize = 64 << 10
+)
+
+// HTTPClient sends requests. *http.Client satisfies it; stub it, or point
+// WithBaseURL at a local server, to verify without reaching Github.
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Verifier looks for tokens in public gists.
+type Verifier struct {
+	client  HTTPClient
+	baseURL string
+	token   string
+}
+
+// Option configures optional behaviour of a Verifier.
+type Option func(*Verifier)
+
+// WithBaseURL sets the API the Verifier calls, replacing DefaultBaseURL.
+func WithBaseURL(baseURL string) Option {
+	return func(v *Verifier) {
+		v.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithToken authenticates the Verifier's requests with a Github token, which
+// raises Github's rate limit for them.
+func WithToken(token string) Option {
+	return func(v *Verifier) {
+		v.token = token
+	}
+}
+
+// NewVerifier returns a Verifier sending its requests with client, or with
+// an http.Client timing out after ten seconds if client is nil.
+func NewVerifier(client HTTPClient, opts ...Option) *Verifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	v := &Verifier{client: client, baseURL: DefaultBaseURL}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// gist is the part of a gist in Github's API that the Verifier reads.
+type gist struct {
+	Description string `json:"description"`
+	Files       map[string]struct {
+		RawURL string `json:"raw_url"`
+		Size   int    `json:"size"`
+	} `json:"files"`
+}
+
+// HasToken reports whether one of the newest public gists of the Github
+// user handle contains token, in its description or one of its files. A
+// handle with no Github account has no gists.
+func (v *Verifier) HasToken(ctx context.Context, handle, token string) (bool, error) {
+	var gists []gist
+	found, err := v.get(ctx, fmt.Sprintf("%s/users/%s/gists?per_page=%d", v.baseURL, url.PathEscape(handle), gistsChecked), func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&gists)
+	})
+	if err != nil || !found {
+		return false, err
+	}
+	for _, g := range gists {
+		if strings.Contains(g.Description, token) {
+			return true, nil
+		}
+		for _, file := range g.Files {
+			if file.Size > maxFileSize || !v.isRawURL(file.RawURL) {
+				continue
+			}
+			var content []byte
+			_, err := v.get(ctx, file.RawURL, func(body io.Reader) (err error) {
+				content, err = io.ReadAll(io.LimitReader(body, maxFileSize))
+				return err
+			})
+			if err != nil {
+				return false, err
+			}
+			if strings.Contains(string(content), token) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// rawHost serves the contents of gist files.
+const rawHost = "gist.githubusercontent.com"
+
+// isRawURL reports whether rawURL can be where a gist file is served from:
+// Github's host for them, or the host of the API, which is what local stubs
+// serve them from. Other URLs in a response are not fetched.
+func (v *Verifier) isRawURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme == "https" && u.Host == rawHost {
+		return true
+	}
+	base, err := url.Parse(v.baseURL)
+	return err == nil && u.Scheme == base.Scheme && u.Host == base.Host
+}
+
+// get fetches rawURL and hands the body to read. It returns false, and no
+// error, if there is nothing at rawURL.
+func (v *Verifier) get(ctx context.Context, rawURL string, read func(io.Reader) error) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if v.token != "" && strings.HasPrefix(rawURL, v.baseURL+"/") {
+		req.Header.Set("Authorization", "Bearer "+v.token)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("github: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("github: GET %s: %s", req.URL.Redacted(), resp.Status)
+	}
+	if err := read(resp.Body); err != nil {
+		return false, fmt.Errorf("github: reading %s: %w", req.URL.Redacted(), err)
+	}
+	return true, nil
+}
@@ -0,0 +1,59 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasToken(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/octocat/gists":
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			fmt.Fprintf(w, `[
+				{"description": "notes", "files": {"a.md": {"raw_url": "%[1]s/raw/a.md", "size": 5}}},
+				{"description": "", "files": {
+					"big.txt": {"raw_url": "%[1]s/raw/big.txt", "size": 1000000},
+					"verify.txt": {"raw_url": "%[1]s/raw/verify.txt", "size": 30}
+				}}
+			]`, server.URL)
+		case "/users/mallory/gists":
+			fmt.Fprint(w, `[{"description": "", "files": {"x": {"raw_url": "http://169.254.169.254/latest", "size": 5}}}]`)
+		case "/raw/a.md":
+			fmt.Fprint(w, "hello")
+		case "/raw/verify.txt":
+			fmt.Fprint(w, "nuboverflow-verify-abc")
+		case "/raw/big.txt":
+			t.Error("files that are too large should not be fetched")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	verifier := NewVerifier(server.Client(), WithBaseURL(server.URL+"/"), WithToken("secret"))
+	ctx := context.Background()
+
+	found, err := verifier.HasToken(ctx, "octocat", "nuboverflow-verify-abc")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = verifier.HasToken(ctx, "octocat", "nuboverflow-verify-xyz")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = verifier.HasToken(ctx, "nobody", "nuboverflow-verify-abc")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Files served from elsewhere are not fetched.
+	found, err = verifier.HasToken(ctx, "mallory", "nuboverflow-verify-abc")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
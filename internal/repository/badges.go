@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm/clause"
+)
+
+func (s *store) AddAwards(ctx context.Context, awards []user.Award) ([]user.Award, error) {
+	added := make([]user.Award, 0, len(awards))
+	for _, award := range awards {
+		result := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&award)
+		if result.Error != nil {
+			log.Printf("GORM ERROR: %s", result.Error.Error())
+			return added, translateError(result.Error)
+		}
+		if result.RowsAffected > 0 {
+			added = append(added, award)
+		}
+	}
+	return added, nil
+}
+
+func (s *store) GetAwards(ctx context.Context, userIDs []int) ([]user.Award, error) {
+	var awards []user.Award
+	result := s.DB.WithContext(ctx).
+		Where("user_id IN ?", userIDs).
+		Order("created_at DESC").
+		Order("id DESC").
+		Find(&awards)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Award{}, translateError(result.Error)
+	}
+	return awards, nil
+}
+
+func (s *store) CountAwards(ctx context.Context, userIDs []int) (map[int]user.BadgeCounts, error) {
+	var rows []struct {
+		UserID int
+		Class  user.BadgeClass
+		Count  int64
+	}
+	result := s.DB.WithContext(ctx).
+		Model(&user.Award{}).
+		Select("user_id, class, COUNT(*) AS count").
+		Where("user_id IN ?", userIDs).
+		Group("user_id").
+		Group("class").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return nil, translateError(result.Error)
+	}
+	counts := make(map[int]user.BadgeCounts)
+	for _, row := range rows {
+		c := counts[row.UserID]
+		switch row.Class {
+		case user.Gold:
+			c.Gold = row.Count
+		case user.Silver:
+			c.Silver = row.Count
+		case user.Bronze:
+			c.Bronze = row.Count
+		}
+		counts[row.UserID] = c
+	}
+	return counts, nil
+}
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"gorm.io/gorm"
+)
+
+// mysqlDuplicateEntry is the MySQL error number for a unique key violation.
+const mysqlDuplicateEntry = 1062
+
+// translateError turns gorm and MySQL errors into domain errors. Errors with
+// no domain meaning are returned unchanged.
+func translateError(err error) error {
+	var mysqlErr *mysqldriver.MySQLError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domainerr.Wrap(domainerr.KindNotFound, err, "user not found")
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry:
+		return domainerr.Wrap(domainerr.KindConflict, err, duplicateMessage(mysqlErr.Message))
+	default:
+		return err
+	}
+}
+
+// duplicateMessage describes a unique key violation by the index it hit,
+// which MySQL names at the end of the error message.
+func duplicateMessage(message string) string {
+	switch {
+	case strings.Contains(message, emailIndexName):
+		return "a user with this email already exists"
+	case strings.Contains(message, userNameIndexName):
+		return "username is already taken"
+	default:
+		return "user already exists"
+	}
+}
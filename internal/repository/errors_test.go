@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	t.Run("Tests missing records are NotFound", func(t *testing.T) {
+		err := translateError(gorm.ErrRecordNotFound)
+		assert.True(t, errors.Is(err, domainerr.ErrNotFound))
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	t.Run("Tests duplicate keys are a Conflict naming the taken field", func(t *testing.T) {
+		cases := map[string]string{
+			"Duplicate entry 'bob@example.com-1' for key 'users.idx_users_normalized_email'": "a user with this email already exists",
+			"Duplicate entry 'bob-1' for key 'users.idx_users_normalized_user_name'":         "username is already taken",
+			"Duplicate entry '1' for key 'users.PRIMARY'":                                    "user already exists",
+		}
+		for message, want := range cases {
+			err := translateError(&mysqldriver.MySQLError{Number: mysqlDuplicateEntry, Message: message})
+			assert.True(t, errors.Is(err, domainerr.ErrConflict), message)
+			var domainErr *domainerr.Error
+			assert.True(t, errors.As(err, &domainErr))
+			assert.Equal(t, want, domainErr.Message)
+		}
+	})
+
+	t.Run("Tests other errors are returned unchanged", func(t *testing.T) {
+		err := errors.New("connection refused")
+		assert.Equal(t, err, translateError(err))
+	})
+}
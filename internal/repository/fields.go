@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type fieldsKey struct{}
+
+// requiredColumns are loaded even when only some fields are asked for: the
+// ID identifies the user, the version makes its ETag, and the privacy
+// settings decide who may see the rest.
+var requiredColumns = []string{
+	"id",
+	"version",
+	"privacy_show_email",
+	"privacy_show_github",
+	"privacy_show_linkedin",
+	"privacy_show_work_place",
+}
+
+// WithFields returns a context under which user lookups only load the given
+// columns, plus those every user needs. Columns not loaded are left zero.
+// Writes ignore it.
+func WithFields(ctx context.Context, columns ...string) context.Context {
+	return context.WithValue(ctx, fieldsKey{}, columns)
+}
+
+// FieldsFrom returns the columns set by WithFields, or nil if lookups under
+// ctx load whole users.
+func FieldsFrom(ctx context.Context) []string {
+	columns, _ := ctx.Value(fieldsKey{}).([]string)
+	return columns
+}
+
+// selectFields narrows db to the columns requested through ctx, if any.
+func selectFields(ctx context.Context, db *gorm.DB) *gorm.DB {
+	columns := FieldsFrom(ctx)
+	if columns == nil {
+		return db
+	}
+	selected := append([]string{}, requiredColumns...)
+	for _, column := range columns {
+		if !contains(selected, column) {
+			selected = append(selected, column)
+		}
+	}
+	return db.Select(selected)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,328 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *store) Follow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	var followed bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUsers(tx, followerID, followeeID); err != nil {
+			return err
+		}
+		blocked, err := blockedEitherWay(tx, followerID, followeeID)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return user.ErrBlocked
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&user.Follow{FollowerID: followerID, FolloweeID: followeeID})
+		if result.Error != nil {
+			return result.Error
+		}
+		followed = result.RowsAffected > 0
+		if !followed {
+			return nil
+		}
+		return addFollowCounts(tx, followerID, followeeID, 1)
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return followed, nil
+}
+
+func (s *store) Unfollow(ctx context.Context, followerID, followeeID int) (bool, error) {
+	var unfollowed bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Deleted users can still be unfollowed.
+		if err := lockUsers(tx.Unscoped(), followerID, followeeID); err != nil {
+			return err
+		}
+		var err error
+		unfollowed, err = unfollow(tx, followerID, followeeID)
+		return err
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return unfollowed, nil
+}
+
+func (s *store) GetFollowers(ctx context.Context, id int, after user.Cursor, limit int) ([]user.Follow, error) {
+	return s.follows(ctx, "followee_id", "follower_id", id, after, limit)
+}
+
+func (s *store) GetFollowing(ctx context.Context, id int, after user.Cursor, limit int) ([]user.Follow, error) {
+	return s.follows(ctx, "follower_id", "followee_id", id, after, limit)
+}
+
+// follows returns a page of the follows whose column is id, newest first.
+func (s *store) follows(ctx context.Context, column, other string, id int, after user.Cursor, limit int) ([]user.Follow, error) {
+	follows := []user.Follow{}
+	result := keyset(s.DB.WithContext(ctx).Where(column+" = ?", id), other, after, limit).Find(&follows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Follow{}, translateError(result.Error)
+	}
+	return follows, nil
+}
+
+func (s *store) GetFollowsBetween(ctx context.Context, a, b int) ([]user.Follow, error) {
+	var follows []user.Follow
+	result := s.DB.WithContext(ctx).
+		Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)", a, b, b, a).
+		Find(&follows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Follow{}, translateError(result.Error)
+	}
+	return follows, nil
+}
+
+func (s *store) BlockUser(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	var blocked bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUsers(tx, blockerID, blockedID); err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&user.Block{BlockerID: blockerID, BlockedID: blockedID})
+		if result.Error != nil {
+			return result.Error
+		}
+		blocked = result.RowsAffected > 0
+		if _, err := unfollow(tx, blockerID, blockedID); err != nil {
+			return err
+		}
+		_, err := unfollow(tx, blockedID, blockerID)
+		return err
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return blocked, nil
+}
+
+func (s *store) GetBlocks(ctx context.Context, blockerID int, after user.Cursor, limit int) ([]user.Block, error) {
+	blocks := []user.Block{}
+	result := keyset(s.DB.WithContext(ctx).Where("blocker_id = ?", blockerID), "blocked_id", after, limit).Find(&blocks)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Block{}, translateError(result.Error)
+	}
+	return blocks, nil
+}
+
+func (s *store) FindBlocks(ctx context.Context, pairs []user.Block) ([]user.Block, error) {
+	blocks := []user.Block{}
+	if len(pairs) == 0 {
+		return blocks, nil
+	}
+	keys := make([][]interface{}, len(pairs))
+	for i, pair := range pairs {
+		keys[i] = []interface{}{pair.BlockerID, pair.BlockedID}
+	}
+	result := s.DB.WithContext(ctx).Where("(blocker_id, blocked_id) IN ?", keys).Find(&blocks)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Block{}, translateError(result.Error)
+	}
+	return blocks, nil
+}
+
+func (s *store) UnblockUser(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	result := s.DB.WithContext(ctx).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&user.Block{})
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *store) IsBlocked(ctx context.Context, a, b int) (bool, error) {
+	blocked, err := blockedEitherWay(s.DB.WithContext(ctx), a, b)
+	if err != nil {
+		log.Printf("GORM ERROR: %s", err.Error())
+		return false, translateError(err)
+	}
+	return blocked, nil
+}
+
+func (s *store) MuteUser(ctx context.Context, muterID, mutedID int) (bool, error) {
+	if err := usersExist(s.DB.WithContext(ctx), muterID, mutedID); err != nil {
+		return false, txError(err)
+	}
+	result := s.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&user.Mute{MuterID: muterID, MutedID: mutedID})
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *store) UnmuteUser(ctx context.Context, muterID, mutedID int) (bool, error) {
+	result := s.DB.WithContext(ctx).
+		Where("muter_id = ? AND muted_id = ?", muterID, mutedID).
+		Delete(&user.Mute{})
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return false, translateError(result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *store) GetMutes(ctx context.Context, muterID int, after user.Cursor, limit int) ([]user.Mute, error) {
+	mutes := []user.Mute{}
+	result := keyset(s.DB.WithContext(ctx).Where("muter_id = ?", muterID), "muted_id", after, limit).Find(&mutes)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Mute{}, translateError(result.Error)
+	}
+	return mutes, nil
+}
+
+// keyset narrows query to up to limit rows, newest first, starting after the
+// one made at after.CreatedAt by or of the user whose ID is in other.
+func keyset(query *gorm.DB, other string, after user.Cursor, limit int) *gorm.DB {
+	if after != (user.Cursor{}) {
+		query = query.Where("created_at < ? OR (created_at = ? AND "+other+" < ?)",
+			after.CreatedAt, after.CreatedAt, after.UserID)
+	}
+	return query.
+		Order("created_at DESC").
+		Order(other + " DESC").
+		Limit(limit)
+}
+
+// lockUsers locks the rows of the given users until the transaction tx ends,
+// and fails with NotFound unless all of them exist. The rows are locked in
+// ID order, so transactions locking the same users wait for each other
+// instead of deadlocking.
+func lockUsers(tx *gorm.DB, ids ...int) error {
+	return usersExist(tx.Clauses(clause.Locking{Strength: "UPDATE"}), ids...)
+}
+
+// usersExist fails with NotFound unless all of the given users exist.
+func usersExist(db *gorm.DB, ids ...int) error {
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !containsInt(unique, id) {
+			unique = append(unique, id)
+		}
+	}
+	sort.Ints(unique)
+	var found []int
+	result := db.Model(&user.User{}).
+		Where("id IN ?", unique).
+		Order("id").
+		Pluck("id", &found)
+	if result.Error != nil {
+		return result.Error
+	}
+	if len(found) != len(unique) {
+		return domainerr.NotFound("user not found")
+	}
+	return nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func blockedEitherWay(db *gorm.DB, a, b int) (bool, error) {
+	var blocks int64
+	result := db.Model(&user.Block{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", a, b, b, a).
+		Count(&blocks)
+	return blocks > 0, result.Error
+}
+
+// unfollow removes the follow, if any, and the counts it added.
+func unfollow(tx *gorm.DB, followerID, followeeID int) (bool, error) {
+	result := tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&user.Follow{})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	return true, addFollowCounts(tx, followerID, followeeID, -1)
+}
+
+// addFollowCounts adds delta to the follower's FollowingCount and the
+// followee's FollowerCount. It leaves their Versions alone: being followed
+// shouldn't fail an edit of the profile in progress. The ETags of users
+// still change with the counts, as they cover the whole response.
+func addFollowCounts(tx *gorm.DB, followerID, followeeID, delta int) error {
+	err := tx.Unscoped().Model(&user.User{}).Where("id = ?", followerID).
+		UpdateColumn("following_count", gorm.Expr("following_count + ?", delta)).Error
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&user.User{}).Where("id = ?", followeeID).
+		UpdateColumn("follower_count", gorm.Expr("follower_count + ?", delta)).Error
+}
+
+// forgetRelations deletes the follows, blocks and mutes of the users selected
+// by users, and takes their follows off the counts of the users on the other
+// side.
+func forgetRelations(tx *gorm.DB, users *gorm.DB) error {
+	var follows []user.Follow
+	if err := tx.Where("follower_id IN (?) OR followee_id IN (?)", users, users).Find(&follows).Error; err != nil {
+		return err
+	}
+	lostFollowers := make(map[int]int)
+	lostFollowing := make(map[int]int)
+	for _, f := range follows {
+		lostFollowing[f.FollowerID]++
+		lostFollowers[f.FolloweeID]++
+	}
+	for id, lost := range lostFollowers {
+		err := tx.Unscoped().Model(&user.User{}).Where("id = ?", id).
+			UpdateColumn("follower_count", gorm.Expr("follower_count - ?", lost)).Error
+		if err != nil {
+			return err
+		}
+	}
+	for id, lost := range lostFollowing {
+		err := tx.Unscoped().Model(&user.User{}).Where("id = ?", id).
+			UpdateColumn("following_count", gorm.Expr("following_count - ?", lost)).Error
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("follower_id IN (?) OR followee_id IN (?)", users, users).Delete(&user.Follow{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("blocker_id IN (?) OR blocked_id IN (?)", users, users).Delete(&user.Block{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("muter_id IN (?) OR muted_id IN (?)", users, users).Delete(&user.Mute{}).Error
+}
+
+// txError passes on the domain errors a transaction returned itself and
+// translates those that came from the database.
+func txError(err error) error {
+	var domainErr *domainerr.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	log.Printf("GORM ERROR: %s", err.Error())
+	return translateError(err)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// normalizeStoredLinks rewrites the Github and Linkedin links stored before
+// links were normalized as the handle and profile name they name. Links that
+// are not valid are logged and left alone.
+func (s *store) normalizeStoredLinks() error {
+	var users []user.User
+	result := s.DB.Unscoped().Select("id", "github", "linkedin").Where("github <> '' OR linkedin <> ''").
+		FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+			for _, usr := range users {
+				github, linkedin := usr.Github, usr.Linkedin
+				var err error
+				if github != "" {
+					if github, err = canonical.GithubHandle(github); err != nil {
+						log.Printf("Cannot normalize the Github link of user %d: %s", usr.ID, err)
+						github = usr.Github
+					}
+				}
+				if linkedin != "" {
+					if linkedin, err = canonical.LinkedinProfile(linkedin); err != nil {
+						log.Printf("Cannot normalize the Linkedin link of user %d: %s", usr.ID, err)
+						linkedin = usr.Linkedin
+					}
+				}
+				if github == usr.Github && linkedin == usr.Linkedin {
+					continue
+				}
+				err = s.DB.Unscoped().Model(&user.User{ID: usr.ID}).UpdateColumns(map[string]interface{}{
+					"github":   github,
+					"linkedin": linkedin,
+					"version":  gorm.Expr("version + 1"),
+				}).Error
+				if err != nil {
+					log.Printf("Failed to normalize the links of user %d: %s", usr.ID, translateError(err))
+				}
+			}
+			return nil
+		})
+	return result.Error
+}
+
+func (s *store) SaveGithubChallenge(ctx context.Context, challenge user.GithubChallenge) error {
+	if err := usersExist(s.DB.WithContext(ctx), challenge.UserID); err != nil {
+		return txError(err)
+	}
+	result := s.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"handle", "token", "created_at", "expires_at"}),
+	}).Create(&challenge)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return translateError(result.Error)
+	}
+	return nil
+}
+
+func (s *store) GetGithubChallenge(ctx context.Context, userID int) (user.GithubChallenge, error) {
+	var challenge user.GithubChallenge
+	result := s.DB.WithContext(ctx).Where("user_id = ?", userID).First(&challenge)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return user.GithubChallenge{}, domainerr.NotFound("no github verification is in progress")
+		}
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return user.GithubChallenge{}, translateError(result.Error)
+	}
+	return challenge, nil
+}
+
+func (s *store) VerifyGithub(ctx context.Context, id int, handle string) (user.User, error) {
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.User{}).Where("id = ? AND github = ?", id, handle).Updates(map[string]interface{}{
+			"verified_github": handle,
+			"version":         gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if err := usersExist(tx, id); err != nil {
+				return err
+			}
+			return user.ErrGithubChanged
+		}
+		return tx.Where("user_id = ?", id).Delete(&user.GithubChallenge{}).Error
+	})
+	if err != nil {
+		return user.User{}, txError(err)
+	}
+	return s.GetUserByID(ctx, id)
+}
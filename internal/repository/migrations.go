@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migration records the version of a data migration that last ran to
+// completion, so that it only runs again when its version changes.
+type migration struct {
+	Name      string `gorm:"primaryKey;size:64"`
+	Version   string `gorm:"size:255;not null"`
+	AppliedAt time.Time
+}
+
+// runMigration runs migrate unless the migration called name already ran at
+// version, and records that it did.
+func runMigration(db *gorm.DB, name, version string, migrate func() error) error {
+	var last migration
+	err := db.Where("name = ?", name).First(&last).Error
+	switch {
+	case err == nil && last.Version == version:
+		return nil
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	log.Printf("Running migration %s at version %s.", name, version)
+	if err := migrate(); err != nil {
+		return err
+	}
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&migration{Name: name, Version: version, AppliedAt: time.Now()}).Error
+}
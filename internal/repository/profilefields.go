@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *store) GetProfileFields(ctx context.Context) ([]user.ProfileField, error) {
+	fields := []user.ProfileField{}
+	if result := s.DB.WithContext(ctx).Order("position").Order("name").Find(&fields); result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.ProfileField{}, translateError(result.Error)
+	}
+	return fields, nil
+}
+
+func (s *store) SaveProfileField(ctx context.Context, field user.ProfileField) (user.ProfileField, []int, error) {
+	var affected []int
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current user.ProfileField
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", field.Name).First(&current).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return tx.Create(&field).Error
+		case err != nil:
+			return err
+		}
+		field.CreatedAt = current.CreatedAt
+		if err := tx.Save(&field).Error; err != nil {
+			return err
+		}
+		if field.Visibility != current.Visibility {
+			// Who sees the values changed, and so did the users holding them.
+			affected, err = bumpProfileFieldHolders(tx, field.Name)
+		}
+		return err
+	})
+	if err != nil {
+		return user.ProfileField{}, nil, txError(err)
+	}
+	return field, affected, nil
+}
+
+func (s *store) DeleteProfileField(ctx context.Context, name string) ([]int, error) {
+	var affected []int
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("name = ?", name).Delete(&user.ProfileField{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainerr.NotFound("profile field %q not found", name)
+		}
+		var err error
+		if affected, err = bumpProfileFieldHolders(tx, name); err != nil {
+			return err
+		}
+		return tx.Where("field = ?", name).Delete(&user.ProfileFieldValue{}).Error
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+	return affected, nil
+}
+
+// bumpProfileFieldHolders increments the Version of the users holding a value
+// for the profile field name, and returns their IDs.
+func bumpProfileFieldHolders(tx *gorm.DB, name string) ([]int, error) {
+	var ids []int
+	if err := tx.Model(&user.ProfileFieldValue{}).Where("field = ?", name).Pluck("user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	err := tx.Model(&user.User{}).Where("id IN ?", ids).Update("version", gorm.Expr("version + 1")).Error
+	return ids, err
+}
+
+func (s *store) SetProfileFieldValues(ctx context.Context, id int, values map[string]string) (user.User, error) {
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&user.User{ID: id}).Update("version", gorm.Expr("version + 1"))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainerr.NotFound("user not found")
+		}
+		if err := tx.Where("user_id = ?", id).Delete(&user.ProfileFieldValue{}).Error; err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return nil
+		}
+		rows := make([]user.ProfileFieldValue, 0, len(values))
+		for field, value := range values {
+			rows = append(rows, user.ProfileFieldValue{UserID: id, Field: field, Value: value})
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].Field < rows[j].Field })
+		return tx.Create(&rows).Error
+	})
+	if err != nil {
+		return user.User{}, txError(err)
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+// loadProfileFields sets the ProfileFields of users, which are kept in their
+// own table, along with the visibility of each field.
+func loadProfileFields(db *gorm.DB, users []user.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	ids := make([]int, len(users))
+	for i, usr := range users {
+		ids[i] = usr.ID
+	}
+	var rows []struct {
+		UserID     int
+		Field      string
+		Value      string
+		Visibility user.ProfileFieldVisibility
+	}
+	result := db.Model(&user.ProfileFieldValue{}).
+		Select("profile_field_values.user_id, profile_field_values.field, profile_field_values.value, profile_fields.visibility").
+		Joins("JOIN profile_fields ON profile_fields.name = profile_field_values.field").
+		Where("profile_field_values.user_id IN ?", ids).
+		Order("profile_field_values.field").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return translateError(result.Error)
+	}
+	byUser := make(map[int][]user.ProfileFieldValue)
+	for _, row := range rows {
+		byUser[row.UserID] = append(byUser[row.UserID], user.ProfileFieldValue{
+			UserID:     row.UserID,
+			Field:      row.Field,
+			Value:      row.Value,
+			Visibility: row.Visibility,
+		})
+	}
+	for i := range users {
+		users[i].ProfileFields = byUser[users[i].ID]
+	}
+	return nil
+}
+
+// loadUserProfileFields is loadProfileFields for a single user.
+func loadUserProfileFields(db *gorm.DB, usr *user.User) error {
+	users := []user.User{*usr}
+	if err := loadProfileFields(db, users); err != nil {
+		return err
+	}
+	usr.ProfileFields = users[0].ProfileFields
+	return nil
+}
+
+// matchProfileFields narrows query to users whose profile fields have the
+// given values.
+func matchProfileFields(db, query *gorm.DB, values map[string]string) *gorm.DB {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		holders := db.Model(&user.ProfileFieldValue{}).Select("user_id").Where("field = ? AND value = ?", name, values[name])
+		query = query.Where("id IN (?)", holders)
+	}
+	return query
+}
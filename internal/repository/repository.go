@@ -1,29 +1,172 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
 	"github.com/millbj92/nuboverflow-users/internal/user"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Store interface {
-	GetAllUsers() ([]user.User, error)
-	GetUserByID(id int) (user.User, error)
-	GetUserByEmail(email string) (user.User, error)
-	CreateUser(user *user.User) (*user.User, error)
-	UpdateUser(user user.User) (user.User, error)
-	DeleteUser(id int) error
+	GetAllUsers(ctx context.Context) ([]user.User, error)
+	GetUserByID(ctx context.Context, id int) (user.User, error)
+	// GetUsersByIDs returns the users with the given IDs that exist, in no particular order.
+	GetUsersByIDs(ctx context.Context, ids []int) ([]user.User, error)
+	GetUserByEmail(ctx context.Context, email string) (user.User, error)
+	GetUserByUserName(ctx context.Context, name string) (user.User, error)
+	FindUsers(ctx context.Context, filter user.Filter) ([]user.User, error)
+	SearchUsers(ctx context.Context, query user.SearchQuery) (user.SearchPage, error)
+	SuggestUsers(ctx context.Context, prefix string, limit int) ([]user.Suggestion, error)
+	CreateUser(ctx context.Context, user *user.User) (*user.User, error)
+	// UpdateUser writes the non-zero fields of user if its Version still matches
+	// the stored one, and returns the stored user after the update. UserScore
+	// is never written: it only changes through AddReputationEvent. Nor are
+	// the follow counts, which Follow, Unfollow and BlockUser keep, or
+	// AvatarHash and VerifiedGithub, which UpdateAvatar and VerifyGithub set.
+	UpdateUser(ctx context.Context, user user.User) (user.User, error)
+	DeleteUser(ctx context.Context, id int) error
+	GetDeletedUserByID(ctx context.Context, id int) (user.User, error)
+	RestoreUser(ctx context.Context, id int) (user.User, error)
+	PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
+	// RenameUser changes a user's username and records the change in one transaction.
+	RenameUser(ctx context.Context, id int, name string) (user.User, error)
+	// UpdatePrivacy replaces a user's privacy settings and returns the stored user.
+	UpdatePrivacy(ctx context.Context, id int, privacy user.Privacy) (user.User, error)
+	// UpdateAvatar sets a user's AvatarHash, empty for none, and returns the
+	// stored user along with the hash it replaced.
+	UpdateAvatar(ctx context.Context, id int, hash string) (user.User, string, error)
+	// SaveGithubChallenge replaces the user's pending Github verification.
+	SaveGithubChallenge(ctx context.Context, challenge user.GithubChallenge) error
+	// GetGithubChallenge returns the user's pending Github verification.
+	GetGithubChallenge(ctx context.Context, userID int) (user.GithubChallenge, error)
+	// VerifyGithub records that the user owns the Github account handle and
+	// ends their pending verification, as long as handle is still the
+	// account their profile links to.
+	VerifyGithub(ctx context.Context, id int, handle string) (user.User, error)
+	// GetProfileFields returns the fields admins added to profiles, in the
+	// order of their Position.
+	GetProfileFields(ctx context.Context) ([]user.ProfileField, error)
+	// SaveProfileField adds a profile field or replaces the one with its name.
+	// If that changes who sees the field's values, it returns the IDs of the
+	// users holding them, whose views changed.
+	SaveProfileField(ctx context.Context, field user.ProfileField) (user.ProfileField, []int, error)
+	// DeleteProfileField removes a profile field along with every user's
+	// value for it, and returns the IDs of the users who had one.
+	DeleteProfileField(ctx context.Context, name string) ([]int, error)
+	// SetProfileFieldValues replaces a user's profile field values, by field
+	// name, and returns the stored user.
+	SetProfileFieldValues(ctx context.Context, id int, values map[string]string) (user.User, error)
+	// AddUserSkill lists the skill name, adding it to the catalog if it is
+	// new, among a user's skills. It returns false if the user already did.
+	AddUserSkill(ctx context.Context, id int, name string) (bool, error)
+	// RemoveUserSkill undoes AddUserSkill, along with the endorsements the
+	// user got for the skill. It returns false if there was nothing to undo.
+	RemoveUserSkill(ctx context.Context, id int, name string) (bool, error)
+	// GetUserSkills returns the skills of the given users, most endorsed first.
+	GetUserSkills(ctx context.Context, userIDs []int) ([]user.UserSkill, error)
+	// EndorseSkill makes endorserID endorse the user with id for a skill they
+	// list, counting it in one transaction. It returns false if they already
+	// did, and user.ErrEndorseBlocked if either user blocked the other.
+	EndorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error)
+	// UnendorseSkill undoes EndorseSkill. It returns false if there was
+	// nothing to undo.
+	UnendorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error)
+	// GetSkills returns up to limit skills of the catalog starting with
+	// prefix, those most users list first.
+	GetSkills(ctx context.Context, prefix string, limit int) ([]user.SkillSummary, error)
+	// GetTopUsersForSkill returns up to limit of the users listing the skill
+	// name, ranked by their endorsements for it and then by UserScore.
+	GetTopUsersForSkill(ctx context.Context, name string, limit int) ([]user.SkilledUser, error)
+	// GetUserNameChanges returns a user's username changes, newest first.
+	GetUserNameChanges(ctx context.Context, id int) ([]user.UserNameChange, error)
+	// GetUserNameChangeByOldName returns the latest change since the given time
+	// away from the username name.
+	GetUserNameChangeByOldName(ctx context.Context, name string, since time.Time) (user.UserNameChange, error)
+	// CountUserNameChanges returns how many times each of the given users
+	// changed their username. Users who never did are left out.
+	CountUserNameChanges(ctx context.Context, ids []int) (map[int]int64, error)
+	// AddReputationEvent appends event to the ledger and adds its Delta to the
+	// user's UserScore in one transaction. If an event with the same Source and
+	// IdempotencyKey was added before, it returns that one, and false, instead.
+	AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error)
+	// GetReputationEvents returns a page of a user's reputation events, newest first.
+	GetReputationEvents(ctx context.Context, userID, page, perPage int) (user.ReputationPage, error)
+	// RecalculateUserScores sets every UserScore that drifted from the sum of
+	// the user's reputation events back to it, and returns the IDs of the
+	// users it changed.
+	RecalculateUserScores(ctx context.Context) ([]int, error)
+	// AddAwards stores the awards users don't hold yet, and returns those.
+	AddAwards(ctx context.Context, awards []user.Award) ([]user.Award, error)
+	// GetAwards returns the awards of the given users, newest first.
+	GetAwards(ctx context.Context, userIDs []int) ([]user.Award, error)
+	// CountAwards counts the awards of each of the given users by class.
+	// Users without any are left out.
+	CountAwards(ctx context.Context, userIDs []int) (map[int]user.BadgeCounts, error)
+	// Follow makes followerID follow followeeID and adds to both users'
+	// follow counts in one transaction. It returns false if they already did,
+	// and user.ErrBlocked if either user blocked the other.
+	Follow(ctx context.Context, followerID, followeeID int) (bool, error)
+	// Unfollow undoes Follow. It returns false if there was nothing to undo.
+	Unfollow(ctx context.Context, followerID, followeeID int) (bool, error)
+	// GetFollowers returns up to limit follows of the user with id, newest
+	// first, starting after the cursor. The zero Cursor starts at the newest.
+	GetFollowers(ctx context.Context, id int, after user.Cursor, limit int) ([]user.Follow, error)
+	// GetFollowing is GetFollowers for the follows the user made.
+	GetFollowing(ctx context.Context, id int, after user.Cursor, limit int) ([]user.Follow, error)
+	// GetFollowsBetween returns the follows, either way, between two users.
+	GetFollowsBetween(ctx context.Context, a, b int) ([]user.Follow, error)
+	// BlockUser makes blockerID block blockedID, removing any follows between
+	// them in the same transaction. It returns false if they already did.
+	BlockUser(ctx context.Context, blockerID, blockedID int) (bool, error)
+	// UnblockUser undoes BlockUser. It returns false if there was nothing to undo.
+	UnblockUser(ctx context.Context, blockerID, blockedID int) (bool, error)
+	// IsBlocked reports whether either of two users blocked the other.
+	IsBlocked(ctx context.Context, a, b int) (bool, error)
+	// GetBlocks returns up to limit of the blocks blockerID made, newest
+	// first, starting after the cursor.
+	GetBlocks(ctx context.Context, blockerID int, after user.Cursor, limit int) ([]user.Block, error)
+	// FindBlocks returns those of the given blocks, identified by BlockerID
+	// and BlockedID, that exist.
+	FindBlocks(ctx context.Context, pairs []user.Block) ([]user.Block, error)
+	// MuteUser makes muterID mute mutedID. It returns false if they already did.
+	MuteUser(ctx context.Context, muterID, mutedID int) (bool, error)
+	// UnmuteUser undoes MuteUser. It returns false if there was nothing to undo.
+	UnmuteUser(ctx context.Context, muterID, mutedID int) (bool, error)
+	// GetMutes returns up to limit of the mutes muterID made, newest first,
+	// starting after the cursor.
+	GetMutes(ctx context.Context, muterID int, after user.Cursor, limit int) ([]user.Mute, error)
 }
 
+// store is the Store kept in MySQL. Search, the unique indexes and the
+// migrations all rely on MySQL, so it is the only database supported.
 type store struct {
-	DB *gorm.DB
+	DB         *gorm.DB
+	emailRules canonical.EmailRules
 }
 
-func New() (Store, error) {
+// Option configures optional behaviour of the store.
+type Option func(*store)
+
+// WithEmailRules sets the rules emails are canonicalized by before they are
+// compared. Changing them recomputes every stored email's canonical form on
+// the next start.
+func WithEmailRules(rules canonical.EmailRules) Option {
+	return func(s *store) {
+		s.emailRules = rules
+	}
+}
+
+func New(opts ...Option) (Store, error) {
 	dbUsername := os.Getenv("DB_USERNAME")
 	dbPassword := os.Getenv("DB_PASSWORD")
 	dbHost := os.Getenv("DB_HOST")
@@ -43,62 +186,471 @@ func New() (Store, error) {
 		return nil, err
 	}
 
-	err = db.AutoMigrate(&user.User{})
+	err = db.AutoMigrate(&user.User{}, &user.UserNameChange{}, &user.ReputationEvent{}, &user.Award{}, &user.Follow{}, &user.Block{}, &user.Mute{}, &user.GithubChallenge{}, &user.ProfileField{}, &user.ProfileFieldValue{}, &user.Skill{}, &user.UserSkill{}, &user.Endorsement{}, &migration{})
 
 	if err != nil {
 		log.Println("Failed to migrate database.")
 		return nil, err
 	}
-	log.Println("Connection to database successful.")
-	return &store{
+
+	if err = ensureSearchIndex(db); err != nil {
+		log.Println("Failed to create search index.")
+		return nil, err
+	}
+
+	s := &store{
 		DB: db,
-	}, nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if err = s.ensureUniqueIndexes(); err != nil {
+		log.Println("Failed to create unique indexes.")
+		return nil, err
+	}
+	if err = runMigration(db, "links", "1", s.normalizeStoredLinks); err != nil {
+		log.Println("Failed to normalize links.")
+		return nil, err
+	}
+	log.Println("Connection to database successful.")
+	return s, nil
 }
 
-func (s *store) GetAllUsers() ([]user.User, error) {
+func (s *store) GetAllUsers(ctx context.Context) ([]user.User, error) {
 	var users []user.User
-	if result := s.DB.Find(&users); result.Error != nil {
+	if result := s.DB.WithContext(ctx).Find(&users); result.Error != nil {
 		log.Printf("GORM ERROR: %s", result.Error.Error())
-		return []user.User{}, result.Error
+		return []user.User{}, translateError(result.Error)
+	}
+	if err := loadProfileFields(s.DB.WithContext(ctx), users); err != nil {
+		return []user.User{}, err
 	}
 	return users, nil
 }
 
-func (s *store) GetUserByID(id int) (user.User, error) {
+func (s *store) GetUserByID(ctx context.Context, id int) (user.User, error) {
 	var usr user.User
-	if result := s.DB.First(&usr, id); result.Error != nil {
-		return user.User{}, result.Error
+	if result := selectFields(ctx, s.DB.WithContext(ctx)).First(&usr, id); result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if err := loadUserProfileFields(s.DB.WithContext(ctx), &usr); err != nil {
+		return user.User{}, err
 	}
 	return usr, nil
 }
 
-func (s *store) GetUserByEmail(email string) (user.User, error) {
+func (s *store) GetUsersByIDs(ctx context.Context, ids []int) ([]user.User, error) {
+	users := []user.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if result := selectFields(ctx, s.DB.WithContext(ctx)).Where("id IN ?", ids).Find(&users); result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.User{}, translateError(result.Error)
+	}
+	if err := loadProfileFields(s.DB.WithContext(ctx), users); err != nil {
+		return []user.User{}, err
+	}
+	return users, nil
+}
+
+func (s *store) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	key, err := s.emailRules.Email(email)
+	if err != nil {
+		return user.User{}, domainerr.NotFound("user not found")
+	}
 	var usr user.User
-	if result := s.DB.Where("email = ?", email).First(&usr); result.Error != nil {
+	if result := s.DB.WithContext(ctx).Where("normalized_email = ?", key).First(&usr); result.Error != nil {
 		log.Println(result.Error.Error())
-		return user.User{}, result.Error
+		return user.User{}, translateError(result.Error)
+	}
+	if err := loadUserProfileFields(s.DB.WithContext(ctx), &usr); err != nil {
+		return user.User{}, err
 	}
 	return usr, nil
 }
 
-func (s *store) CreateUser(usr *user.User) (*user.User, error) {
-	if result := s.DB.Create(usr); result.Error != nil {
-		return nil, result.Error
+func (s *store) GetUserByUserName(ctx context.Context, name string) (user.User, error) {
+	key, err := canonical.UserNameKey(name)
+	if err != nil {
+		return user.User{}, domainerr.NotFound("user not found")
+	}
+	var usr user.User
+	if result := selectFields(ctx, s.DB.WithContext(ctx)).Where("normalized_user_name = ?", key).First(&usr); result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if err := loadUserProfileFields(s.DB.WithContext(ctx), &usr); err != nil {
+		return user.User{}, err
 	}
 	return usr, nil
 }
 
-func (s *store) UpdateUser(usr user.User) (user.User, error) {
-	if result := s.DB.UpdateColumns(usr); result.Error != nil {
-		return user.User{}, result.Error
+func (s *store) FindUsers(ctx context.Context, filter user.Filter) ([]user.User, error) {
+	var users []user.User
+	// gorm skips zero-valued fields in struct conditions, so unset filters match anything.
+	query := selectFields(ctx, s.DB.WithContext(ctx)).Where(&user.User{
+		Github:     filter.Github,
+		Linkedin:   filter.Linkedin,
+		Profession: filter.Profession,
+		WorkPlace:  filter.WorkPlace,
+	})
+	// Emails and usernames match the way lookups do, through their keys.
+	// Values with no key belong to nobody.
+	if filter.Email != "" {
+		key, err := s.emailRules.Email(filter.Email)
+		if err != nil {
+			return []user.User{}, nil
+		}
+		query = query.Where("normalized_email = ?", key)
+	}
+	if filter.UserName != "" {
+		key, err := canonical.UserNameKey(filter.UserName)
+		if err != nil {
+			return []user.User{}, nil
+		}
+		query = query.Where("normalized_user_name = ?", key)
+	}
+	query = matchProfileFields(s.DB.WithContext(ctx), query, filter.ProfileFields)
+	if result := query.Find(&users); result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.User{}, translateError(result.Error)
+	}
+	if err := loadProfileFields(s.DB.WithContext(ctx), users); err != nil {
+		return []user.User{}, err
+	}
+	return users, nil
+}
+
+// SearchUsers leaves the blockers of query.Viewer and matches on hidden
+// workplaces out in the query itself, so that the Total and the pages agree.
+func (s *store) SearchUsers(ctx context.Context, query user.SearchQuery) (user.SearchPage, error) {
+	page := user.SearchPage{
+		Results: []user.SearchResult{},
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	}
+	match, matchArgs, score, scoreArgs := searchExpressions(query)
+	matches := func() *gorm.DB {
+		db := s.DB.WithContext(ctx).Model(&user.User{}).Where(match, matchArgs...)
+		if query.Viewer != 0 {
+			db = db.Where("NOT EXISTS (SELECT 1 FROM blocks WHERE blocks.blocker_id = users.id AND blocks.blocked_id = ?)", query.Viewer)
+		}
+		return db
+	}
+	if result := matches().Count(&page.Total); result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return user.SearchPage{}, translateError(result.Error)
+	}
+	if page.Total == 0 {
+		return page, nil
+	}
+
+	var rows []searchRow
+	result := matches().
+		Select("*, "+score+" AS score", scoreArgs...).
+		Order("score DESC").
+		Limit(query.PerPage).
+		Offset(query.Offset()).
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return user.SearchPage{}, translateError(result.Error)
+	}
+	users := make([]user.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.User
+	}
+	if err := loadProfileFields(s.DB.WithContext(ctx), users); err != nil {
+		return user.SearchPage{}, err
+	}
+	for i, row := range rows {
+		page.Results = append(page.Results, user.SearchResult{
+			User:  users[i],
+			Score: row.Score,
+		})
+	}
+	return page, nil
+}
+
+// SuggestUsers matches prefix against the canonical usernames, as the
+// autocomplete index does.
+func (s *store) SuggestUsers(ctx context.Context, prefix string, limit int) ([]user.Suggestion, error) {
+	key, err := canonical.UserNameKey(prefix)
+	if err != nil {
+		return []user.Suggestion{}, nil
+	}
+	var suggestions []user.Suggestion
+	result := s.DB.WithContext(ctx).Model(&user.User{}).
+		Select("id, user_name, user_score").
+		Where("normalized_user_name LIKE ?", escapeLike(key)+"%").
+		Order("user_score DESC").
+		Order("user_name").
+		Limit(limit).
+		Scan(&suggestions)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.Suggestion{}, translateError(result.Error)
+	}
+	return suggestions, nil
+}
+
+func (s *store) CreateUser(ctx context.Context, usr *user.User) (*user.User, error) {
+	if err := s.canonicalize(usr); err != nil {
+		return nil, err
+	}
+	if usr.Privacy == (user.Privacy{}) {
+		// Otherwise the database defaults apply without usr reflecting them.
+		usr.Privacy = user.DefaultPrivacy
+	}
+	// Users start without reputation; it comes from their events.
+	usr.UserScore = 0
+	usr.FollowerCount, usr.FollowingCount = 0, 0
+	usr.AvatarHash = ""
+	usr.VerifiedGithub = ""
+	if result := s.DB.WithContext(ctx).Create(usr); result.Error != nil {
+		return nil, translateError(result.Error)
 	}
 	return usr, nil
 }
 
-func (s *store) DeleteUser(id int) error {
+func (s *store) UpdateUser(ctx context.Context, usr user.User) (user.User, error) {
+	if err := s.canonicalize(&usr); err != nil {
+		return user.User{}, err
+	}
+	expected := usr.Version
+	usr.Version = expected + 1
+	result := s.DB.WithContext(ctx).Model(&user.User{ID: usr.ID}).Where("version = ?", expected).Omit("user_score", "follower_count", "following_count", "avatar_hash", "verified_github").Updates(usr)
+	if result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		// Either the user does not exist or someone else updated it first.
+		if _, err := s.GetUserByID(ctx, usr.ID); err != nil {
+			return user.User{}, err
+		}
+		return user.User{}, user.ErrVersionMismatch
+	}
+	return s.GetUserByID(ctx, usr.ID)
+}
+
+func (s *store) DeleteUser(ctx context.Context, id int) error {
 	var usr user.User
-	if result := s.DB.Delete(&usr, id); result.Error != nil {
-		return result.Error
+	result := s.DB.WithContext(ctx).Delete(&usr, id)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return domainerr.NotFound("user not found")
 	}
 	return nil
 }
+
+func (s *store) GetDeletedUserByID(ctx context.Context, id int) (user.User, error) {
+	var usr user.User
+	if result := s.DB.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&usr, id); result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if err := loadUserProfileFields(s.DB.WithContext(ctx), &usr); err != nil {
+		return user.User{}, err
+	}
+	return usr, nil
+}
+
+func (s *store) RestoreUser(ctx context.Context, id int) (user.User, error) {
+	result := s.DB.WithContext(ctx).Unscoped().Model(&user.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND purged_at IS NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return user.User{}, domainerr.NotFound("no restorable user with id %d", id)
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+// PurgeDeletedUsers anonymizes users soft-deleted before deletedBefore. Their
+// personal data is overwritten for good, while the row itself stays behind as
+// a tombstone so content authored elsewhere in Nuboverflow still resolves.
+// Their username history goes too, so old names no longer lead to them, and
+// so do their follows, blocks, mutes, profile field values, skills and
+// endorsements.
+func (s *store) PurgeDeletedUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		purgeable := tx.Unscoped().Model(&user.User{}).
+			Where("deleted_at < ? AND purged_at IS NULL", deletedBefore)
+		if err := tx.Where("user_id IN (?)", purgeable.Session(&gorm.Session{}).Select("id")).
+			Delete(&user.UserNameChange{}).Error; err != nil {
+			return err
+		}
+		if err := forgetRelations(tx, purgeable.Session(&gorm.Session{}).Select("id")); err != nil {
+			return err
+		}
+		if err := forgetSkills(tx, purgeable.Session(&gorm.Session{}).Select("id")); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", purgeable.Session(&gorm.Session{}).Select("id")).
+			Delete(&user.GithubChallenge{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id IN (?)", purgeable.Session(&gorm.Session{}).Select("id")).
+			Delete(&user.ProfileFieldValue{}).Error; err != nil {
+			return err
+		}
+		result := purgeable.Updates(map[string]interface{}{
+			"user_name":            gorm.Expr("CONCAT('deleted-', id)"),
+			"email":                gorm.Expr("CONCAT('deleted-', id, '@users.invalid')"),
+			"normalized_user_name": gorm.Expr("CONCAT('deleted-', id)"),
+			"normalized_email":     gorm.Expr("CONCAT('deleted-', id, '@users.invalid')"),
+			"password":             "",
+			"github":               "",
+			"linkedin":             "",
+			"bio":                  "",
+			"profession":           "",
+			"work_place":           "",
+			"avatar_hash":          "",
+			"verified_github":      "",
+			"purged_at":            time.Now(),
+		})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		log.Printf("GORM ERROR: %s", err.Error())
+		return 0, translateError(err)
+	}
+	return purged, nil
+}
+
+func (s *store) RenameUser(ctx context.Context, id int, name string) (user.User, error) {
+	renamed := user.User{UserName: name}
+	if err := s.canonicalize(&renamed); err != nil {
+		return user.User{}, err
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current user.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, id).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&current).Updates(map[string]interface{}{
+			"user_name":            renamed.UserName,
+			"normalized_user_name": renamed.NormalizedUserName,
+			"version":              gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&user.UserNameChange{
+			UserID:                id,
+			OldUserName:           current.UserName,
+			NewUserName:           renamed.UserName,
+			NormalizedOldUserName: current.NormalizedUserName,
+		}).Error
+	})
+	if err != nil {
+		return user.User{}, translateError(err)
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+func (s *store) UpdatePrivacy(ctx context.Context, id int, privacy user.Privacy) (user.User, error) {
+	// A map, unlike a struct, also writes the settings that are false.
+	result := s.DB.WithContext(ctx).Model(&user.User{ID: id}).Updates(map[string]interface{}{
+		"privacy_show_email":      privacy.ShowEmail,
+		"privacy_show_github":     privacy.ShowGithub,
+		"privacy_show_linkedin":   privacy.ShowLinkedin,
+		"privacy_show_work_place": privacy.ShowWorkPlace,
+		"version":                 gorm.Expr("version + 1"),
+	})
+	if result.Error != nil {
+		return user.User{}, translateError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return user.User{}, domainerr.NotFound("user not found")
+	}
+	return s.GetUserByID(ctx, id)
+}
+
+func (s *store) UpdateAvatar(ctx context.Context, id int, hash string) (user.User, string, error) {
+	var replaced string
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current user.User
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "avatar_hash").First(&current, id).Error
+		if err != nil {
+			return err
+		}
+		replaced = current.AvatarHash
+		// A map, so that removing the avatar writes the empty hash.
+		return tx.Model(&user.User{ID: id}).Updates(map[string]interface{}{
+			"avatar_hash": hash,
+			"version":     gorm.Expr("version + 1"),
+		}).Error
+	})
+	if err != nil {
+		return user.User{}, "", txError(err)
+	}
+	updated, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, "", err
+	}
+	return updated, replaced, nil
+}
+
+func (s *store) GetUserNameChanges(ctx context.Context, id int) ([]user.UserNameChange, error) {
+	var changes []user.UserNameChange
+	result := s.DB.WithContext(ctx).Where("user_id = ?", id).Order("created_at DESC").Order("id DESC").Find(&changes)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.UserNameChange{}, translateError(result.Error)
+	}
+	return changes, nil
+}
+
+func (s *store) GetUserNameChangeByOldName(ctx context.Context, name string, since time.Time) (user.UserNameChange, error) {
+	key, err := canonical.UserNameKey(name)
+	if err != nil {
+		return user.UserNameChange{}, domainerr.NotFound("username change not found")
+	}
+	var change user.UserNameChange
+	result := s.DB.WithContext(ctx).
+		Where("normalized_old_user_name = ? AND created_at >= ?", key, since).
+		Order("created_at DESC").
+		Order("id DESC").
+		First(&change)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return user.UserNameChange{}, domainerr.Wrap(domainerr.KindNotFound, result.Error, "username change not found")
+		}
+		return user.UserNameChange{}, translateError(result.Error)
+	}
+	return change, nil
+}
+
+func (s *store) CountUserNameChanges(ctx context.Context, ids []int) (map[int]int64, error) {
+	var rows []struct {
+		UserID int
+		Count  int64
+	}
+	result := s.DB.WithContext(ctx).
+		Model(&user.UserNameChange{}).
+		Select("user_id, COUNT(*) AS count").
+		Where("user_id IN ?", ids).
+		Group("user_id").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return nil, translateError(result.Error)
+	}
+	counts := make(map[int]int64, len(rows))
+	for _, row := range rows {
+		counts[row.UserID] = row.Count
+	}
+	return counts, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it matches literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
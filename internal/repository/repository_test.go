@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestStore connects to the database configured through the DB_*
+// environment variables, as the server does, and skips the test when there
+// is none.
+func newTestStore(t *testing.T) *store {
+	if os.Getenv("DB_HOST") == "" {
+		t.Skip("DB_HOST is not set; skipping database test")
+	}
+	s, err := New()
+	if err != nil {
+		t.Fatalf("connecting to database: %s", err)
+	}
+	return s.(*store)
+}
+
+func TestCreateUserConcurrently(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+
+	race := func(t *testing.T, signup func(i int) *user.User) {
+		const signups = 20
+		var wg sync.WaitGroup
+		errs := make([]error, signups)
+		for i := 0; i < signups; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				usr := signup(i)
+				if _, err := s.CreateUser(ctx, usr); err == nil {
+					t.Cleanup(func() { s.DB.Unscoped().Delete(&user.User{}, usr.ID) })
+				} else {
+					errs[i] = err
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		winners := 0
+		for _, err := range errs {
+			if err == nil {
+				winners++
+				continue
+			}
+			assert.True(t, errors.Is(err, domainerr.ErrConflict), err.Error())
+		}
+		assert.Equal(t, 1, winners)
+	}
+
+	t.Run("Tests only one signup wins an email", func(t *testing.T) {
+		race(t, func(i int) *user.User {
+			email := fmt.Sprintf("racer-%d@example.com", run)
+			if i%2 == 1 {
+				email = strings.ToUpper(email)
+			}
+			return &user.User{UserName: fmt.Sprintf("racer-%d-%d", run, i), Email: email}
+		})
+	})
+
+	t.Run("Tests only one signup wins a username", func(t *testing.T) {
+		race(t, func(i int) *user.User {
+			name := fmt.Sprintf("Racer-%d", run)
+			if i%2 == 1 {
+				name = strings.ToLower(name)
+			}
+			return &user.User{UserName: name, Email: fmt.Sprintf("racer-%d-%d@example.com", run, i)}
+		})
+	})
+}
+
+func TestFindUsersByCanonicalKeys(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+
+	usr, err := s.CreateUser(ctx, &user.User{UserName: fmt.Sprintf("finder-%d", run), Email: fmt.Sprintf("finder-%d@example.com", run)})
+	if err != nil {
+		t.Fatalf("creating user: %s", err)
+	}
+	t.Cleanup(func() { s.DB.Unscoped().Delete(&user.User{}, usr.ID) })
+
+	byEmail, err := s.FindUsers(ctx, user.Filter{Email: strings.ToUpper(usr.Email)})
+	assert.NoError(t, err)
+	if assert.Len(t, byEmail, 1) {
+		assert.Equal(t, usr.ID, byEmail[0].ID)
+	}
+	byName, err := s.FindUsers(ctx, user.Filter{UserName: strings.ToUpper(usr.UserName)})
+	assert.NoError(t, err)
+	if assert.Len(t, byName, 1) {
+		assert.Equal(t, usr.ID, byName[0].ID)
+	}
+	none, err := s.FindUsers(ctx, user.Filter{Email: "not an email"})
+	assert.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func TestSearchUsersLeavesOutBlockers(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+	term := fmt.Sprintf("searchterm%d", run)
+
+	var ids []int
+	for i := 0; i < 3; i++ {
+		created, err := s.CreateUser(ctx, &user.User{UserName: fmt.Sprintf("searcher-%d-%d", run, i), Email: fmt.Sprintf("searcher-%d-%d@example.com", run, i), Bio: term})
+		if err != nil {
+			t.Fatalf("creating user: %s", err)
+		}
+		ids = append(ids, created.ID)
+	}
+	t.Cleanup(func() {
+		s.DB.Where("blocker_id IN ?", ids).Delete(&user.Block{})
+		s.DB.Unscoped().Delete(&user.User{}, ids)
+	})
+	_, err := s.BlockUser(ctx, ids[1], ids[0])
+	assert.NoError(t, err)
+
+	page, err := s.SearchUsers(ctx, user.SearchQuery{Query: term, Page: 1, PerPage: 1, Viewer: ids[0]})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	if assert.Len(t, page.Results, 1) {
+		assert.NotEqual(t, ids[1], page.Results[0].User.ID)
+	}
+	page, err = s.SearchUsers(ctx, user.SearchQuery{Query: term, Page: 2, PerPage: 1, Viewer: ids[0]})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Results, 1) {
+		assert.NotEqual(t, ids[1], page.Results[0].User.ID)
+	}
+
+	page, err = s.SearchUsers(ctx, user.SearchQuery{Query: term, Page: 1, PerPage: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), page.Total)
+}
+
+func TestSearchUsersMatchesHiddenWorkPlacesForAdminsAndOwners(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+	term := fmt.Sprintf("workplace%d", run)
+
+	usr, err := s.CreateUser(ctx, &user.User{UserName: fmt.Sprintf("hider-%d", run), Email: fmt.Sprintf("hider-%d@example.com", run), WorkPlace: term})
+	if err != nil {
+		t.Fatalf("creating user: %s", err)
+	}
+	t.Cleanup(func() { s.DB.Unscoped().Delete(&user.User{}, usr.ID) })
+	_, err = s.UpdatePrivacy(ctx, usr.ID, user.Privacy{ShowWorkPlace: false})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		query user.SearchQuery
+		total int64
+	}{
+		{user.SearchQuery{Query: term}, 0},
+		{user.SearchQuery{Query: term, Viewer: usr.ID + 1}, 0},
+		{user.SearchQuery{Query: term, Viewer: usr.ID}, 1},
+		{user.SearchQuery{Query: term, SearchHidden: true}, 1},
+	} {
+		tc.query.Page, tc.query.PerPage = 1, 10
+		page, err := s.SearchUsers(ctx, tc.query)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.total, page.Total, "%+v", tc.query)
+		assert.Len(t, page.Results, int(tc.total), "%+v", tc.query)
+	}
+}
+
+func TestAddReputationEventConcurrently(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+
+	usr, err := s.CreateUser(ctx, &user.User{UserName: fmt.Sprintf("earner-%d", run), Email: fmt.Sprintf("earner-%d@example.com", run)})
+	if err != nil {
+		t.Fatalf("creating user: %s", err)
+	}
+	t.Cleanup(func() {
+		s.DB.Where("user_id = ?", usr.ID).Delete(&user.ReputationEvent{})
+		s.DB.Unscoped().Delete(&user.User{}, usr.ID)
+	})
+	created, err := s.GetUserByID(ctx, usr.ID)
+	assert.NoError(t, err)
+
+	const posts = 20
+	var wg sync.WaitGroup
+	added := make([]bool, posts)
+	for i := 0; i < posts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every other post is a retry of the first event.
+			key := fmt.Sprintf("vote-%d", i)
+			if i%2 == 1 {
+				key = "vote-0"
+			}
+			_, ok, err := s.AddReputationEvent(ctx, user.ReputationEvent{UserID: usr.ID, Source: "questions", IdempotencyKey: key, Reason: "answer_upvoted", Delta: 10})
+			assert.NoError(t, err)
+			added[i] = ok
+		}(i)
+	}
+	wg.Wait()
+
+	counted := 0
+	for _, ok := range added {
+		if ok {
+			counted++
+		}
+	}
+	assert.Equal(t, posts/2, counted)
+	stored, err := s.GetUserByID(ctx, usr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, posts/2*10, stored.UserScore)
+	assert.Equal(t, created.Version, stored.Version, "score changes leave the version alone")
+
+	s.DB.Model(&user.User{}).Where("id = ?", usr.ID).Update("user_score", 7)
+	fixed, err := s.RecalculateUserScores(ctx)
+	assert.NoError(t, err)
+	assert.Contains(t, fixed, usr.ID)
+	stored, err = s.GetUserByID(ctx, usr.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, posts/2*10, stored.UserScore)
+	assert.Equal(t, created.Version, stored.Version)
+}
+
+func TestFollowConcurrently(t *testing.T) {
+	s := newTestStore(t)
+	ctx := context.Background()
+	run := time.Now().UnixNano()
+
+	var a, b *user.User
+	for i, u := range []**user.User{&a, &b} {
+		created, err := s.CreateUser(ctx, &user.User{UserName: fmt.Sprintf("follower-%d-%d", run, i), Email: fmt.Sprintf("follower-%d-%d@example.com", run, i)})
+		if err != nil {
+			t.Fatalf("creating user: %s", err)
+		}
+		*u = created
+	}
+	t.Cleanup(func() {
+		s.DB.Where("follower_id IN ? OR followee_id IN ?", []int{a.ID, b.ID}, []int{a.ID, b.ID}).Delete(&user.Follow{})
+		s.DB.Where("blocker_id IN ? OR blocked_id IN ?", []int{a.ID, b.ID}, []int{a.ID, b.ID}).Delete(&user.Block{})
+		s.DB.Where("muter_id IN ? OR muted_id IN ?", []int{a.ID, b.ID}, []int{a.ID, b.ID}).Delete(&user.Mute{})
+		s.DB.Unscoped().Delete(&user.User{}, []int{a.ID, b.ID})
+	})
+
+	// Both follow each other, many times over, at once.
+	const follows = 20
+	var wg sync.WaitGroup
+	for i := 0; i < follows; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			follower, followee := a.ID, b.ID
+			if i%2 == 1 {
+				follower, followee = followee, follower
+			}
+			_, err := s.Follow(ctx, follower, followee)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	counts := func() (int, int) {
+		stored, err := s.GetUserByID(ctx, a.ID)
+		assert.NoError(t, err)
+		return stored.FollowerCount, stored.FollowingCount
+	}
+	followers, following := counts()
+	assert.Equal(t, 1, followers)
+	assert.Equal(t, 1, following)
+	between, err := s.GetFollowsBetween(ctx, a.ID, b.ID)
+	assert.NoError(t, err)
+	assert.Len(t, between, 2)
+
+	blocked, err := s.BlockUser(ctx, a.ID, b.ID)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+	followers, following = counts()
+	assert.Equal(t, 0, followers)
+	assert.Equal(t, 0, following)
+	_, err = s.Follow(ctx, b.ID, a.ID)
+	assert.ErrorIs(t, err, user.ErrBlocked)
+
+	found, err := s.FindBlocks(ctx, []user.Block{{BlockerID: a.ID, BlockedID: b.ID}, {BlockerID: b.ID, BlockedID: a.ID}})
+	assert.NoError(t, err)
+	assert.Len(t, found, 1)
+	assert.Equal(t, a.ID, found[0].BlockerID)
+}
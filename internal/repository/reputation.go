@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errEventExists rolls back adding a reputation event that was added before.
+var errEventExists = errors.New("reputation event already exists")
+
+func (s *store) AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Update the user first: holding its row orders this against
+		// RecalculateUserScores, which would otherwise count the event twice.
+		// The Version is left alone, as a vote shouldn't fail an edit of the
+		// profile in progress; UpdateUser never writes the score.
+		result := tx.Model(&user.User{}).Where("id = ?", event.UserID).
+			Update("user_score", gorm.Expr("user_score + ?", event.Delta))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domainerr.NotFound("user not found")
+		}
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errEventExists
+		}
+		return nil
+	})
+	if errors.Is(err, errEventExists) {
+		var existing user.ReputationEvent
+		result := s.DB.WithContext(ctx).
+			Where("source = ? AND idempotency_key = ?", event.Source, event.IdempotencyKey).
+			First(&existing)
+		if result.Error != nil {
+			log.Printf("GORM ERROR: %s", result.Error.Error())
+			return user.ReputationEvent{}, false, translateError(result.Error)
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		var domainErr *domainerr.Error
+		if errors.As(err, &domainErr) {
+			return user.ReputationEvent{}, false, err
+		}
+		log.Printf("GORM ERROR: %s", err.Error())
+		return user.ReputationEvent{}, false, translateError(err)
+	}
+	return event, true, nil
+}
+
+func (s *store) GetReputationEvents(ctx context.Context, userID, page, perPage int) (user.ReputationPage, error) {
+	events := user.ReputationPage{
+		Events:  []user.ReputationEvent{},
+		Page:    page,
+		PerPage: perPage,
+	}
+	query := s.DB.WithContext(ctx).Model(&user.ReputationEvent{}).Where("user_id = ?", userID)
+	if result := query.Count(&events.Total); result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return user.ReputationPage{}, translateError(result.Error)
+	}
+	if events.Total == 0 {
+		return events, nil
+	}
+	result := s.DB.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Order("id DESC").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&events.Events)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return user.ReputationPage{}, translateError(result.Error)
+	}
+	return events, nil
+}
+
+func (s *store) RecalculateUserScores(ctx context.Context) ([]int, error) {
+	var drifted []int
+	result := s.DB.WithContext(ctx).Raw(`SELECT users.id FROM users
+		LEFT JOIN reputation_events ON reputation_events.user_id = users.id
+		GROUP BY users.id, users.user_score
+		HAVING users.user_score <> COALESCE(SUM(reputation_events.delta), 0)`).
+		Scan(&drifted)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return nil, translateError(result.Error)
+	}
+
+	fixed := make([]int, 0, len(drifted))
+	for _, id := range drifted {
+		// Sum again under the row lock, counting events added since the scan.
+		// Like AddReputationEvent, this leaves the Version alone.
+		result := s.DB.WithContext(ctx).Exec(`UPDATE users
+			SET user_score = (SELECT COALESCE(SUM(delta), 0) FROM reputation_events WHERE user_id = ?),
+				updated_at = ?
+			WHERE id = ?`, id, time.Now(), id)
+		if result.Error != nil {
+			log.Printf("GORM ERROR: %s", result.Error.Error())
+			return fixed, translateError(result.Error)
+		}
+		fixed = append(fixed, id)
+	}
+	return fixed, nil
+}
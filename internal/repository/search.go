@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+)
+
+const (
+	searchIndexName       = "idx_users_search"
+	publicSearchIndexName = "idx_users_search_public"
+)
+
+// searchMatch full-text searches users with MySQL's FULLTEXT index. It is
+// used both as the WHERE condition and as the relevance score, and takes the
+// search text as its only placeholder argument.
+const searchMatch = "MATCH(user_name, bio, profession, work_place) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// publicSearchMatch is searchMatch without the workplace, which users can
+// hide. MySQL only matches the exact column lists of FULLTEXT indexes, so it
+// has an index of its own.
+const publicSearchMatch = "MATCH(user_name, bio, profession) AGAINST (? IN NATURAL LANGUAGE MODE)"
+
+// searchesWorkPlace is true for users whose workplace a search can match:
+// those who show it, and the viewer, its only placeholder argument.
+const searchesWorkPlace = "(privacy_show_work_place = TRUE OR users.id = ?)"
+
+// searchRow is a user row along with the relevance score computed by the database.
+type searchRow struct {
+	user.User
+	Score float64
+}
+
+// searchExpressions returns the WHERE condition and relevance expression
+// used for query, along with the arguments each takes. Unless query
+// searches hidden workplaces, users who hide theirs are matched and ranked
+// without it, so a search cannot find them by it.
+func searchExpressions(query user.SearchQuery) (match string, matchArgs []interface{}, score string, scoreArgs []interface{}) {
+	if query.SearchHidden {
+		args := []interface{}{query.Query}
+		return searchMatch, args, searchMatch, args
+	}
+	match = "(" + searchesWorkPlace + " AND " + searchMatch + ") OR " + publicSearchMatch
+	score = "IF(" + searchesWorkPlace + ", " + searchMatch + ", " + publicSearchMatch + ")"
+	args := []interface{}{query.Viewer, query.Query, query.Query}
+	return match, args, score, args
+}
+
+// ensureSearchIndex creates the full-text indexes backing SearchUsers if they do not exist yet.
+func ensureSearchIndex(db *gorm.DB) error {
+	indexes := []struct {
+		name, columns string
+	}{
+		{searchIndexName, "user_name, bio, profession, work_place"},
+		{publicSearchIndexName, "user_name, bio, profession"},
+	}
+	for _, index := range indexes {
+		if db.Migrator().HasIndex(&user.User{}, index.name) {
+			continue
+		}
+		if err := db.Exec("CREATE FULLTEXT INDEX " + index.name + " ON users (" + index.columns + ")").Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
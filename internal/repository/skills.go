@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (s *store) AddUserSkill(ctx context.Context, id int, name string) (bool, error) {
+	var added bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := usersExist(tx, id); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&user.Skill{Name: name}).Error; err != nil {
+			return err
+		}
+		var skill user.Skill
+		if err := tx.Where("name = ?", name).First(&skill).Error; err != nil {
+			return err
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&user.UserSkill{UserID: id, SkillID: skill.ID})
+		added = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return added, nil
+}
+
+func (s *store) RemoveUserSkill(ctx context.Context, id int, name string) (bool, error) {
+	var removed bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := usersExist(tx, id); err != nil {
+			return err
+		}
+		skill, err := findSkill(tx, name)
+		if err != nil || skill.ID == 0 {
+			return err
+		}
+		result := tx.Where("user_id = ? AND skill_id = ?", id, skill.ID).Delete(&user.UserSkill{})
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected > 0
+		return tx.Where("user_id = ? AND skill_id = ?", id, skill.ID).Delete(&user.Endorsement{}).Error
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return removed, nil
+}
+
+func (s *store) GetUserSkills(ctx context.Context, userIDs []int) ([]user.UserSkill, error) {
+	var rows []struct {
+		UserID       int
+		SkillID      int
+		Endorsements int
+		CreatedAt    time.Time
+		Skill        string
+	}
+	result := s.DB.WithContext(ctx).Model(&user.UserSkill{}).
+		Select("user_skills.user_id, user_skills.skill_id, user_skills.endorsements, user_skills.created_at, skills.name AS skill").
+		Joins("JOIN skills ON skills.id = user_skills.skill_id").
+		Where("user_skills.user_id IN ?", userIDs).
+		Order("user_skills.endorsements DESC").
+		Order("skills.name").
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.UserSkill{}, translateError(result.Error)
+	}
+	skills := make([]user.UserSkill, len(rows))
+	for i, row := range rows {
+		skills[i] = user.UserSkill{
+			UserID:       row.UserID,
+			SkillID:      row.SkillID,
+			Endorsements: row.Endorsements,
+			CreatedAt:    row.CreatedAt,
+			Skill:        row.Skill,
+		}
+	}
+	return skills, nil
+}
+
+func (s *store) EndorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error) {
+	var endorsed bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockUsers(tx, endorserID, id); err != nil {
+			return err
+		}
+		blocked, err := blockedEitherWay(tx, endorserID, id)
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return user.ErrEndorseBlocked
+		}
+		skill, err := findSkill(tx, name)
+		if err != nil {
+			return err
+		}
+		var listed int64
+		if err := tx.Model(&user.UserSkill{}).Where("user_id = ? AND skill_id = ?", id, skill.ID).Count(&listed).Error; err != nil {
+			return err
+		}
+		if listed == 0 {
+			return domainerr.NotFound("user does not list %s as a skill", name)
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&user.Endorsement{UserID: id, SkillID: skill.ID, EndorserID: endorserID})
+		if result.Error != nil {
+			return result.Error
+		}
+		endorsed = result.RowsAffected > 0
+		if !endorsed {
+			return nil
+		}
+		return addEndorsements(tx, id, skill.ID, 1)
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return endorsed, nil
+}
+
+func (s *store) UnendorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error) {
+	var unendorsed bool
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Deleted users can still take their endorsements back.
+		if err := lockUsers(tx.Unscoped(), endorserID, id); err != nil {
+			return err
+		}
+		skill, err := findSkill(tx, name)
+		if err != nil || skill.ID == 0 {
+			return err
+		}
+		result := tx.Where("user_id = ? AND skill_id = ? AND endorser_id = ?", id, skill.ID, endorserID).Delete(&user.Endorsement{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		unendorsed = true
+		return addEndorsements(tx, id, skill.ID, -1)
+	})
+	if err != nil {
+		return false, txError(err)
+	}
+	return unendorsed, nil
+}
+
+func (s *store) GetSkills(ctx context.Context, prefix string, limit int) ([]user.SkillSummary, error) {
+	skills := []user.SkillSummary{}
+	result := s.DB.WithContext(ctx).Model(&user.Skill{}).
+		Select("skills.name, COUNT(user_skills.user_id) AS users").
+		Joins("LEFT JOIN user_skills ON user_skills.skill_id = skills.id").
+		Where("skills.name LIKE ?", escapeLike(prefix)+"%").
+		Group("skills.id").
+		Group("skills.name").
+		Order("users DESC").
+		Order("skills.name").
+		Limit(limit).
+		Scan(&skills)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.SkillSummary{}, translateError(result.Error)
+	}
+	return skills, nil
+}
+
+// skilledRow is a user row along with their endorsements for a skill.
+type skilledRow struct {
+	user.User
+	Endorsements int
+}
+
+func (s *store) GetTopUsersForSkill(ctx context.Context, name string, limit int) ([]user.SkilledUser, error) {
+	var rows []skilledRow
+	result := s.DB.WithContext(ctx).Model(&user.User{}).
+		Select("users.*, user_skills.endorsements AS endorsements").
+		Joins("JOIN user_skills ON user_skills.user_id = users.id").
+		Joins("JOIN skills ON skills.id = user_skills.skill_id").
+		Where("skills.name = ?", name).
+		Order("user_skills.endorsements DESC").
+		Order("users.user_score DESC").
+		Order("users.id").
+		Limit(limit).
+		Scan(&rows)
+	if result.Error != nil {
+		log.Printf("GORM ERROR: %s", result.Error.Error())
+		return []user.SkilledUser{}, translateError(result.Error)
+	}
+	users := make([]user.User, len(rows))
+	for i, row := range rows {
+		users[i] = row.User
+	}
+	if err := loadProfileFields(s.DB.WithContext(ctx), users); err != nil {
+		return []user.SkilledUser{}, err
+	}
+	skilled := make([]user.SkilledUser, len(rows))
+	for i, row := range rows {
+		skilled[i] = user.SkilledUser{User: users[i], Endorsements: row.Endorsements}
+	}
+	return skilled, nil
+}
+
+// findSkill returns the skill in the catalog named name, or the zero Skill if
+// there is none.
+func findSkill(tx *gorm.DB, name string) (user.Skill, error) {
+	var skill user.Skill
+	err := tx.Where("name = ?", name).First(&skill).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return user.Skill{}, nil
+	}
+	return skill, err
+}
+
+// addEndorsements adds delta to the endorsements of a user's skill.
+func addEndorsements(tx *gorm.DB, id, skillID, delta int) error {
+	return tx.Model(&user.UserSkill{}).Where("user_id = ? AND skill_id = ?", id, skillID).
+		UpdateColumn("endorsements", gorm.Expr("endorsements + ?", delta)).Error
+}
+
+// forgetSkills deletes the skills of the users selected by users, the
+// endorsements they got for them and those they gave, which it takes off the
+// counts of the users they endorsed.
+func forgetSkills(tx *gorm.DB, users *gorm.DB) error {
+	var given []user.Endorsement
+	if err := tx.Where("endorser_id IN (?) AND user_id NOT IN (?)", users, users).Find(&given).Error; err != nil {
+		return err
+	}
+	for _, e := range given {
+		if err := addEndorsements(tx, e.UserID, e.SkillID, -1); err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("endorser_id IN (?) OR user_id IN (?)", users, users).Delete(&user.Endorsement{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id IN (?)", users).Delete(&user.UserSkill{}).Error
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	"gorm.io/gorm"
+)
+
+const (
+	emailIndexName    = "idx_users_normalized_email"
+	userNameIndexName = "idx_users_normalized_user_name"
+
+	// activeColumn is a MySQL generated column that is 1 for users that are not
+	// deleted and NULL otherwise. MySQL has no partial indexes, but a unique
+	// index never considers NULLs equal, so indexing it alongside a key makes
+	// the key unique among active users only.
+	activeColumn = "active"
+)
+
+// uniqueKeys maps each unique index to the canonical column it covers.
+var uniqueKeys = []struct {
+	index, column string
+}{
+	{emailIndexName, "normalized_email"},
+	{userNameIndexName, "normalized_user_name"},
+}
+
+// ensureUniqueIndexes makes the canonical email and username unique among
+// users that are not deleted, so two concurrent signups cannot both claim
+// one. Stored canonical forms are brought up to date first whenever the
+// rules they are computed by changed; if that leaves duplicates behind,
+// creating the index fails.
+func (s *store) ensureUniqueIndexes() error {
+	db := s.DB
+	if !db.Migrator().HasColumn(&user.User{}, activeColumn) {
+		err := db.Exec("ALTER TABLE users ADD COLUMN " + activeColumn +
+			" TINYINT(1) AS (IF(deleted_at IS NULL, 1, NULL)) VIRTUAL").Error
+		if err != nil {
+			return err
+		}
+	}
+
+	rules := fmt.Sprintf("%d provider-rules=%t", canonical.Version, s.emailRules.ProviderRules)
+	if err := runMigration(db, "canonical-keys", rules, s.syncCanonicalKeys); err != nil {
+		return err
+	}
+
+	for _, key := range uniqueKeys {
+		if db.Migrator().HasIndex(&user.User{}, key.index) {
+			continue
+		}
+		err := db.Exec("CREATE UNIQUE INDEX " + key.index +
+			" ON users (" + key.column + ", " + activeColumn + ")").Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncCanonicalKeys recomputes the canonical email and username of every
+// user that has not been purged, and writes back the ones that changed: rows
+// from before the columns existed, or from before the canonicalization rules
+// changed. A user whose key cannot be computed or now collides with another
+// user's is logged and left alone for an admin to resolve.
+func (s *store) syncCanonicalKeys() error {
+	var users []user.User
+	result := s.DB.Unscoped().Where("purged_at IS NULL").FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, usr := range users {
+			email, userName := usr.NormalizedEmail, usr.NormalizedUserName
+			if err := s.canonicalize(&usr); err != nil {
+				log.Printf("Cannot canonicalize user %d: %s", usr.ID, err)
+				continue
+			}
+			if usr.NormalizedEmail == email && usr.NormalizedUserName == userName {
+				continue
+			}
+			err := s.DB.Unscoped().Model(&user.User{ID: usr.ID}).UpdateColumns(map[string]interface{}{
+				"normalized_email":     usr.NormalizedEmail,
+				"normalized_user_name": usr.NormalizedUserName,
+			}).Error
+			if err != nil {
+				log.Printf("Failed to update canonical keys of user %d: %s", usr.ID, translateError(err))
+			}
+		}
+		return nil
+	})
+	return result.Error
+}
+
+// canonicalize fills in the canonical forms of whichever of usr's Email and
+// UserName are set, and puts UserName in the form it is displayed in.
+func (s *store) canonicalize(usr *user.User) error {
+	if usr.Email != "" {
+		key, err := s.emailRules.Email(usr.Email)
+		if err != nil {
+			return err
+		}
+		usr.NormalizedEmail = key
+	}
+	if usr.UserName != "" {
+		name, err := canonical.UserName(usr.UserName)
+		if err != nil {
+			return err
+		}
+		usr.UserName = name
+		if usr.NormalizedUserName, err = canonical.UserNameKey(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
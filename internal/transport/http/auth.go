@@ -0,0 +1,95 @@
+package http
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	usr "github.com/millbj92/nuboverflow-users/internal/user/service"
+)
+
+// Requests reach this service through the Nuboverflow API gateway, which
+// authenticates the caller and forwards their role in this header.
+const roleHeader = "X-User-Role"
+
+const roleAdmin = "admin"
+
+// roleService is the role of other Nuboverflow services calling this one.
+const roleService = "service"
+
+// RequireRole rejects requests whose caller has none of the given roles.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		role := c.Get(roleHeader)
+		if role == "" {
+			return domainerr.Unauthorized("caller is not authenticated")
+		}
+		for _, allowed := range roles {
+			if role == allowed {
+				return c.Next()
+			}
+		}
+		return domainerr.Forbidden("requires the %s role", strings.Join(roles, " or "))
+	}
+}
+
+// userIDHeader carries the authenticated caller's user ID, also set by the gateway.
+const userIDHeader = "X-User-ID"
+
+// serviceNameHeader names the calling service, set by the gateway for callers
+// with the service role.
+const serviceNameHeader = "X-Service-Name"
+
+// Caller is who made a request, as the gateway vouches for. The zero Caller
+// is anonymous.
+type Caller struct {
+	ID   int
+	Role string
+	// Service is the name of the calling service, for the service role.
+	Service string
+}
+
+// callerOf returns the caller of the request c.
+func callerOf(c *fiber.Ctx) Caller {
+	id, err := intFromString(c.Get(userIDHeader))
+	if err != nil {
+		id = 0
+	}
+	return Caller{
+		ID:      id,
+		Role:    c.Get(roleHeader),
+		Service: c.Get(serviceNameHeader),
+	}
+}
+
+// IsAdmin reports whether the caller has the admin role.
+func (c Caller) IsAdmin() bool {
+	return c.Role == roleAdmin
+}
+
+// Is reports whether the caller is the user with id.
+func (c Caller) Is(id int) bool {
+	return c.ID != 0 && c.ID == id
+}
+
+// viewing returns ctx with the caller as the viewer users are looked up for,
+// so those who blocked them are not found. Admins see everyone.
+func (c Caller) viewing(ctx context.Context) context.Context {
+	if c.ID == 0 || c.IsAdmin() {
+		return ctx
+	}
+	return usr.WithViewer(ctx, c.ID)
+}
+
+// canManage returns an error unless the caller is the user with id or an admin.
+func (c Caller) canManage(id int) error {
+	switch {
+	case c.Is(id) || c.IsAdmin():
+		return nil
+	case c.ID == 0 && c.Role == "":
+		return domainerr.Unauthorized("caller is not authenticated")
+	default:
+		return domainerr.Forbidden("only the user and admins can do this")
+	}
+}
@@ -0,0 +1,119 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// problemContentType is the media type of RFC 7807 problem details.
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the type URI of every problem raised from a domain error.
+const problemTypeBase = "https://nuboverflow.com/problems/"
+
+// Problem is an RFC 7807 problem details response. Errors lists the
+// individual fields that failed validation, if any.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes why a single request field was rejected. Field is
+// the field's JSON name and Rule the validation rule it broke.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type problemKind struct {
+	status int
+	slug   string
+}
+
+var problemForKind = map[domainerr.Kind]problemKind{
+	domainerr.KindNotFound:           {fiber.StatusNotFound, "not-found"},
+	domainerr.KindConflict:           {fiber.StatusConflict, "conflict"},
+	domainerr.KindValidation:         {fiber.StatusBadRequest, "validation-error"},
+	domainerr.KindUnauthorized:       {fiber.StatusUnauthorized, "unauthorized"},
+	domainerr.KindForbidden:          {fiber.StatusForbidden, "forbidden"},
+	domainerr.KindPreconditionFailed: {fiber.StatusPreconditionFailed, "precondition-failed"},
+}
+
+// ErrorHandler turns every error returned by a handler into a problem
+// details response. Domain errors get the status code for their kind, fiber
+// errors keep their own, and anything else is logged and reported as a 500
+// without leaking its details.
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	problem := Problem{
+		Type:     "about:blank",
+		Status:   fiber.StatusInternalServerError,
+		Instance: c.OriginalURL(),
+	}
+
+	var domainErr *domainerr.Error
+	var fiberErr *fiber.Error
+	switch {
+	case errors.As(err, &domainErr):
+		kind := problemForKind[domainErr.Kind]
+		problem.Type = problemTypeBase + kind.slug
+		problem.Status = kind.status
+		problem.Detail = domainErr.Message
+	case errors.As(err, &fiberErr):
+		problem.Status = fiberErr.Code
+		problem.Detail = fiberErr.Message
+	case errors.Is(err, context.DeadlineExceeded):
+		problem.Status = fiber.StatusGatewayTimeout
+		problem.Detail = "The request took too long to complete."
+	default:
+		log.Printf("Unhandled error on %s %s: %s", c.Method(), c.Path(), err)
+	}
+	problem.Title = utils.StatusMessage(problem.Status)
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		trans, _ := c.Locals(translatorKey).(ut.Translator)
+		problem.Errors = fieldErrors(validationErrs, trans)
+		if trans != nil {
+			problem.Detail = validationFailedDetail[trans.Locale()]
+			c.Set(fiber.HeaderContentLanguage, trans.Locale())
+		}
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, problemContentType)
+	return c.Status(problem.Status).Send(body)
+}
+
+// fieldErrors describes each failed validation in a form clients can attach
+// to their inputs, in the language of trans when there is one.
+func fieldErrors(errs validator.ValidationErrors, trans ut.Translator) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		message := fe.Error()
+		if trans != nil {
+			message = fe.Translate(trans)
+		}
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: message,
+		})
+	}
+	return fields
+}
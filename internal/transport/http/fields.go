@@ -0,0 +1,246 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	usr "github.com/millbj92/nuboverflow-users/internal/user/service"
+)
+
+// fieldSpec ties a name clients can pass in ?fields= to the key it selects
+// in a user view and the columns that have to be loaded for it. Fields with
+// no columns are always loaded.
+type fieldSpec struct {
+	key     string
+	columns []string
+}
+
+var userFields = map[string]fieldSpec{
+	"id":         {key: "ID"},
+	"createdAt":  {key: "CreatedAt", columns: []string{"created_at"}},
+	"updatedAt":  {key: "UpdatedAt", columns: []string{"updated_at"}},
+	"username":   {key: "UserName", columns: []string{"user_name"}},
+	"email":      {key: "Email", columns: []string{"email"}},
+	"github":     {key: "Github", columns: []string{"github"}},
+	"linkedin":   {key: "Linkedin", columns: []string{"linkedin"}},
+	"userScore":  {key: "UserScore", columns: []string{"user_score"}},
+	"bio":        {key: "Bio", columns: []string{"bio"}},
+	"profession": {key: "Profession", columns: []string{"profession"}},
+	"workplace":  {key: "WorkPlace", columns: []string{"work_place"}},
+	"version":    {key: "Version"},
+	"privacy":    {key: "Privacy"},
+	"deletedAt":  {key: "DeletedAt", columns: []string{"deleted_at"}},
+	"purgedAt":   {key: "PurgedAt", columns: []string{"purged_at"}},
+
+	"followerCount":  {key: "FollowerCount", columns: []string{"follower_count"}},
+	"followingCount": {key: "FollowingCount", columns: []string{"following_count"}},
+	"avatarHash":     {key: "AvatarHash", columns: []string{"avatar_hash"}},
+	"githubVerified": {key: "GithubVerified", columns: []string{"github", "verified_github"}},
+	"profileFields":  {key: "ProfileFields"},
+}
+
+// expander loads a resource related to each of users, keyed by user ID.
+type expander func(ctx context.Context, service usr.Service, users []user.User) (map[int]interface{}, error)
+
+// expansions are the related resources clients can ask for with ?expand=.
+// Each is added to a user under its own name.
+var expansions = map[string]expander{
+	"badges": expandBadges,
+	"skills": expandSkills,
+	"stats":  expandStats,
+}
+
+// unavailableExpansions are related resources this service cannot expand,
+// with the reason it gives clients asking for them.
+var unavailableExpansions = map[string]string{
+	"roles": "roles are assigned by the API gateway and not stored by this service",
+}
+
+func expandBadges(ctx context.Context, service usr.Service, users []user.User) (map[int]interface{}, error) {
+	awards, err := service.GetAwardsByUserIDs(ctx, userIDs(users))
+	if err != nil {
+		return nil, err
+	}
+	related := make(map[int]interface{}, len(awards))
+	for id, a := range awards {
+		related[id] = a
+	}
+	return related, nil
+}
+
+func expandSkills(ctx context.Context, service usr.Service, users []user.User) (map[int]interface{}, error) {
+	skills, err := service.GetSkillsByUserIDs(ctx, userIDs(users))
+	if err != nil {
+		return nil, err
+	}
+	related := make(map[int]interface{}, len(skills))
+	for id, s := range skills {
+		related[id] = s
+	}
+	return related, nil
+}
+
+func expandStats(ctx context.Context, service usr.Service, users []user.User) (map[int]interface{}, error) {
+	stats, err := service.GetUserStats(ctx, userIDs(users))
+	if err != nil {
+		return nil, err
+	}
+	related := make(map[int]interface{}, len(stats))
+	for id, s := range stats {
+		related[id] = s
+	}
+	return related, nil
+}
+
+// shape is how a request wants users represented: which of their fields to
+// include, all of them if fields is nil, and which related resources to add.
+type shape struct {
+	fields []string
+	expand []string
+}
+
+// shapeOf reads the ?fields= and ?expand= query parameters, rejecting names
+// it doesn't know or cannot expand.
+func shapeOf(c *fiber.Ctx) (shape, error) {
+	var s shape
+	var err error
+	if raw := c.Query("fields"); raw != "" {
+		fieldNames := make([]string, 0, len(userFields))
+		for name := range userFields {
+			fieldNames = append(fieldNames, name)
+		}
+		if s.fields, err = nameList("fields", raw, fieldNames); err != nil {
+			return shape{}, err
+		}
+	}
+	if raw := c.Query("expand"); raw != "" {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if reason, ok := unavailableExpansions[name]; ok {
+				return shape{}, domainerr.Validation("query parameter expand cannot include %s: %s", name, reason)
+			}
+		}
+		expansionNames := make([]string, 0, len(expansions))
+		for name := range expansions {
+			expansionNames = append(expansionNames, name)
+		}
+		if s.expand, err = nameList("expand", raw, expansionNames); err != nil {
+			return shape{}, err
+		}
+	}
+	return s, nil
+}
+
+// nameList parses the comma-separated names in the query parameter param,
+// dropping repeats and rejecting any not in valid.
+func nameList(param, raw string, valid []string) ([]string, error) {
+	sort.Strings(valid)
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		if i := sort.SearchStrings(valid, name); i == len(valid) || valid[i] != name {
+			return nil, domainerr.Validation("query parameter %s has unknown name %q, expected one of: %s", param, name, strings.Join(valid, ", "))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, domainerr.Validation("query parameter %s must name at least one of: %s", param, strings.Join(valid, ", "))
+	}
+	return names, nil
+}
+
+// context returns a context under which user lookups load only the columns
+// the requested fields need.
+func (s shape) context(ctx context.Context) context.Context {
+	if s.fields == nil {
+		return ctx
+	}
+	columns := []string{}
+	for _, name := range s.fields {
+		columns = append(columns, userFields[name].columns...)
+	}
+	return repository.WithFields(ctx, columns...)
+}
+
+// render returns users as caller may see them, cut down to the requested
+// fields and with the requested related resources added.
+func (s shape) render(ctx context.Context, service usr.Service, caller Caller, users []user.User) ([]interface{}, error) {
+	out := views(caller, users)
+	if s.fields == nil && s.expand == nil {
+		return out, nil
+	}
+	related := make(map[string]map[int]interface{}, len(s.expand))
+	for _, name := range s.expand {
+		byID, err := expansions[name](ctx, service, users)
+		if err != nil {
+			return nil, err
+		}
+		related[name] = byID
+	}
+	for i, v := range out {
+		shaped, err := s.project(v)
+		if err != nil {
+			return nil, err
+		}
+		for name, byID := range related {
+			shaped[name] = byID[users[i].ID]
+		}
+		out[i] = shaped
+	}
+	return out, nil
+}
+
+// renderOne is render for a single user.
+func (s shape) renderOne(ctx context.Context, service usr.Service, caller Caller, u user.User) (interface{}, error) {
+	out, err := s.render(ctx, service, caller, []user.User{u})
+	if err != nil {
+		return nil, err
+	}
+	return out[0], nil
+}
+
+// project keeps the requested fields of a user view. Fields the view leaves
+// out stay out, so projecting never shows what the view hides.
+func (s shape) project(v interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &all); err != nil {
+		return nil, err
+	}
+	shaped := make(map[string]interface{}, len(all))
+	if s.fields == nil {
+		for key, value := range all {
+			shaped[key] = value
+		}
+		return shaped, nil
+	}
+	for _, name := range s.fields {
+		key := userFields[name].key
+		if value, ok := all[key]; ok {
+			shaped[key] = value
+		}
+	}
+	return shaped, nil
+}
+
+func userIDs(users []user.User) []int {
+	ids := make([]int, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
@@ -1,10 +1,19 @@
+//go:generate mockgen -destination=service_mocks_test.go -package=http github.com/millbj92/nuboverflow-users/internal/user/service Service
 package http
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"net/url"
+	"reflect"
 	"strconv"
+	"strings"
 	"unicode"
 
 	swagger "github.com/arsmn/fiber-swagger/v2"
@@ -16,16 +25,14 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/gofiber/helmet/v2"
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"github.com/millbj92/nuboverflow-users/internal/cache"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
 	_ "github.com/millbj92/nuboverflow-users/internal/transport/http/docs"
 	"github.com/millbj92/nuboverflow-users/internal/user"
 	usr "github.com/millbj92/nuboverflow-users/internal/user/service"
 )
 
-type HttpError struct {
-	Message string
-	Errors  []error
-}
-
 type HealthCheckResponse struct {
 	HTTPService string
 	Database    string
@@ -37,6 +44,80 @@ type CreateUserRequest struct {
 	Email      string `json:"email" validate:"required,email"`
 }
 
+// BatchGetRequest is the body of POST /users/batch-get.
+type BatchGetRequest struct {
+	IDs []int `json:"ids" validate:"required,min=1"`
+}
+
+// RenameUserRequest is the body of POST /users/{id}/username.
+type RenameUserRequest struct {
+	UserName string `json:"username" validate:"required,min=4,max=100"`
+}
+
+// ReputationEventRequest is the body of POST /users/{id}/reputation. The
+// idempotency key can also be sent in the Idempotency-Key header.
+type ReputationEventRequest struct {
+	Source         string `json:"source" validate:"required,max=64"`
+	IdempotencyKey string `json:"idempotencyKey" validate:"required,max=127"`
+	Reason         string `json:"reason" validate:"required,max=64"`
+	Delta          int    `json:"delta" validate:"required"`
+	ReferenceType  string `json:"referenceType" validate:"required_with=ReferenceID,omitempty,oneof=question answer"`
+	ReferenceID    int    `json:"referenceId" validate:"required_with=ReferenceType"`
+}
+
+// BlockCheckRequest is the body of POST /blocks/check.
+type BlockCheckRequest struct {
+	Checks []BlockCheckPair `json:"checks" validate:"required,min=1,dive"`
+}
+
+// BlockCheckPair asks whether the user with UserID is blocked by the one with
+// BlockedBy.
+type BlockCheckPair struct {
+	UserID    int `json:"userId" validate:"required"`
+	BlockedBy int `json:"blockedBy" validate:"required"`
+}
+
+// BlockCheckResponse answers a BlockCheckRequest, in the order of its checks.
+type BlockCheckResponse struct {
+	Checks []user.BlockCheck `json:"checks"`
+}
+
+// RecalculateResponse is the response of POST /admin/reputation/recalculate.
+// Updated is how many users' UserScore was corrected.
+type RecalculateResponse struct {
+	Updated int `json:"updated"`
+}
+
+// idempotencyKeyHeader can carry the idempotency key of a reputation event.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// userByUserNamePath is where GET /users/by-username/{name} is served, for
+// redirecting renamed users.
+const userByUserNamePath = "/api/v1/users/by-username/"
+
+type config struct {
+	timeouts     Timeouts
+	cacheMetrics *cache.Metrics
+}
+
+// Option configures optional behaviour of the routes created by CreateRoutes.
+type Option func(*config)
+
+// WithTimeouts overrides the per-route request timeouts.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(c *config) {
+		c.timeouts = timeouts
+	}
+}
+
+// WithCacheMetrics serves the user cache's hit and miss counts at
+// GET /admin/cache/stats.
+func WithCacheMetrics(metrics *cache.Metrics) Option {
+	return func(c *config) {
+		c.cacheMetrics = metrics
+	}
+}
+
 // @title Nuboverflow - Users Microservice
 // @version 1.0
 // @description Used for creation of users within the Nuboverflow domain.
@@ -47,52 +128,151 @@ type CreateUserRequest struct {
 
 // @license.name MIT
 // @license.url https://github.com/millbj92/nuboverflow-users/blob/main/LICENSE
-func CreateRoutes(service usr.Service, v *validator.Validate) *fiber.App {
+func CreateRoutes(service usr.Service, v *validator.Validate, opts ...Option) *fiber.App {
+	cfg := config{
+		timeouts: DefaultTimeouts(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 
 	registerValidators(v)
+	uni := registerTranslations(v)
 
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		ErrorHandler: ErrorHandler,
+	})
 
 	app.Use(helmet.New())
+	app.Use(Localize(uni))
 
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowMethods: "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowOrigins:  "*",
+		AllowMethods:  "GET,POST,PUT,PATCH,DELETE,HEAD,OPTIONS",
+		AllowHeaders:  "Origin, Content-Type, Accept, Accept-Language, If-Match, If-None-Match, Idempotency-Key",
+		ExposeHeaders: "ETag",
 	}))
 
 	app.Get("/docs/*", swagger.Handler)
 	v1 := app.Group("/api/v1")
-	v1.Get("/users", GetAllUsers(service))
-	v1.Post("/users", CreateUser(service, v))
-	v1.Get("/users", GetUserByEmail(service))
-	v1.Put("/users", UpdateUser(service))
-	v1.Get("users/:id", GetUserByID(service))
-	v1.Delete("/users/:id", DeleteUser(service))
+	v1.Use("/admin", RequireRole(roleAdmin))
+
+	// handle registers a route whose service calls are bounded by its configured timeout.
+	handle := func(method, path string, handlers ...fiber.Handler) {
+		v1.Add(method, path, append([]fiber.Handler{Timeout(cfg.timeouts.For(method, path))}, handlers...)...)
+	}
+	handle(fiber.MethodGet, "/users", ListUsers(service))
+	handle(fiber.MethodPost, "/users", CreateUser(service, v))
+	handle(fiber.MethodPut, "/users", UpdateUser(service))
+	handle(fiber.MethodPost, "/users/batch-get", BatchGetUsers(service, v))
+	handle(fiber.MethodGet, "/users/search", SearchUsers(service))
+	handle(fiber.MethodGet, "/users/autocomplete", AutocompleteUsers(service))
+	handle(fiber.MethodGet, "/users/by-username/:name", GetUserByUserName(service))
+	handle(fiber.MethodGet, "/users/:id", GetUserByID(service))
+	handle(fiber.MethodPut, "/users/:id/privacy", UpdatePrivacy(service))
+	handle(fiber.MethodGet, "/users/:id/avatar", GetAvatar(service))
+	handle(fiber.MethodPut, "/users/:id/avatar", SetAvatar(service))
+	handle(fiber.MethodDelete, "/users/:id/avatar", DeleteAvatar(service))
+	handle(fiber.MethodPost, "/users/:id/github/challenge", StartGithubVerification(service))
+	handle(fiber.MethodPost, "/users/:id/github/verify", VerifyGithub(service))
+	handle(fiber.MethodPut, "/users/:id/profile-fields", SetProfileFields(service))
+	handle(fiber.MethodGet, "/profile-fields", GetProfileFields(service))
+	handle(fiber.MethodPost, "/users/:id/username", RenameUser(service, v))
+	handle(fiber.MethodGet, "/users/:id/username-history", GetUserNameHistory(service))
+	handle(fiber.MethodGet, "/users/:id/reputation", GetReputationEvents(service))
+	handle(fiber.MethodGet, "/users/:id/privileges", GetPrivileges(service))
+	handle(fiber.MethodGet, "/users/:id/badges", GetAwards(service))
+	handle(fiber.MethodGet, "/badges", GetBadges(service))
+	handle(fiber.MethodGet, "/users/:id/followers", GetFollowers(service))
+	handle(fiber.MethodGet, "/users/:id/following", GetFollowing(service))
+	handle(fiber.MethodPut, "/users/:id/following/:targetId", FollowUser(service))
+	handle(fiber.MethodDelete, "/users/:id/following/:targetId", UnfollowUser(service))
+	handle(fiber.MethodGet, "/users/:id/relationship/:targetId", GetRelationship(service))
+	handle(fiber.MethodPut, "/users/:id/blocks/:targetId", BlockUser(service))
+	handle(fiber.MethodDelete, "/users/:id/blocks/:targetId", UnblockUser(service))
+	handle(fiber.MethodGet, "/users/:id/blocks", GetBlockedUsers(service))
+	handle(fiber.MethodGet, "/users/:id/mutes", GetMutedUsers(service))
+	handle(fiber.MethodPut, "/users/:id/mutes/:targetId", MuteUser(service))
+	handle(fiber.MethodDelete, "/users/:id/mutes/:targetId", UnmuteUser(service))
+	handle(fiber.MethodGet, "/users/:id/skills", GetUserSkills(service))
+	handle(fiber.MethodPut, "/users/:id/skills/:skill", AddUserSkill(service))
+	handle(fiber.MethodDelete, "/users/:id/skills/:skill", RemoveUserSkill(service))
+	handle(fiber.MethodPut, "/users/:id/skills/:skill/endorsers/:endorserId", EndorseSkill(service))
+	handle(fiber.MethodDelete, "/users/:id/skills/:skill/endorsers/:endorserId", UnendorseSkill(service))
+	handle(fiber.MethodGet, "/skills", GetSkills(service))
+	handle(fiber.MethodGet, "/skills/:skill/top-users", GetTopUsersForSkill(service))
+	handle(fiber.MethodPost, "/blocks/check", RequireRole(roleService, roleAdmin), CheckBlocks(service, v))
+	handle(fiber.MethodGet, "/users/:id/privileges/:name", CheckPrivilege(service))
+	handle(fiber.MethodPost, "/users/:id/reputation", RequireRole(roleService, roleAdmin), AddReputationEvent(service, v))
+	handle(fiber.MethodDelete, "/users/:id", DeleteUser(service))
+	handle(fiber.MethodPost, "/admin/users/:id/restore", RestoreUser(service))
+	handle(fiber.MethodPost, "/admin/reputation/recalculate", RecalculateUserScores(service))
+	handle(fiber.MethodPut, "/admin/profile-fields/:name", SaveProfileField(service))
+	handle(fiber.MethodDelete, "/admin/profile-fields/:name", DeleteProfileField(service))
+	if cfg.cacheMetrics != nil {
+		v1.Get("/admin/cache/stats", CacheStats(cfg.cacheMetrics))
+	}
 	v1.Get("/ping", Healthcheck())
 	v1.Get("/dashboard", monitor.New())
 
 	return app
 }
 
-// GetAllUsers godoc
-// @Summary List all users
-// @Description Get all user accounts
+// ListUsers godoc
+// @Summary List users
+// @Description Get all user accounts, optionally narrowed by exact-match filters. With ids, look up those users instead, as POST /users/batch-get does.
 // @Tags users
 // @Produce  json
-// @Success 200 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
+// @Param email query string false "Filter by email" Format(email)
+// @Param username query string false "Filter by username"
+// @Param github query string false "Filter by Github username or profile URL"
+// @Param linkedin query string false "Filter by Linkedin profile URL or name"
+// @Param profession query string false "Filter by profession"
+// @Param workplace query string false "Filter by workplace"
+// @Param profile.{name} query string false "Filter by the value of the profile field name, e.g. profile.pronouns=she/her"
+// @Param ids query string false "Comma-separated user IDs to look up"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
+// @Success 200 {array} model.User
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
 // @Router /users [get]
-func GetAllUsers(service usr.Service) fiber.Handler {
+func ListUsers(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		users, err := service.GetAllUsers()
+		shape, err := shapeOf(c)
+		if err != nil {
+			return err
+		}
+		if raw := c.Query("ids"); raw != "" {
+			ids, err := intList(raw)
+			if err != nil {
+				return domainerr.Validation("query parameter ids must be comma-separated numbers")
+			}
+			return batchGetUsers(c, service, shape, ids)
+		}
+
+		filter := user.Filter{
+			Email:      c.Query("email"),
+			UserName:   c.Query("username"),
+			Github:     c.Query("github"),
+			Linkedin:   c.Query("linkedin"),
+			Profession: c.Query("profession"),
+			WorkPlace:  c.Query("workplace"),
+		}
+		filter.ProfileFields = profileFieldFilter(c)
+		users, err := service.FindUsers(callerOf(c).viewing(shape.context(c.UserContext())), filter)
 		if err != nil {
 			log.Printf("UserService failed to GET /users\nError: %s", err)
 			return err
 		}
-		err = c.JSON(&users)
+		caller := callerOf(c)
+		out, err := shape.render(c.UserContext(), service, caller, visibleMatches(caller, filter, users))
+		if err != nil {
+			return err
+		}
+		varyByCaller(c)
+		err = c.JSON(out)
 		if err != nil {
 			log.Printf("Failed to response to GET /users: %s", err)
 			return err
@@ -101,6 +281,136 @@ func GetAllUsers(service usr.Service) fiber.Handler {
 	}
 }
 
+// BatchGetUsers godoc
+// @Summary Look up many users by ID
+// @Description Returns the users in the order their IDs were given, and lists the IDs with no user. At most 100 IDs per call.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param ids body http.BatchGetRequest true "User IDs"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
+// @Success 200 {object} user.Batch
+// @Failure 400 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/batch-get [post]
+func BatchGetUsers(service usr.Service, v *validator.Validate) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := BatchGetRequest{}
+		if err := c.BodyParser(&requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		if err := v.Struct(requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request failed validation")
+		}
+		shape, err := shapeOf(c)
+		if err != nil {
+			return err
+		}
+		return batchGetUsers(c, service, shape, requestBody.IDs)
+	}
+}
+
+func batchGetUsers(c *fiber.Ctx, service usr.Service, shape shape, ids []int) error {
+	batch, err := service.GetUsersByIDs(callerOf(c).viewing(shape.context(c.UserContext())), ids)
+	if err != nil {
+		log.Printf("Error calling GetUsersByIDs: %s", err)
+		return err
+	}
+	users, err := shape.render(c.UserContext(), service, callerOf(c), batch.Users)
+	if err != nil {
+		return err
+	}
+	varyByCaller(c)
+	if err = c.JSON(batchView{Users: users, NotFound: batch.NotFound}); err != nil {
+		log.Printf("Failed to respond to %s %s: %s", c.Method(), c.Path(), err)
+		return err
+	}
+	return nil
+}
+
+// SearchUsers godoc
+// @Summary Search users
+// @Description Full-text search over username, bio, profession and workplace, ordered by relevance
+// @Tags users
+// @Produce  json
+// @Param q query string true "Search text"
+// @Param page query int false "Page number, starting at 1"
+// @Param per_page query int false "Results per page (max 100)"
+// @Success 200 {object} user.SearchPage
+// @Failure 400 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/search [get]
+func SearchUsers(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		q := c.Query("q")
+		if strings.TrimSpace(q) == "" {
+			return domainerr.Validation("query parameter q is required")
+		}
+		page, err := intQuery(c, "page", 1)
+		if err != nil {
+			return domainerr.Validation("query parameter page must be a number")
+		}
+		perPage, err := intQuery(c, "per_page", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter per_page must be a number")
+		}
+
+		caller := callerOf(c)
+		results, err := service.SearchUsers(caller.viewing(c.UserContext()), user.SearchQuery{
+			Query:        q,
+			Page:         page,
+			PerPage:      perPage,
+			SearchHidden: caller.IsAdmin(),
+		})
+		if err != nil {
+			log.Printf("Error calling SearchUsers: %s", err)
+			return err
+		}
+		varyByCaller(c)
+		if err = c.JSON(searchPageFor(caller, results)); err != nil {
+			log.Printf("Failed to respond to GET /users/search: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// AutocompleteUsers godoc
+// @Summary Suggest usernames
+// @Description Usernames starting with a prefix, ignoring case and lookalike characters, highest UserScore first. Used for @mentions.
+// @Tags users
+// @Produce  json
+// @Param prefix query string true "Username prefix"
+// @Param limit query int false "Maximum number of suggestions (max 50)"
+// @Success 200 {array} user.Suggestion
+// @Failure 400 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/autocomplete [get]
+func AutocompleteUsers(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		prefix := c.Query("prefix")
+		if prefix == "" {
+			return domainerr.Validation("query parameter prefix is required")
+		}
+		limit, err := intQuery(c, "limit", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter limit must be a number")
+		}
+
+		suggestions, err := service.SuggestUsers(callerOf(c).viewing(c.UserContext()), prefix, limit)
+		if err != nil {
+			log.Printf("Error calling SuggestUsers: %s", err)
+			return err
+		}
+		varyByCaller(c)
+		if err = c.JSON(suggestions); err != nil {
+			log.Printf("Failed to respond to GET /users/autocomplete: %s", err)
+			return err
+		}
+		return nil
+	}
+}
 
 // GetUserByID godoc
 // @Summary Get a single user by their ID
@@ -109,31 +419,33 @@ func GetAllUsers(service usr.Service) fiber.Handler {
 // @Accept  int
 // @Produce  json
 // @Param id path int true "User ID"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
 // @Success 200 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
 // @Router /users/{id} [get]
 func GetUserByID(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id, err := intFromString(utils.ImmutableString(c.Params("id")))
+		id, err := idParam(c)
 		if err != nil {
 			return err
 		}
-		result, err := service.GetUserByID(id)
+		shape, err := shapeOf(c)
+		if err != nil {
+			return err
+		}
+		result, err := service.GetUserByID(callerOf(c).viewing(shape.context(c.UserContext())), id)
 		if err != nil {
-			if err.Error() == "record not found" {
-				if err := c.Status(fiber.StatusNotFound).JSON(HttpError{
-					Message: "Resource was not found.",
-					Errors:  []error{err},
-				}); err != nil {
-					return err
-				}
-			}
 			log.Printf("UserService failed to GetUserByID: %s", err)
 			return err
 		}
-		err = c.JSON(result)
+		out, err := shape.renderOne(c.UserContext(), service, callerOf(c), result)
+		if err != nil {
+			return err
+		}
+		err = sendUser(c, result, out)
 		if err != nil {
 			log.Printf("Failed to response to GET users/%s\nError: %s", fmt.Sprint(id), err)
 			return err
@@ -143,29 +455,54 @@ func GetUserByID(service usr.Service) fiber.Handler {
 }
 
 
-// GetUserByEmail godoc
-// @Summary Get a user by their email address.
-// @Description get user by ID
+// GetUserByUserName godoc
+// @Summary Get a single user by their username
+// @Description get user by username. A username its owner recently changed redirects to their new one.
 // @Tags users
-// @Accept  string
 // @Produce  json
-// @Param q query string false "user search by q" Format(email)
+// @Param name path string true "Username"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
 // @Success 200 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
-// @Router /users/{email} [get]
-func GetUserByEmail(service usr.Service) fiber.Handler {
+// @Success 302 "Redirect to the user's current username"
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/by-username/{name} [get]
+func GetUserByUserName(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		email := c.Query("email")
-		user, err := service.GetUserByEmail(email)
+		name := utils.ImmutableString(c.Params("name"))
+		shape, err := shapeOf(c)
+		if err != nil {
+			return err
+		}
+		user, err := service.GetUserByUserName(callerOf(c).viewing(shape.context(c.UserContext())), name)
+		if errors.Is(err, domainerr.ErrNotFound) {
+			renamed, renamedErr := service.GetRenamedUser(callerOf(c).viewing(c.UserContext()), name)
+			if renamedErr == nil {
+				location := userByUserNamePath + url.PathEscape(renamed.UserName)
+				if query := c.Context().QueryArgs().String(); query != "" {
+					location += "?" + query
+				}
+				// Not permanent: the old name is up for grabs once its reservation ends.
+				return c.Redirect(location, fiber.StatusFound)
+			}
+			if !errors.Is(renamedErr, domainerr.ErrNotFound) {
+				err = renamedErr
+			}
+		}
+		if err != nil {
+			log.Printf("Error calling GetUserByUserName: %s", err)
+			return err
+		}
+		out, err := shape.renderOne(c.UserContext(), service, callerOf(c), user)
 		if err != nil {
-			log.Printf("Error calling GetUserByEmail: %s", err)
 			return err
 		}
-		err = c.JSON(user)
+		varyByCaller(c)
+		err = c.JSON(out)
 		if err != nil {
-			log.Printf("Failed to respond to POST /users/email\nEmail: %s\nError: %s", email, err)
+			log.Printf("Failed to respond to GET /users/by-username/%s\nError: %s", name, err)
 			return err
 		}
 		return nil
@@ -180,37 +517,27 @@ func GetUserByEmail(service usr.Service) fiber.Handler {
 // @Produce  json
 // @Param account body model.CreateUser true "Create user"
 // @Success 200 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
-// @Router /accounts [post]
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users [post]
 func CreateUser(service usr.Service, v *validator.Validate) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		requestBody := CreateUserRequest{}
 		if err := c.BodyParser(&requestBody); err != nil {
-			return c.Status(503).SendString(err.Error())
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
 		}
 
 		if err := v.Struct(requestBody); err != nil {
 			log.Println(err)
-			return err
-		}
-
-		//Validate if user already exists
-		existingUser, err := service.GetUserByEmail(requestBody.Email)
-		if err != nil {
-			log.Println(err)
-		}
-		if existingUser.ID > 0 {
-			log.Println("Returning error: User exists.")
-		    return errors.New("user exists")
+			return domainerr.Wrap(domainerr.KindValidation, err, "request failed validation")
 		}
 
 		//Hash password.
 		passBytes := []byte(requestBody.Password)
 		hashedPassword, err := bcrypt.GenerateFromPassword(passBytes, bcrypt.DefaultCost)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		requestBody.Password = string(hashedPassword)
 
@@ -220,17 +547,12 @@ func CreateUser(service usr.Service, v *validator.Validate) fiber.Handler {
 			Password: requestBody.Password,
 		}
 		//Send to service to be stored in the Store.
-		user, err := service.CreateUser(&domainUser)
+		user, err := service.CreateUser(c.UserContext(), &domainUser)
 		if err != nil {
-			if err.Error() == "user exists" {
-				return c.Status(fiber.StatusBadRequest).SendString("User Already Exists")
-
-			} else {
-				log.Printf("Error calling CreateUser: %s", err)
-				return err
-			}
+			log.Printf("Error calling CreateUser: %s", err)
+			return err
 		}
-		if err = c.JSON(user); err != nil {
+		if err = c.JSON(user.SelfProfile()); err != nil {
 			log.Printf("Error responding to POST /users: %s", err)
 			return err
 		}
@@ -240,29 +562,50 @@ func CreateUser(service usr.Service, v *validator.Validate) fiber.Handler {
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description update by json user
+// @Description update by json user. Requires the ETag of the version being edited in If-Match. Github and Linkedin can be profile URLs or the names in them, and are stored as the names. Only the user and admins can update them.
 // @Tags users
 // @Accept  json
 // @Produce  json
+// @Param If-Match header string true "ETag from a previous GET /users/{id}"
 // @Param user body model.UpdateUser true "Update user"
 // @Success 200 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
-// @Router /accounts [put]
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 412 {object} http.Problem
+// @Failure 428 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users [put]
 func UpdateUser(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		ifMatch := c.Get(fiber.HeaderIfMatch)
+		if ifMatch == "" {
+			return fiber.NewError(fiber.StatusPreconditionRequired, "If-Match header is required")
+		}
+		version, err := versionFromETag(ifMatch)
+		if err != nil {
+			return domainerr.Validation("If-Match header is not a valid ETag")
+		}
+
 		usr := new(user.User)
 		if err := c.BodyParser(usr); err != nil {
 			log.Printf("Error parsing user: %s", err)
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(usr.ID); err != nil {
 			return err
 		}
-		user, err := service.UpdateUser(*usr)
+		usr.Version = version
+		// Privacy has its own endpoint, which can also turn settings off.
+		usr.Privacy = user.Privacy{}
+		updated, err := service.UpdateUser(c.UserContext(), *usr)
 		if err != nil {
 			log.Printf("Error calling UpdateUser %s", err)
 			return err
 		}
-		if err = c.JSON(user); err != nil {
+		if err = sendUser(c, updated, view(caller, updated)); err != nil {
 			log.Printf("Error responding to PUT /users: %s", err)
 			return err
 		}
@@ -272,27 +615,32 @@ func UpdateUser(service usr.Service) fiber.Handler {
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete by user ID
+// @Description Soft-delete by user ID. The user can be restored by an admin until the restore window passes. Only the user and admins can delete them.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param id path int true "User ID" Format(int64)
-// @Success 204 {object} model.User
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
 // @Router /users/{id} [delete]
 func DeleteUser(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		id, err := intFromString(utils.ImmutableString(c.Params("id")))
+		id, err := idParam(c)
 		if err != nil {
 			return err
 		}
-		if err := service.DeleteUser(id); err != nil {
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if err := service.DeleteUser(c.UserContext(), id); err != nil {
 			log.Printf("Error deleting user: %s", err)
 			return err
 		}
-		if err := c.SendStatus(fiber.StatusOK); err != nil {
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
 			log.Printf("Error responding to DELETE /user/:id\nid: %s\nError: %s", fmt.Sprint(id), err)
 			return err
 		}
@@ -300,36 +648,1591 @@ func DeleteUser(service usr.Service) fiber.Handler {
 	}
 }
 
-// Healthcheck godoc
-// @Summary Healthcheck the Users API
-// @Description Ping this endpoint to get a current healthcheck.
+// UpdatePrivacy godoc
+// @Summary Change a user's privacy settings
+// @Description Replaces which of email, Github, Linkedin and workplace are shown to other users. Settings left out are turned off. Only the user and admins can change them.
 // @Tags users
-// @Produce  string
-// @Success 200 {object} string
-// @Failure 400 {object} httputil.HTTPError
-// @Failure 404 {object} httputil.HTTPError
-// @Failure 500 {object} httputil.HTTPError
-// @Router /users/ping [get]
-func Healthcheck() fiber.Handler {
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param privacy body user.Privacy true "Privacy settings"
+// @Success 200 {object} user.SelfProfile
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/privacy [put]
+func UpdatePrivacy(service usr.Service) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		err := c.SendString("pong")
+		id, err := idParam(c)
 		if err != nil {
-			log.Printf("Error responding to health check: %s", err)
+			return err
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(id); err != nil {
+			return err
+		}
+		privacy := user.Privacy{}
+		if err := c.BodyParser(&privacy); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		updated, err := service.UpdatePrivacy(c.UserContext(), id, privacy)
+		if err != nil {
+			log.Printf("Error calling UpdatePrivacy: %s", err)
+			return err
+		}
+		if err = sendUser(c, updated, view(caller, updated)); err != nil {
+			log.Printf("Error responding to PUT /users/%d/privacy: %s", id, err)
 			return err
 		}
 		return nil
 	}
 }
 
-func intFromString(sid string) (int, error) {
-	uid, err := strconv.ParseInt(sid, 10, 32)
-	if err != nil {
-		return 0, err
+// SetAvatar godoc
+// @Summary Upload a user's avatar
+// @Description Replaces the user's avatar with a PNG, JPEG or GIF of at most 2 MiB, sent as the avatar field of a multipart form. It is cropped to a square, turned upright and stored at several sizes as PNG, JPEG and WebP, without its EXIF data. Only the user and admins can change it.
+// @Tags avatars
+// @Accept  mpfd
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param avatar formData file true "PNG, JPEG or GIF image"
+// @Success 200 {object} user.SelfProfile
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/avatar [put]
+func SetAvatar(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(id); err != nil {
+			return err
+		}
+		header, err := c.FormFile("avatar")
+		if err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "avatar file is required")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+		updated, err := service.SetAvatar(c.UserContext(), id, data)
+		if err != nil {
+			log.Printf("Error calling SetAvatar: %s", err)
+			return err
+		}
+		if err = sendUser(c, updated, view(caller, updated)); err != nil {
+			log.Printf("Error responding to PUT /users/%d/avatar: %s", id, err)
+			return err
+		}
+		return nil
 	}
-	return int(uid), nil
 }
 
-func registerValidators(v *validator.Validate) {
+// DeleteAvatar godoc
+// @Summary Remove a user's avatar
+// @Description The user gets their identicon back. Only the user and admins can remove it.
+// @Tags avatars
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} user.SelfProfile
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/avatar [delete]
+func DeleteAvatar(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(id); err != nil {
+			return err
+		}
+		updated, err := service.DeleteAvatar(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling DeleteAvatar: %s", err)
+			return err
+		}
+		if err = sendUser(c, updated, view(caller, updated)); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/avatar: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetAvatar godoc
+// @Summary Get a user's avatar
+// @Description The avatar at the smallest stored size of at least size pixels, or the largest one. Users without an avatar get an identicon drawn from their email hash. Without format, WebP is sent to clients that accept it and PNG to others.
+// @Tags avatars
+// @Produce  png
+// @Produce  jpeg
+// @Produce  image/webp
+// @Param id path int true "User ID"
+// @Param size query int false "Edge length in pixels"
+// @Param format query string false "png, jpeg or webp"
+// @Success 200 {file} binary
+// @Success 304 "Not modified"
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/avatar [get]
+func GetAvatar(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		size, err := intQuery(c, "size", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter size must be a number")
+		}
+		format := avatar.PNG
+		if raw := c.Query("format"); raw != "" {
+			if format, err = avatar.ParseFormat(raw); err != nil {
+				return err
+			}
+		} else if strings.Contains(c.Get(fiber.HeaderAccept), avatar.WebP.ContentType()) {
+			format = avatar.WebP
+		}
+		c.Vary(fiber.HeaderAccept)
+		varyByCaller(c)
+
+		variant, err := service.GetAvatar(callerOf(c).viewing(c.UserContext()), id, size, format)
+		if err != nil {
+			log.Printf("Error calling GetAvatar: %s", err)
+			return err
+		}
+		sum := sha256.Sum256(variant.Data)
+		tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		c.Set(fiber.HeaderETag, tag)
+		if noneMatch(c.Get(fiber.HeaderIfNoneMatch), tag) {
+			return c.SendStatus(fiber.StatusNotModified)
+		}
+		c.Set(fiber.HeaderContentType, variant.Format.ContentType())
+		if err := c.Send(variant.Data); err != nil {
+			log.Printf("Error responding to GET /users/%d/avatar: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// StartGithubVerification godoc
+// @Summary Start verifying a user's Github account
+// @Description Gives the user a token to publish in a public gist of the Github account their profile links to, then complete the verification with POST /users/{id}/github/verify before it expires. Only the user and admins can verify it.
+// @Tags users
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 201 {object} user.GithubChallenge
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 409 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/github/challenge [post]
+func StartGithubVerification(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		challenge, err := service.StartGithubVerification(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling StartGithubVerification: %s", err)
+			return err
+		}
+		if err := c.Status(fiber.StatusCreated).JSON(challenge); err != nil {
+			log.Printf("Error responding to POST /users/%d/github/challenge: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// VerifyGithub godoc
+// @Summary Complete the verification of a user's Github account
+// @Description Looks for the token from POST /users/{id}/github/challenge in the newest public gists of the user's Github account and marks it verified if it is there. The gist can be deleted afterwards.
+// @Tags users
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} user.SelfProfile
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 409 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/github/verify [post]
+func VerifyGithub(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(id); err != nil {
+			return err
+		}
+		verified, err := service.VerifyGithub(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling VerifyGithub: %s", err)
+			return err
+		}
+		if err = sendUser(c, verified, view(caller, verified)); err != nil {
+			log.Printf("Error responding to POST /users/%d/github/verify: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// SetProfileFields godoc
+// @Summary Set a user's profile fields
+// @Description Replaces the user's values for the fields admins added to profiles, sent as an object of values by field name. Fields left out, or sent empty, are unset, which required fields cannot be. Only the user and admins can set them.
+// @Tags profile fields
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param values body map[string]string true "Values by field name"
+// @Success 200 {object} user.SelfProfile
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/profile-fields [put]
+func SetProfileFields(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		caller := callerOf(c)
+		if err := caller.canManage(id); err != nil {
+			return err
+		}
+		values := map[string]string{}
+		if err := c.BodyParser(&values); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		updated, err := service.SetProfileFields(c.UserContext(), id, values)
+		if err != nil {
+			log.Printf("Error calling SetProfileFields: %s", err)
+			return err
+		}
+		if err = sendUser(c, updated, view(caller, updated)); err != nil {
+			log.Printf("Error responding to PUT /users/%d/profile-fields: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetProfileFields godoc
+// @Summary List profile fields
+// @Description The fields admins added to user profiles, with the rules their values follow, in the order forms should show them.
+// @Tags profile fields
+// @Produce  json
+// @Success 200 {array} user.ProfileField
+// @Failure 500 {object} http.Problem
+// @Router /profile-fields [get]
+func GetProfileFields(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		fields, err := service.GetProfileFields(c.UserContext())
+		if err != nil {
+			log.Printf("Error calling GetProfileFields: %s", err)
+			return err
+		}
+		if err = c.JSON(fields); err != nil {
+			log.Printf("Error responding to GET /profile-fields: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// SaveProfileField godoc
+// @Summary Add or replace a profile field
+// @Description Adds a field to user profiles, or replaces the field with the name. Values users already set are kept as they are. Admin only.
+// @Tags admin
+// @Accept  json
+// @Produce  json
+// @Param name path string true "Field name"
+// @Param field body user.ProfileField true "Field definition"
+// @Success 200 {object} user.ProfileField
+// @Failure 400 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /admin/profile-fields/{name} [put]
+func SaveProfileField(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := utils.ImmutableString(c.Params("name"))
+		field := user.ProfileField{}
+		if err := c.BodyParser(&field); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		field.Name = name
+		saved, err := service.SaveProfileField(c.UserContext(), field)
+		if err != nil {
+			log.Printf("Error calling SaveProfileField: %s", err)
+			return err
+		}
+		if err = c.JSON(saved); err != nil {
+			log.Printf("Error responding to PUT /admin/profile-fields/%s: %s", name, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// DeleteProfileField godoc
+// @Summary Remove a profile field
+// @Description Removes a field from user profiles, along with every value users set for it. Admin only.
+// @Tags admin
+// @Param name path string true "Field name"
+// @Success 204
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /admin/profile-fields/{name} [delete]
+func DeleteProfileField(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name := utils.ImmutableString(c.Params("name"))
+		if err := service.DeleteProfileField(c.UserContext(), name); err != nil {
+			log.Printf("Error calling DeleteProfileField: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /admin/profile-fields/%s: %s", name, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// RenameUser godoc
+// @Summary Change a user's username
+// @Description The old username is kept in the user's history, stays reserved for them for a while and redirects to the new one. Usernames can only be changed once per cooldown period. Only the user and admins can do this.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param rename body http.RenameUserRequest true "New username"
+// @Success 200 {object} model.User
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 409 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/username [post]
+func RenameUser(service usr.Service, v *validator.Validate) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		requestBody := RenameUserRequest{}
+		if err := c.BodyParser(&requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		if err := v.Struct(requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request failed validation")
+		}
+		renamed, err := service.RenameUser(c.UserContext(), id, requestBody.UserName)
+		if err != nil {
+			log.Printf("Error renaming user: %s", err)
+			return err
+		}
+		if err = sendUser(c, renamed, view(callerOf(c), renamed)); err != nil {
+			log.Printf("Error responding to POST /users/%d/username: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetUserNameHistory godoc
+// @Summary List a user's username changes
+// @Description Newest first.
+// @Tags users
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} model.UserNameChange
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/username-history [get]
+func GetUserNameHistory(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		changes, err := service.GetUserNameHistory(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling GetUserNameHistory: %s", err)
+			return err
+		}
+		if err = c.JSON(changes); err != nil {
+			log.Printf("Error responding to GET /users/%d/username-history: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// RestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Undo a soft delete within the configured restore window. Admin only.
+// @Tags admin
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} model.User
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 409 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /admin/users/{id}/restore [post]
+func RestoreUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		restored, err := service.RestoreUser(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error restoring user: %s", err)
+			return err
+		}
+		if err = c.JSON(restored.AdminView()); err != nil {
+			log.Printf("Error responding to POST /admin/users/%d/restore: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// AddReputationEvent godoc
+// @Summary Add a reputation event
+// @Description Records reputation a user gained or lost and updates their UserScore. For other services: requires the service or admin role. Services post under their own name as the source, which they may leave out; only admins choose any source. Posting an event again with the same source and idempotency key returns the first one with a 200 and changes nothing.
+// @Tags reputation
+// @Accept  json
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param event body http.ReputationEventRequest true "Reputation event"
+// @Param Idempotency-Key header string false "Idempotency key, if not in the body"
+// @Success 201 {object} user.ReputationEvent
+// @Success 200 {object} user.ReputationEvent
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 409 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/reputation [post]
+func AddReputationEvent(service usr.Service, v *validator.Validate) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		requestBody := ReputationEventRequest{}
+		if err := c.BodyParser(&requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		if requestBody.IdempotencyKey == "" {
+			requestBody.IdempotencyKey = c.Get(idempotencyKeyHeader)
+		}
+		if caller := callerOf(c); caller.Role == roleService {
+			// So that no service can post under another's source, nor use up
+			// its idempotency keys.
+			if caller.Service == "" {
+				return domainerr.Unauthorized("calling service is not identified")
+			}
+			if requestBody.Source != "" && requestBody.Source != caller.Service {
+				return domainerr.Forbidden("services can only post events under their own name")
+			}
+			requestBody.Source = caller.Service
+		}
+		if err := v.Struct(requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request failed validation")
+		}
+
+		event, added, err := service.AddReputationEvent(c.UserContext(), user.ReputationEvent{
+			UserID:         id,
+			Source:         requestBody.Source,
+			IdempotencyKey: requestBody.IdempotencyKey,
+			Reason:         requestBody.Reason,
+			Delta:          requestBody.Delta,
+			ReferenceType:  requestBody.ReferenceType,
+			ReferenceID:    requestBody.ReferenceID,
+		})
+		if err != nil {
+			log.Printf("Error calling AddReputationEvent: %s", err)
+			return err
+		}
+		if added {
+			c.Status(fiber.StatusCreated)
+		}
+		if err = c.JSON(event); err != nil {
+			log.Printf("Error responding to POST /users/%d/reputation: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetReputationEvents godoc
+// @Summary List a user's reputation events
+// @Description The events a user's UserScore is the sum of, newest first
+// @Tags reputation
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param page query int false "Page number, starting at 1"
+// @Param per_page query int false "Events per page (max 100)"
+// @Success 200 {object} user.ReputationPage
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/reputation [get]
+func GetReputationEvents(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		page, err := intQuery(c, "page", 1)
+		if err != nil {
+			return domainerr.Validation("query parameter page must be a number")
+		}
+		perPage, err := intQuery(c, "per_page", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter per_page must be a number")
+		}
+
+		events, err := service.GetReputationEvents(c.UserContext(), id, page, perPage)
+		if err != nil {
+			log.Printf("Error calling GetReputationEvents: %s", err)
+			return err
+		}
+		if err = c.JSON(events); err != nil {
+			log.Printf("Error responding to GET /users/%d/reputation: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetPrivileges godoc
+// @Summary List a user's privileges
+// @Description The privileges a user's reputation has unlocked, and how much more they need for each of the rest
+// @Tags reputation
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {object} user.Privileges
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/privileges [get]
+func GetPrivileges(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		privileges, err := service.GetPrivileges(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling GetPrivileges: %s", err)
+			return err
+		}
+		if err = c.JSON(privileges); err != nil {
+			log.Printf("Error responding to GET /users/%d/privileges: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// CheckPrivilege godoc
+// @Summary Check a user's privilege
+// @Description Whether a user's reputation has unlocked a privilege, for other services to gate what users do
+// @Tags reputation
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param name path string true "Privilege name, e.g. comment"
+// @Success 200 {object} user.PrivilegeCheck
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/privileges/{name} [get]
+func CheckPrivilege(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		name := utils.ImmutableString(c.Params("name"))
+		check, err := service.CheckPrivilege(c.UserContext(), id, name)
+		if err != nil {
+			log.Printf("Error calling CheckPrivilege: %s", err)
+			return err
+		}
+		if err = c.JSON(check); err != nil {
+			log.Printf("Error responding to GET /users/%d/privileges/%s: %s", id, name, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetBadges godoc
+// @Summary List badges
+// @Description Every badge users can earn
+// @Tags badges
+// @Produce  json
+// @Success 200 {array} user.Badge
+// @Failure 500 {object} http.Problem
+// @Router /badges [get]
+func GetBadges(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		badges, err := service.GetBadges(c.UserContext())
+		if err != nil {
+			log.Printf("Error calling GetBadges: %s", err)
+			return err
+		}
+		if err = c.JSON(badges); err != nil {
+			log.Printf("Error responding to GET /badges: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetAwards godoc
+// @Summary List a user's badges
+// @Description The badges a user was awarded, newest first
+// @Tags badges
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} user.Award
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/badges [get]
+func GetAwards(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		awards, err := service.GetAwards(c.UserContext(), id)
+		if err != nil {
+			log.Printf("Error calling GetAwards: %s", err)
+			return err
+		}
+		if err = c.JSON(awards); err != nil {
+			log.Printf("Error responding to GET /users/%d/badges: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// FollowUser godoc
+// @Summary Follow a user
+// @Description Makes the user follow the target. Following a user again changes nothing. Users who blocked each other cannot follow each other. Only the user and admins can do this.
+// @Tags follows
+// @Param id path int true "ID of the user following"
+// @Param targetId path int true "ID of the user to follow"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/following/{targetId} [put]
+func FollowUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.FollowUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling FollowUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to PUT /users/%d/following/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// UnfollowUser godoc
+// @Summary Unfollow a user
+// @Description Makes the user stop following the target, if they did. Only the user and admins can do this.
+// @Tags follows
+// @Param id path int true "ID of the user following"
+// @Param targetId path int true "ID of the user to unfollow"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/following/{targetId} [delete]
+func UnfollowUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.UnfollowUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling UnfollowUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/following/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetFollowers godoc
+// @Summary List a user's followers
+// @Description The users following a user, most recent follows first. Pass the nextCursor of a page as cursor to get the next one.
+// @Tags follows
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Users per page (max 100)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
+// @Success 200 {object} http.userPageView
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/followers [get]
+func GetFollowers(service usr.Service) fiber.Handler {
+	return listUserPage(service, "followers", service.GetFollowers)
+}
+
+// GetFollowing godoc
+// @Summary List the users a user follows
+// @Description Most recent follows first. Pass the nextCursor of a page as cursor to get the next one.
+// @Tags follows
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Users per page (max 100)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Param expand query string false "Comma-separated related resources to add: badges, skills, stats. Roles are kept by the API gateway and cannot be expanded."
+// @Success 200 {object} http.userPageView
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/following [get]
+func GetFollowing(service usr.Service) fiber.Handler {
+	return listUserPage(service, "following", service.GetFollowing)
+}
+
+// listUserPage serves GET /users/:id/<path> from list.
+func listUserPage(service usr.Service, path string, list func(context.Context, int, string, int) (user.UserPage, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		limit, err := intQuery(c, "limit", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter limit must be a number")
+		}
+		shape, err := shapeOf(c)
+		if err != nil {
+			return err
+		}
+
+		ctx := callerOf(c).viewing(shape.context(c.UserContext()))
+		page, err := list(ctx, id, c.Query("cursor"), limit)
+		if err != nil {
+			log.Printf("Error listing %s: %s", path, err)
+			return err
+		}
+		users, err := shape.render(c.UserContext(), service, callerOf(c), page.Users)
+		if err != nil {
+			log.Printf("Error listing %s: %s", path, err)
+			return err
+		}
+		varyByCaller(c)
+		if err = c.JSON(userPageView{Users: users, NextCursor: page.NextCursor}); err != nil {
+			log.Printf("Error responding to GET /users/%d/%s: %s", id, path, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetRelationship godoc
+// @Summary Get how two users relate
+// @Description Whether the user follows the target, is followed by them, or both
+// @Tags follows
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param targetId path int true "ID of the other user"
+// @Success 200 {object} user.Relationship
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/relationship/{targetId} [get]
+func GetRelationship(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		relationship, err := service.GetRelationship(c.UserContext(), id, targetID)
+		if err != nil {
+			log.Printf("Error calling GetRelationship: %s", err)
+			return err
+		}
+		if err = c.JSON(relationship); err != nil {
+			log.Printf("Error responding to GET /users/%d/relationship/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// BlockUser godoc
+// @Summary Block a user
+// @Description Makes the user block the target, ending any follows between them. Neither can follow the other until the block is lifted, and the target can no longer look the user up. Only the user and admins can do this.
+// @Tags blocks
+// @Param id path int true "ID of the user blocking"
+// @Param targetId path int true "ID of the user to block"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/blocks/{targetId} [put]
+func BlockUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.BlockUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling BlockUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to PUT /users/%d/blocks/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// UnblockUser godoc
+// @Summary Unblock a user
+// @Description Lifts the user's block of the target, if any. Follows it ended stay ended. Only the user and admins can do this.
+// @Tags blocks
+// @Param id path int true "ID of the user blocking"
+// @Param targetId path int true "ID of the user to unblock"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/blocks/{targetId} [delete]
+func UnblockUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.UnblockUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling UnblockUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/blocks/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetBlockedUsers godoc
+// @Summary List the users a user blocked
+// @Description Most recent blocks first. Pass the nextCursor of a page as cursor to get the next one. Only the user and admins can see them.
+// @Tags blocks
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Users per page (max 100)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Success 200 {object} http.userPageView
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/blocks [get]
+func GetBlockedUsers(service usr.Service) fiber.Handler {
+	return manageable(listUserPage(service, "blocks", service.GetBlockedUsers))
+}
+
+// GetMutedUsers godoc
+// @Summary List the users a user muted
+// @Description Most recent mutes first. Pass the nextCursor of a page as cursor to get the next one. Only the user and admins can see them.
+// @Tags blocks
+// @Produce  json
+// @Param id path int true "User ID"
+// @Param cursor query string false "nextCursor of the previous page"
+// @Param limit query int false "Users per page (max 100)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,username,userScore"
+// @Success 200 {object} http.userPageView
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/mutes [get]
+func GetMutedUsers(service usr.Service) fiber.Handler {
+	return manageable(listUserPage(service, "mutes", service.GetMutedUsers))
+}
+
+// manageable only lets the user with :id and admins through to next.
+func manageable(next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		return next(c)
+	}
+}
+
+// MuteUser godoc
+// @Summary Mute a user
+// @Description Makes the user mute the target, whose posts and notifications other services then keep from them. The target isn't told and can still follow them. Only the user and admins can do this.
+// @Tags blocks
+// @Param id path int true "ID of the user muting"
+// @Param targetId path int true "ID of the user to mute"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/mutes/{targetId} [put]
+func MuteUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.MuteUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling MuteUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to PUT /users/%d/mutes/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// UnmuteUser godoc
+// @Summary Unmute a user
+// @Description Lifts the user's mute of the target, if any. Only the user and admins can do this.
+// @Tags blocks
+// @Param id path int true "ID of the user muting"
+// @Param targetId path int true "ID of the user to unmute"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/mutes/{targetId} [delete]
+func UnmuteUser(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, targetID, err := pairParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.UnmuteUser(c.UserContext(), id, targetID); err != nil {
+			log.Printf("Error calling UnmuteUser: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/mutes/%d: %s", id, targetID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetUserSkills godoc
+// @Summary List a user's skills
+// @Description The skills a user lists, with how many users endorsed each, most endorsed first
+// @Tags skills
+// @Produce  json
+// @Param id path int true "User ID"
+// @Success 200 {array} user.UserSkill
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/skills [get]
+func GetUserSkills(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		skills, err := service.GetUserSkills(callerOf(c).viewing(c.UserContext()), id)
+		if err != nil {
+			log.Printf("Error calling GetUserSkills: %s", err)
+			return err
+		}
+		if err = c.JSON(skills); err != nil {
+			log.Printf("Error responding to GET /users/%d/skills: %s", id, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// AddUserSkill godoc
+// @Summary Add a skill to a user
+// @Description Lists the skill among the user's, adding it to the catalog if nobody listed it before. Skills are tags such as go or kubernetes: spaces become hyphens and case is ignored. Adding a skill again changes nothing. Users list at most 50 skills. Only the user and admins can do this.
+// @Tags skills
+// @Param id path int true "User ID"
+// @Param skill path string true "Skill"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/skills/{skill} [put]
+func AddUserSkill(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		skill, err := skillParam(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.AddUserSkill(c.UserContext(), id, skill); err != nil {
+			log.Printf("Error calling AddUserSkill: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to PUT /users/%d/skills/%s: %s", id, skill, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// RemoveUserSkill godoc
+// @Summary Remove a skill from a user
+// @Description Takes the skill off the user's, along with its endorsements. Only the user and admins can do this.
+// @Tags skills
+// @Param id path int true "User ID"
+// @Param skill path string true "Skill"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/skills/{skill} [delete]
+func RemoveUserSkill(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := idParam(c)
+		if err != nil {
+			return err
+		}
+		skill, err := skillParam(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(id); err != nil {
+			return err
+		}
+		if _, err := service.RemoveUserSkill(c.UserContext(), id, skill); err != nil {
+			log.Printf("Error calling RemoveUserSkill: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/skills/%s: %s", id, skill, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// EndorseSkill godoc
+// @Summary Endorse a user's skill
+// @Description Makes the endorser vouch for a skill the user lists, adding one to its endorsements. Endorsing again changes nothing. Users cannot endorse themselves, nor users they blocked or were blocked by. Only the endorser and admins can do this.
+// @Tags skills
+// @Param id path int true "ID of the user listing the skill"
+// @Param skill path string true "Skill"
+// @Param endorserId path int true "ID of the user endorsing it"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/skills/{skill}/endorsers/{endorserId} [put]
+func EndorseSkill(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, skill, endorserID, err := endorsementParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(endorserID); err != nil {
+			return err
+		}
+		if _, err := service.EndorseSkill(c.UserContext(), endorserID, id, skill); err != nil {
+			log.Printf("Error calling EndorseSkill: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to PUT /users/%d/skills/%s/endorsers/%d: %s", id, skill, endorserID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// UnendorseSkill godoc
+// @Summary Withdraw an endorsement
+// @Description Takes the endorser's endorsement of the user's skill back, if any. Only the endorser and admins can do this.
+// @Tags skills
+// @Param id path int true "ID of the user listing the skill"
+// @Param skill path string true "Skill"
+// @Param endorserId path int true "ID of the user who endorsed it"
+// @Success 204
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /users/{id}/skills/{skill}/endorsers/{endorserId} [delete]
+func UnendorseSkill(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, skill, endorserID, err := endorsementParams(c)
+		if err != nil {
+			return err
+		}
+		if err := callerOf(c).canManage(endorserID); err != nil {
+			return err
+		}
+		if _, err := service.UnendorseSkill(c.UserContext(), endorserID, id, skill); err != nil {
+			log.Printf("Error calling UnendorseSkill: %s", err)
+			return err
+		}
+		if err := c.SendStatus(fiber.StatusNoContent); err != nil {
+			log.Printf("Error responding to DELETE /users/%d/skills/%s/endorsers/%d: %s", id, skill, endorserID, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetSkills godoc
+// @Summary List skills
+// @Description Skills in the catalog, with how many users list each, most listed first. Used to suggest skills as users type.
+// @Tags skills
+// @Produce  json
+// @Param prefix query string false "Skill prefix"
+// @Param limit query int false "Maximum number of skills (max 100)"
+// @Success 200 {array} user.SkillSummary
+// @Failure 400 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /skills [get]
+func GetSkills(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit, err := intQuery(c, "limit", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter limit must be a number")
+		}
+		skills, err := service.GetSkills(c.UserContext(), c.Query("prefix"), limit)
+		if err != nil {
+			log.Printf("Error calling GetSkills: %s", err)
+			return err
+		}
+		if err = c.JSON(skills); err != nil {
+			log.Printf("Error responding to GET /skills: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// GetTopUsersForSkill godoc
+// @Summary List the top users of a skill
+// @Description The users listing the skill, most endorsed for it first and then by UserScore
+// @Tags skills
+// @Produce  json
+// @Param skill path string true "Skill"
+// @Param limit query int false "Maximum number of users (max 100)"
+// @Success 200 {array} user.SkilledUser
+// @Failure 400 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /skills/{skill}/top-users [get]
+func GetTopUsersForSkill(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		skill, err := skillParam(c)
+		if err != nil {
+			return err
+		}
+		limit, err := intQuery(c, "limit", 0)
+		if err != nil {
+			return domainerr.Validation("query parameter limit must be a number")
+		}
+		users, err := service.GetTopUsersForSkill(callerOf(c).viewing(c.UserContext()), skill, limit)
+		if err != nil {
+			log.Printf("Error calling GetTopUsersForSkill: %s", err)
+			return err
+		}
+		varyByCaller(c)
+		if err = c.JSON(skilledUsersFor(callerOf(c), users)); err != nil {
+			log.Printf("Error responding to GET /skills/%s/top-users: %s", skill, err)
+			return err
+		}
+		return nil
+	}
+}
+
+// CheckBlocks godoc
+// @Summary Check blocks in bulk
+// @Description Whether each user is blocked by the other user of their check, answered in order with one query. For other services: requires the service or admin role.
+// @Tags blocks
+// @Accept  json
+// @Produce  json
+// @Param checks body http.BlockCheckRequest true "Up to 500 checks"
+// @Success 200 {object} http.BlockCheckResponse
+// @Failure 400 {object} http.Problem
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /blocks/check [post]
+func CheckBlocks(service usr.Service, v *validator.Validate) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestBody := BlockCheckRequest{}
+		if err := c.BodyParser(&requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request body is malformed")
+		}
+		if err := v.Struct(requestBody); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "request failed validation")
+		}
+		checks := make([]user.BlockCheck, len(requestBody.Checks))
+		for i, check := range requestBody.Checks {
+			checks[i] = user.BlockCheck{UserID: check.UserID, BlockedBy: check.BlockedBy}
+		}
+		answered, err := service.CheckBlocks(c.UserContext(), checks)
+		if err != nil {
+			log.Printf("Error calling CheckBlocks: %s", err)
+			return err
+		}
+		if err = c.JSON(BlockCheckResponse{Checks: answered}); err != nil {
+			log.Printf("Error responding to POST /blocks/check: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// RecalculateUserScores godoc
+// @Summary Rebuild reputation from the ledger
+// @Description Sets every UserScore that drifted from the sum of the user's reputation events back to it. Requires the admin role.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} http.RecalculateResponse
+// @Failure 401 {object} http.Problem
+// @Failure 403 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /admin/reputation/recalculate [post]
+func RecalculateUserScores(service usr.Service) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		updated, err := service.RecalculateUserScores(c.UserContext())
+		if err != nil {
+			log.Printf("Error recalculating user scores: %s", err)
+			return err
+		}
+		if err = c.JSON(RecalculateResponse{Updated: updated}); err != nil {
+			log.Printf("Error responding to POST /admin/reputation/recalculate: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// CacheStats godoc
+// @Summary User cache statistics
+// @Description Hit and miss counts of the user lookup cache since the service started. Admin only.
+// @Tags admin
+// @Produce  json
+// @Success 200 {object} cache.Stats
+// @Failure 403 {object} http.Problem
+// @Router /admin/cache/stats [get]
+func CacheStats(metrics *cache.Metrics) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.JSON(metrics.Stats()); err != nil {
+			log.Printf("Error responding to GET /admin/cache/stats: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+// Healthcheck godoc
+// @Summary Healthcheck the Users API
+// @Description Ping this endpoint to get a current healthcheck.
+// @Tags users
+// @Produce  string
+// @Success 200 {object} string
+// @Failure 400 {object} http.Problem
+// @Failure 404 {object} http.Problem
+// @Failure 500 {object} http.Problem
+// @Router /ping [get]
+func Healthcheck() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.SendString("pong")
+		if err != nil {
+			log.Printf("Error responding to health check: %s", err)
+			return err
+		}
+		return nil
+	}
+}
+
+func intFromString(sid string) (int, error) {
+	uid, err := strconv.ParseInt(sid, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int(uid), nil
+}
+
+// intList parses a comma-separated list of integers.
+func intList(raw string) ([]int, error) {
+	parts := strings.Split(raw, ",")
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := intFromString(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// etag returns the entity tag for body, a representation of the user u. It
+// starts with the user's Version, which If-Match is checked against, and
+// ends with a digest of body, so it also changes with what is represented
+// without being versioned, such as counts, the score and expansions.
+func etag(u user.User, body []byte) string {
+	h := fnv.New64a()
+	h.Write(body)
+	return fmt.Sprintf(`"%d-%x"`, u.Version, h.Sum64())
+}
+
+// noneMatch reports whether an If-None-Match header matches the entity tag
+// tag, so that the response is not modified. As RFC 9110 requires for this
+// header, "*" matches any tag, the header may list several, and tags are
+// compared weakly, ignoring any W/ prefix. Parsing stops at the first
+// malformed tag.
+func noneMatch(header, tag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for {
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			return false
+		}
+		header = strings.TrimPrefix(header, "W/")
+		// Opaque tags are quoted, and may have commas in them.
+		if header[0] != '"' {
+			return false
+		}
+		end := strings.IndexByte(header[1:], '"')
+		if end < 0 {
+			return false
+		}
+		if header[:end+2] == tag {
+			return true
+		}
+		header = header[end+2:]
+	}
+}
+
+// versionFromETag parses the user Version out of an ETag produced by etag.
+func versionFromETag(tag string) (int, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, errors.New("malformed etag")
+	}
+	version := tag[1 : len(tag)-1]
+	if i := strings.IndexByte(version, '-'); i >= 0 {
+		version = version[:i]
+	}
+	return intFromString(version)
+}
+
+// idParam reads the numeric :id route parameter.
+func idParam(c *fiber.Ctx) (int, error) {
+	id, err := intFromString(utils.ImmutableString(c.Params("id")))
+	if err != nil {
+		return 0, domainerr.Validation("id must be a number")
+	}
+	return id, nil
+}
+
+// pairParams reads the numeric :id and :targetId route parameters.
+func pairParams(c *fiber.Ctx) (int, int, error) {
+	id, err := idParam(c)
+	if err != nil {
+		return 0, 0, err
+	}
+	targetID, err := intFromString(utils.ImmutableString(c.Params("targetId")))
+	if err != nil {
+		return 0, 0, domainerr.Validation("targetId must be a number")
+	}
+	return id, targetID, nil
+}
+
+// intQuery reads an integer query parameter, falling back to def when it is absent.
+func intQuery(c *fiber.Ctx, key string, def int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, nil
+	}
+	return intFromString(raw)
+}
+
+// skillParam returns the :skill path parameter, which is escaped when it
+// has characters such as # in it.
+func skillParam(c *fiber.Ctx) (string, error) {
+	skill, err := url.PathUnescape(c.Params("skill"))
+	if err != nil {
+		return "", domainerr.Validation("skill is not properly escaped")
+	}
+	return skill, nil
+}
+
+// endorsementParams returns the :id, :skill and :endorserId path parameters.
+func endorsementParams(c *fiber.Ctx) (int, string, int, error) {
+	id, err := idParam(c)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	skill, err := skillParam(c)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	endorserID, err := intFromString(utils.ImmutableString(c.Params("endorserId")))
+	if err != nil {
+		return 0, "", 0, domainerr.Validation("endorserId must be a number")
+	}
+	return id, skill, endorserID, nil
+}
+
+// profileFieldQueryPrefix starts the query parameters that filter users by
+// their profile fields, as in profile.pronouns=she/her.
+const profileFieldQueryPrefix = "profile."
+
+// profileFieldFilter returns the values the profile.{name} query parameters
+// of c ask for, by field name, or nil if there are none.
+func profileFieldFilter(c *fiber.Ctx) map[string]string {
+	var values map[string]string
+	c.Context().QueryArgs().VisitAll(func(key, value []byte) {
+		name := strings.TrimPrefix(string(key), profileFieldQueryPrefix)
+		if len(name) == len(key) || name == "" || len(value) == 0 {
+			return
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[name] = string(value)
+	})
+	return values
+}
+
+func registerValidators(v *validator.Validate) {
+	// Report fields by their JSON name so clients can match errors to inputs.
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
 	var mustHave = []func(rune) bool{
 		unicode.IsUpper,
 		unicode.IsLower,
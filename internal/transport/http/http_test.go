@@ -0,0 +1,1761 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	gomock "github.com/golang/mock/gomock"
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"github.com/millbj92/nuboverflow-users/internal/cache"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+	usr "github.com/millbj92/nuboverflow-users/internal/user/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutes(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+
+	t.Run("GET /users lists every user when no filter is given", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{}).
+			Return([]user.User{{ID: 1}, {ID: 2}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var users []user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+		assert.Len(t, users, 2)
+	})
+
+	t.Run("GET /users?email= filters by email", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{Email: "test@test.com"}).
+			Return([]user.User{{ID: 1, Email: "test@test.com", Privacy: user.Privacy{ShowEmail: true}}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users?email=test@test.com", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var users []user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+		assert.Len(t, users, 1)
+		assert.Equal(t, "test@test.com", users[0].Email)
+	})
+
+	t.Run("GET /users?email= does not reveal hidden emails", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{Email: "test@test.com"}).
+			Return([]user.User{{ID: 1, Email: "test@test.com"}}, nil).
+			Times(4)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		for _, tc := range []struct {
+			role, id string
+			matches  int
+		}{
+			{"", "", 0},
+			{"user", "2", 0},
+			{"user", "1", 1},
+			{"admin", "2", 1},
+		} {
+			req := httptest.NewRequest("GET", "/api/v1/users?email=test@test.com", nil)
+			req.Header.Set("X-User-Role", tc.role)
+			req.Header.Set("X-User-ID", tc.id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+
+			var users []user.User
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+			assert.Len(t, users, tc.matches, "caller %s", tc.id)
+		}
+	})
+
+	t.Run("GET /users/:id shows each caller their view", func(t *testing.T) {
+		stored := user.User{
+			ID:       1,
+			UserName: "bob",
+			Email:    "bob@example.com",
+			Github:   "bobdev",
+			Linkedin: "bob-linkedin",
+			Version:  3,
+			Privacy:  user.Privacy{ShowGithub: true},
+		}
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().GetUserByID(gomock.Any(), 1).Return(stored, nil).Times(3)
+		app := CreateRoutes(serviceMock, validator.New())
+		get := func(role, id string) map[string]interface{} {
+			req := httptest.NewRequest("GET", "/api/v1/users/1", nil)
+			req.Header.Set("X-User-Role", role)
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Contains(t, resp.Header.Get("Vary"), "X-User-ID")
+			var body map[string]interface{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			return body
+		}
+
+		public := get("user", "2")
+		assert.Equal(t, "bobdev", public["Github"])
+		assert.NotContains(t, public, "Email")
+		assert.NotContains(t, public, "Linkedin")
+		assert.NotContains(t, public, "Privacy")
+
+		self := get("user", "1")
+		assert.Equal(t, "bob@example.com", self["Email"])
+		assert.Equal(t, "bob-linkedin", self["Linkedin"])
+		assert.Contains(t, self, "Privacy")
+		assert.NotContains(t, self, "DeletedAt")
+
+		admin := get("admin", "")
+		assert.Equal(t, "bob@example.com", admin["Email"])
+		assert.Contains(t, admin, "Privacy")
+	})
+
+	t.Run("PUT /users/:id/privacy", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			UpdatePrivacy(gomock.Any(), 1, user.Privacy{ShowEmail: true, ShowGithub: true}).
+			Return(user.User{ID: 1, Version: 4, Privacy: user.Privacy{ShowEmail: true, ShowGithub: true}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		put := func(role, id string) (int, string) {
+			req := httptest.NewRequest("PUT", "/api/v1/users/1/privacy",
+				strings.NewReader(`{"showEmail":true,"showGithub":true}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Role", role)
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode, resp.Header.Get("ETag")
+		}
+
+		status, _ := put("", "")
+		assert.Equal(t, 401, status)
+		status, _ = put("user", "2")
+		assert.Equal(t, 403, status)
+		status, tag := put("user", "1")
+		assert.Equal(t, 200, status)
+		assert.Regexp(t, `^"4-[0-9a-f]+"$`, tag)
+	})
+
+	t.Run("PUT /users/:id/avatar", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SetAvatar(gomock.Any(), 1, []byte("picture")).
+			Return(user.User{ID: 1, Version: 5, AvatarHash: "abc"}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		put := func(id, field string) int {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile(field, "me.png")
+			assert.NoError(t, err)
+			_, err = part.Write([]byte("picture"))
+			assert.NoError(t, err)
+			assert.NoError(t, form.Close())
+
+			req := httptest.NewRequest("PUT", "/api/v1/users/1/avatar", &body)
+			req.Header.Set("Content-Type", form.FormDataContentType())
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 403, put("2", "avatar"))
+		assert.Equal(t, 400, put("1", "picture"))
+		assert.Equal(t, 200, put("1", "avatar"))
+	})
+
+	t.Run("GET /users/:id/avatar picks the format and answers revalidations", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetAvatar(gomock.Any(), 1, 64, avatar.WebP).
+			Return(avatar.Variant{Size: 64, Format: avatar.WebP, Data: []byte("webp")}, nil).
+			Times(2)
+		serviceMock.
+			EXPECT().
+			GetAvatar(gomock.Any(), 1, 0, avatar.JPEG).
+			Return(avatar.Variant{Size: 256, Format: avatar.JPEG, Data: []byte("jpeg")}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("GET", "/api/v1/users/1/avatar?size=64", nil)
+		req.Header.Set("Accept", "image/webp,image/*")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Equal(t, "image/webp", resp.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(resp.Body)
+		assert.Equal(t, "webp", string(body))
+
+		req = httptest.NewRequest("GET", "/api/v1/users/1/avatar?size=64", nil)
+		req.Header.Set("Accept", "image/webp,image/*")
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 304, resp.StatusCode)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/users/1/avatar?format=jpg", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "image/jpeg", resp.Header.Get("Content-Type"))
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/users/1/avatar?format=svg", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("POST /users/:id/github/challenge and /verify", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			StartGithubVerification(gomock.Any(), 1).
+			Return(user.GithubChallenge{UserID: 1, Handle: "octocat", Token: "nuboverflow-verify-abc"}, nil)
+		serviceMock.
+			EXPECT().
+			VerifyGithub(gomock.Any(), 1).
+			Return(user.User{ID: 1, Github: "octocat", VerifiedGithub: "octocat", Privacy: user.DefaultPrivacy}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		post := func(path, id string) *http.Response {
+			req := httptest.NewRequest("POST", path, nil)
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		assert.Equal(t, 403, post("/api/v1/users/1/github/challenge", "2").StatusCode)
+		resp := post("/api/v1/users/1/github/challenge", "1")
+		assert.Equal(t, 201, resp.StatusCode)
+		var challenge user.GithubChallenge
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&challenge))
+		assert.Equal(t, "nuboverflow-verify-abc", challenge.Token)
+
+		resp = post("/api/v1/users/1/github/verify", "1")
+		assert.Equal(t, 200, resp.StatusCode)
+		var profile user.SelfProfile
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&profile))
+		assert.True(t, profile.GithubVerified)
+	})
+
+	t.Run("GET /users?profile.name= filters by profile fields without revealing private ones", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{ProfileFields: map[string]string{"pronouns": "she/her", "years": "4"}}).
+			Return([]user.User{
+				{ID: 1, ProfileFields: []user.ProfileFieldValue{
+					{Field: "pronouns", Value: "she/her", Visibility: user.ProfileFieldPublic},
+					{Field: "years", Value: "4", Visibility: user.ProfileFieldPrivate},
+				}},
+			}, nil).
+			Times(2)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{ProfileFields: map[string]string{"pronouns": "she/her"}}).
+			Return([]user.User{
+				{ID: 1, ProfileFields: []user.ProfileFieldValue{
+					{Field: "pronouns", Value: "she/her", Visibility: user.ProfileFieldPublic},
+					{Field: "years", Value: "4", Visibility: user.ProfileFieldPrivate},
+				}},
+			}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		list := func(query, id string) []map[string]interface{} {
+			req := httptest.NewRequest("GET", "/api/v1/users?"+query, nil)
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			var users []map[string]interface{}
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&users))
+			return users
+		}
+
+		assert.Len(t, list("profile.pronouns=she/her&profile.years=4", "2"), 0)
+		own := list("profile.pronouns=she/her&profile.years=4", "1")
+		if assert.Len(t, own, 1) {
+			assert.Equal(t, map[string]interface{}{"pronouns": "she/her", "years": "4"}, own[0]["ProfileFields"])
+		}
+		others := list("profile.pronouns=she/her", "2")
+		if assert.Len(t, others, 1) {
+			assert.Equal(t, map[string]interface{}{"pronouns": "she/her"}, others[0]["ProfileFields"])
+		}
+	})
+
+	t.Run("PUT /users/:id/profile-fields", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SetProfileFields(gomock.Any(), 1, map[string]string{"pronouns": "they/them"}).
+			Return(user.User{ID: 1, Version: 3, ProfileFields: []user.ProfileFieldValue{
+				{UserID: 1, Field: "pronouns", Value: "they/them", Visibility: user.ProfileFieldPublic},
+			}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		put := func(id string) *http.Response {
+			req := httptest.NewRequest("PUT", "/api/v1/users/1/profile-fields", strings.NewReader(`{"pronouns": "they/them"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		assert.Equal(t, 403, put("2").StatusCode)
+		resp := put("1")
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Regexp(t, `^"3-[0-9a-f]+"$`, resp.Header.Get("ETag"))
+		var profile user.SelfProfile
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&profile))
+		assert.Equal(t, map[string]string{"pronouns": "they/them"}, profile.ProfileFields)
+	})
+
+	t.Run("PUT and DELETE /admin/profile-fields/:name", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SaveProfileField(gomock.Any(), user.ProfileField{Name: "pronouns", Label: "Pronouns", Type: user.ProfileFieldText}).
+			Return(user.ProfileField{Name: "pronouns", Label: "Pronouns", Type: user.ProfileFieldText, Visibility: user.ProfileFieldPublic}, nil)
+		serviceMock.
+			EXPECT().
+			DeleteProfileField(gomock.Any(), "pronouns").
+			Return(nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		send := func(method, role string) *http.Response {
+			req := httptest.NewRequest(method, "/api/v1/admin/profile-fields/pronouns", strings.NewReader(`{"name": "ignored", "label": "Pronouns", "type": "text"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Role", role)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		assert.Equal(t, 403, send("PUT", "user").StatusCode)
+		resp := send("PUT", "admin")
+		assert.Equal(t, 200, resp.StatusCode)
+		var field user.ProfileField
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&field))
+		assert.Equal(t, user.ProfileFieldPublic, field.Visibility)
+		assert.Equal(t, 204, send("DELETE", "admin").StatusCode)
+	})
+
+	t.Run("PUT and DELETE /users/:id/skills/:skill", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().AddUserSkill(gomock.Any(), 1, "c#").Return(true, nil)
+		serviceMock.EXPECT().RemoveUserSkill(gomock.Any(), 1, "c#").Return(true, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		send := func(method, id string) int {
+			req := httptest.NewRequest(method, "/api/v1/users/1/skills/c%23", nil)
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 403, send("PUT", "2"))
+		assert.Equal(t, 204, send("PUT", "1"))
+		assert.Equal(t, 403, send("DELETE", "2"))
+		assert.Equal(t, 204, send("DELETE", "1"))
+	})
+
+	t.Run("PUT and DELETE /users/:id/skills/:skill/endorsers/:endorserId", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().EndorseSkill(gomock.Any(), 2, 1, "go").Return(true, nil)
+		serviceMock.EXPECT().UnendorseSkill(gomock.Any(), 2, 1, "go").Return(true, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		send := func(method, id string) int {
+			req := httptest.NewRequest(method, "/api/v1/users/1/skills/go/endorsers/2", nil)
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 403, send("PUT", "1"))
+		assert.Equal(t, 204, send("PUT", "2"))
+		assert.Equal(t, 403, send("DELETE", "1"))
+		assert.Equal(t, 204, send("DELETE", "2"))
+	})
+
+	t.Run("GET /skills/:skill/top-users", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetTopUsersForSkill(gomock.Any(), "go", 5).
+			Return([]user.SkilledUser{
+				{User: user.User{ID: 1, Email: "one@example.com"}, Endorsements: 7},
+				{User: user.User{ID: 2, Email: "two@example.com"}, Endorsements: 3},
+			}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		list := func(query string) *http.Response {
+			req := httptest.NewRequest("GET", "/api/v1/skills/go/top-users"+query, nil)
+			req.Header.Set("X-User-Role", "user")
+			req.Header.Set("X-User-ID", "1")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp
+		}
+
+		assert.Equal(t, 400, list("?limit=many").StatusCode)
+		resp := list("?limit=5")
+		assert.Equal(t, 200, resp.StatusCode)
+		var ranked []struct {
+			User         map[string]interface{} `json:"user"`
+			Endorsements int                    `json:"endorsements"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&ranked))
+		if assert.Len(t, ranked, 2) {
+			assert.Equal(t, 7, ranked[0].Endorsements)
+			assert.Equal(t, "one@example.com", ranked[0].User["Email"])
+			assert.NotContains(t, ranked[1].User, "Email")
+		}
+	})
+
+	t.Run("GET /users combines username and profile filters", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			FindUsers(gomock.Any(), user.Filter{
+				UserName:   "bob",
+				Github:     "bobdev",
+				Linkedin:   "bob-linkedin",
+				Profession: "Engineer",
+				WorkPlace:  "Nuboverflow",
+			}).
+			Return([]user.User{}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET",
+			"/api/v1/users?username=bob&github=bobdev&linkedin=bob-linkedin&profession=Engineer&workplace=Nuboverflow", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("GET /users/search", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SearchUsers(gomock.Any(), user.SearchQuery{Query: "golang", Page: 2, PerPage: 10}).
+			Return(user.SearchPage{
+				Results: []user.SearchResult{{User: user.User{ID: 9}, Score: 2}},
+				Total:   11,
+				Page:    2,
+				PerPage: 10,
+			}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/search?q=golang&page=2&per_page=10", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var page user.SearchPage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Equal(t, int64(11), page.Total)
+		assert.Equal(t, 9, page.Results[0].User.ID)
+	})
+
+	t.Run("GET /users/search does not give hidden workplaces away", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SearchUsers(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, query user.SearchQuery) (user.SearchPage, error) {
+				// The store only matches hidden workplaces for admins and their
+				// owners, but highlights every field that has the terms.
+				results := []user.SearchResult{
+					{User: user.User{ID: 2, WorkPlace: "Acme"}, Highlights: map[string]string{"workplace": "<em>Acme</em>", "bio": "<em>Acme</em> fan"}},
+					{User: user.User{ID: 3, WorkPlace: "Acme", Privacy: user.Privacy{ShowWorkPlace: true}}, Highlights: map[string]string{"workplace": "<em>Acme</em>"}},
+				}
+				if query.SearchHidden {
+					results = append(results, user.SearchResult{User: user.User{ID: 1, WorkPlace: "Acme"}, Highlights: map[string]string{"workplace": "<em>Acme</em>"}})
+				}
+				return user.SearchPage{Results: results, Total: int64(len(results))}, nil
+			}).
+			Times(2)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		search := func(role string) searchPageView {
+			req := httptest.NewRequest("GET", "/api/v1/users/search?q=acme", nil)
+			req.Header.Set("X-User-Role", role)
+			req.Header.Set("X-User-ID", "4")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			var page searchPageView
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+			return page
+		}
+
+		others := search("user")
+		assert.Equal(t, int64(2), others.Total)
+		if assert.Len(t, others.Results, 2) {
+			assert.Equal(t, map[string]string{"bio": "<em>Acme</em> fan"}, others.Results[0].Highlights)
+			assert.Len(t, others.Results[1].Highlights, 1)
+		}
+		admin := search("admin")
+		assert.Equal(t, int64(3), admin.Total)
+		if assert.Len(t, admin.Results, 3) {
+			assert.Len(t, admin.Results[0].Highlights, 2)
+		}
+	})
+
+	t.Run("GET /users/search requires q", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/search", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("GET /users/autocomplete", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			SuggestUsers(gomock.Any(), "go", 5).
+			Return([]user.Suggestion{{ID: 1, UserName: "gopher", UserScore: 42}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/autocomplete?prefix=go&limit=5", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var suggestions []user.Suggestion
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&suggestions))
+		assert.Equal(t, "gopher", suggestions[0].UserName)
+	})
+
+	t.Run("GET /users/autocomplete requires prefix", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/autocomplete", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("GET /users/by-username/:name", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByUserName(gomock.Any(), "bob").
+			Return(user.User{ID: 3, UserName: "bob"}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/by-username/bob", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var usr user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&usr))
+		assert.Equal(t, 3, usr.ID)
+	})
+
+	t.Run("GET /users/by-username/:name redirects a renamed user", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByUserName(gomock.Any(), "bobby").
+			Return(user.User{}, domainerr.NotFound("user not found"))
+		serviceMock.
+			EXPECT().
+			GetRenamedUser(gomock.Any(), "bobby").
+			Return(user.User{ID: 3, UserName: "bob smith"}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/by-username/bobby", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 302, resp.StatusCode)
+		assert.Equal(t, "/api/v1/users/by-username/bob%20smith", resp.Header.Get("Location"))
+
+		serviceMock.
+			EXPECT().
+			GetUserByUserName(gomock.Any(), "bobby").
+			Return(user.User{}, domainerr.NotFound("user not found"))
+		serviceMock.
+			EXPECT().
+			GetRenamedUser(gomock.Any(), "bobby").
+			DoAndReturn(func(ctx context.Context, name string) (user.User, error) {
+				// The new username is needed whatever fields were asked for.
+				assert.Nil(t, repository.FieldsFrom(ctx))
+				return user.User{ID: 3, UserName: "bob smith"}, nil
+			})
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/users/by-username/bobby?fields=id", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, "/api/v1/users/by-username/bob%20smith?fields=id", resp.Header.Get("Location"))
+	})
+
+	t.Run("GET /users/by-username/:name of an unknown name", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByUserName(gomock.Any(), "nobody").
+			Return(user.User{}, domainerr.NotFound("user not found"))
+		serviceMock.
+			EXPECT().
+			GetRenamedUser(gomock.Any(), "nobody").
+			Return(user.User{}, domainerr.NotFound("username change not found"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/by-username/nobody", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("POST /users/:id/username", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			RenameUser(gomock.Any(), 3, "robert").
+			Return(user.User{ID: 3, UserName: "robert", Version: 5}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users/3/username", strings.NewReader(`{"username":"robert"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "3")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Regexp(t, `^"5-[0-9a-f]+"$`, resp.Header.Get("ETag"))
+	})
+
+	t.Run("POST /users/:id/username by anyone but the user or an admin", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		rename := func(id string) int {
+			req := httptest.NewRequest("POST", "/api/v1/users/3/username", strings.NewReader(`{"username":"robert"}`))
+			req.Header.Set("Content-Type", "application/json")
+			if id != "" {
+				req.Header.Set("X-User-Role", "user")
+				req.Header.Set("X-User-ID", id)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 401, rename(""))
+		assert.Equal(t, 403, rename("4"))
+	})
+
+	t.Run("POST /users/:id/username validates the new name", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users/3/username", strings.NewReader(`{"username":"rob"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-ID", "3")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("GET /users/:id/username-history", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserNameHistory(gomock.Any(), 3).
+			Return([]user.UserNameChange{{UserID: 3, OldUserName: "bobby", NewUserName: "bob"}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/3/username-history", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var changes []user.UserNameChange
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&changes))
+		assert.Equal(t, "bobby", changes[0].OldUserName)
+	})
+
+	t.Run("GET /admin/cache/stats", func(t *testing.T) {
+		metrics := &cache.Metrics{}
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New(), WithCacheMetrics(metrics))
+
+		req := httptest.NewRequest("GET", "/api/v1/admin/cache/stats", nil)
+		req.Header.Set("X-User-Role", "admin")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var stats cache.Stats
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+		assert.Equal(t, cache.Stats{}, stats)
+	})
+
+	t.Run("POST /users/batch-get", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUsersByIDs(gomock.Any(), []int{3, 1, 2}).
+			Return(user.Batch{Users: []user.User{{ID: 3}, {ID: 1}}, NotFound: []int{2}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users/batch-get", strings.NewReader(`{"ids":[3,1,2]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var batch user.Batch
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+		assert.Equal(t, 3, batch.Users[0].ID)
+		assert.Equal(t, 1, batch.Users[1].ID)
+		assert.Equal(t, []int{2}, batch.NotFound)
+	})
+
+	t.Run("POST /users/batch-get requires ids", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users/batch-get", strings.NewReader(`{"ids":[]}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("GET /users?ids=", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUsersByIDs(gomock.Any(), []int{5, 4}).
+			Return(user.Batch{Users: []user.User{{ID: 5}, {ID: 4}}, NotFound: []int{}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users?ids=5,4", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/users?ids=5,x", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("GET /users?fields= loads and returns only those fields", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUsersByIDs(gomock.Any(), []int{5}).
+			DoAndReturn(func(ctx context.Context, ids []int) (user.Batch, error) {
+				assert.Equal(t, []string{"user_name", "user_score", "email"}, repository.FieldsFrom(ctx))
+				return user.Batch{Users: []user.User{{ID: 5, UserName: "bob", UserScore: 12, Email: "bob@test.com"}}}, nil
+			})
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users?ids=5&fields=id,username,userScore,email,username", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var batch struct {
+			Users []map[string]interface{} `json:"users"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+		// The email is hidden, so asking for it doesn't show it.
+		assert.Equal(t, map[string]interface{}{"ID": 5.0, "UserName": "bob", "UserScore": 12.0}, batch.Users[0])
+	})
+
+	t.Run("GET /users/:id rejects unknown fields and expansions", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		for _, query := range []string{"fields=id,password", "fields=,", "expand=friends"} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7?"+query, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, 400, resp.StatusCode, query)
+		}
+	})
+
+	t.Run("GET /users/:id?expand=stats", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			DoAndReturn(func(ctx context.Context, id int) (user.User, error) {
+				assert.Nil(t, repository.FieldsFrom(ctx))
+				return user.User{ID: 7, UserName: "bob"}, nil
+			})
+		serviceMock.
+			EXPECT().
+			GetUserStats(gomock.Any(), []int{7}).
+			Return(map[int]user.Stats{7: {UserNameChanges: 2}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7?expand=stats", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var body struct {
+			user.PublicProfile
+			Stats user.Stats `json:"stats"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "bob", body.UserName)
+		assert.Equal(t, int64(2), body.Stats.UserNameChanges)
+	})
+
+	t.Run("GET /users/:id?expand=roles is not available", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7?expand=stats,roles", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+
+		var body Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Contains(t, body.Detail, "roles are assigned by the API gateway")
+	})
+
+	t.Run("GET /users/:id tags each projection and expansion apart", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7, UserName: "bob", Version: 3}, nil).
+			AnyTimes()
+		stats := serviceMock.
+			EXPECT().
+			GetUserStats(gomock.Any(), []int{7}).
+			Return(map[int]user.Stats{7: {UserNameChanges: 1}}, nil)
+		serviceMock.
+			EXPECT().
+			GetUserStats(gomock.Any(), []int{7}).
+			Return(map[int]user.Stats{7: {UserNameChanges: 2}}, nil).
+			After(stats)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		get := func(query, ifNoneMatch string) (int, string) {
+			req := httptest.NewRequest("GET", "/api/v1/users/7"+query, nil)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode, resp.Header.Get("ETag")
+		}
+
+		_, full := get("", "")
+		_, projected := get("?fields=id,username", "")
+		assert.NotEqual(t, full, projected)
+		status, _ := get("?fields=id,username", full)
+		assert.Equal(t, 200, status)
+
+		// The stats change without the user's version changing.
+		_, expanded := get("?expand=stats", "")
+		status, _ = get("?expand=stats", expanded)
+		assert.Equal(t, 200, status)
+	})
+
+	t.Run("GET /users/:id", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Regexp(t, `^"0-[0-9a-f]+"$`, resp.Header.Get("ETag"))
+
+		var usr user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&usr))
+		assert.Equal(t, 7, usr.ID)
+	})
+
+	t.Run("POST /users", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			CreateUser(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, u *user.User) (*user.User, error) {
+				u.ID = 1
+				return u, nil
+			})
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users",
+			strings.NewReader(`{"username":"tester","password":"Secr3t!pass","email":"test@test.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var usr user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&usr))
+		assert.Equal(t, 1, usr.ID)
+		assert.Equal(t, "tester", usr.UserName)
+	})
+
+	t.Run("GET /users/:id with a matching If-None-Match", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7, Version: 3}, nil).
+			Times(2)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7", nil))
+		assert.NoError(t, err)
+		req := httptest.NewRequest("GET", "/api/v1/users/7", nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 304, resp.StatusCode)
+	})
+
+	t.Run("GET /users/:id revalidates counts that change without a new version", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7, Version: 3, FollowerCount: 1}, nil)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7, Version: 3, FollowerCount: 2}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7", nil))
+		assert.NoError(t, err)
+		req := httptest.NewRequest("GET", "/api/v1/users/7", nil)
+		req.Header.Set("If-None-Match", resp.Header.Get("ETag"))
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var usr user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&usr))
+		assert.Equal(t, 2, usr.FollowerCount)
+	})
+
+	t.Run("GET /users/:id parses If-None-Match", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			Return(user.User{ID: 7, Version: 3}, nil).
+			AnyTimes()
+
+		app := CreateRoutes(serviceMock, validator.New())
+		status := func(ifNoneMatch string) (int, string) {
+			req := httptest.NewRequest("GET", "/api/v1/users/7", nil)
+			req.Header.Set("If-None-Match", ifNoneMatch)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode, resp.Header.Get("ETag")
+		}
+		_, tag := status("")
+		opaque := strings.Trim(tag, `"`)
+
+		for header, want := range map[string]int{
+			`W/` + tag:               304,
+			`"1", "2" ,` + tag:       304,
+			`"a,b", W/` + tag:        304,
+			`*`:                      304,
+			`"3", "` + opaque + `3"`: 200,
+			`"` + opaque:             200,
+			opaque:                   200,
+			`"1", garbage ` + tag:    200,
+		} {
+			got, _ := status(header)
+			assert.Equal(t, want, got, header)
+		}
+	})
+
+	t.Run("PUT /users", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			UpdateUser(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, u user.User) (user.User, error) {
+				assert.Equal(t, 2, u.Version)
+				u.Version++
+				return u, nil
+			})
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users", strings.NewReader(`{"ID":4,"Bio":"Gopher"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"2"`)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		assert.Regexp(t, `^"3-[0-9a-f]+"$`, resp.Header.Get("ETag"))
+
+		var usr user.User
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&usr))
+		assert.Equal(t, "Gopher", usr.Bio)
+	})
+
+	t.Run("PUT /users requires If-Match", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users", strings.NewReader(`{"ID":4,"Bio":"Gopher"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 428, resp.StatusCode)
+	})
+
+	t.Run("PUT /users with a stale If-Match", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			UpdateUser(gomock.Any(), gomock.Any()).
+			Return(user.User{}, user.ErrVersionMismatch)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users", strings.NewReader(`{"ID":4,"Bio":"Gopher"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"1"`)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 412, resp.StatusCode)
+	})
+
+	t.Run("PUT /users by anyone but the user or an admin", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		update := func(id string) int {
+			req := httptest.NewRequest("PUT", "/api/v1/users", strings.NewReader(`{"ID":4,"Bio":"Gopher"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", `"2"`)
+			if id != "" {
+				req.Header.Set("X-User-Role", "user")
+				req.Header.Set("X-User-ID", id)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 401, update(""))
+		assert.Equal(t, 403, update("5"))
+	})
+
+	t.Run("DELETE /users/:id", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			DeleteUser(gomock.Any(), 5).
+			Return(nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("DELETE", "/api/v1/users/5", nil)
+		req.Header.Set("X-User-ID", "5")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+	})
+
+	t.Run("DELETE /users/:id by anyone but the user or an admin", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		remove := func(id string) int {
+			req := httptest.NewRequest("DELETE", "/api/v1/users/5", nil)
+			if id != "" {
+				req.Header.Set("X-User-Role", "user")
+				req.Header.Set("X-User-ID", id)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 401, remove(""))
+		assert.Equal(t, 403, remove("4"))
+	})
+
+	t.Run("GET /ping", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/ping", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, "pong", string(body))
+	})
+	t.Run("POST /admin/users/:id/restore requires a caller", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("POST", "/api/v1/admin/users/3/restore", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+
+	t.Run("POST /admin/users/:id/restore requires the admin role", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/admin/users/3/restore", nil)
+		req.Header.Set("X-User-Role", "member")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("POST /admin/users/:id/restore", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			RestoreUser(gomock.Any(), 3).
+			Return(user.User{ID: 3}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/admin/users/3/restore", nil)
+		req.Header.Set("X-User-Role", "admin")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+	})
+
+	t.Run("POST /admin/users/:id/restore after the window", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			RestoreUser(gomock.Any(), 3).
+			Return(user.User{}, usr.ErrRestoreWindowPassed)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/admin/users/3/restore", nil)
+		req.Header.Set("X-User-Role", "admin")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 409, resp.StatusCode)
+	})
+
+	t.Run("POST /users/:id/reputation", func(t *testing.T) {
+		event := user.ReputationEvent{
+			UserID:         4,
+			Source:         "questions",
+			IdempotencyKey: "vote-81",
+			Reason:         "answer_upvoted",
+			Delta:          10,
+			ReferenceType:  "answer",
+			ReferenceID:    81,
+		}
+		serviceMock := NewMockService(mockCtrl)
+		gomock.InOrder(
+			serviceMock.EXPECT().AddReputationEvent(gomock.Any(), event).Return(event, true, nil),
+			serviceMock.EXPECT().AddReputationEvent(gomock.Any(), event).Return(event, false, nil),
+		)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		post := func(role string) int {
+			req := httptest.NewRequest("POST", "/api/v1/users/4/reputation", strings.NewReader(
+				`{"source":"questions","reason":"answer_upvoted","delta":10,"referenceType":"answer","referenceId":81}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", "vote-81")
+			req.Header.Set("X-User-Role", role)
+			req.Header.Set("X-Service-Name", "questions")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 401, post(""))
+		assert.Equal(t, 403, post("user"))
+		assert.Equal(t, 201, post("service"))
+		assert.Equal(t, 200, post("service"))
+	})
+
+	t.Run("POST /users/:id/reputation takes the source from the calling service", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			AddReputationEvent(gomock.Any(), user.ReputationEvent{UserID: 4, Source: "answers", IdempotencyKey: "k", Reason: "accepted", Delta: 15}).
+			Return(user.ReputationEvent{}, true, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		post := func(service, body string) int {
+			req := httptest.NewRequest("POST", "/api/v1/users/4/reputation", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Role", "service")
+			if service != "" {
+				req.Header.Set("X-Service-Name", service)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		assert.Equal(t, 403, post("answers", `{"source":"questions","idempotencyKey":"k","reason":"accepted","delta":15}`))
+		assert.Equal(t, 401, post("", `{"idempotencyKey":"k","reason":"accepted","delta":15}`))
+		assert.Equal(t, 201, post("answers", `{"idempotencyKey":"k","reason":"accepted","delta":15}`))
+	})
+
+	t.Run("POST /users/:id/reputation validates the event", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		for _, body := range []string{
+			`{"source":"questions","idempotencyKey":"k","reason":"answer_upvoted"}`,
+			`{"source":"questions","reason":"answer_upvoted","delta":10}`,
+			`{"source":"questions","idempotencyKey":"k","reason":"answer_upvoted","delta":10,"referenceType":"comment","referenceId":1}`,
+			`{"source":"questions","idempotencyKey":"k","reason":"answer_upvoted","delta":10,"referenceId":1}`,
+		} {
+			req := httptest.NewRequest("POST", "/api/v1/users/4/reputation", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-Role", "service")
+			req.Header.Set("X-Service-Name", "questions")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, 400, resp.StatusCode, body)
+		}
+	})
+
+	t.Run("GET /users/:id/reputation", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetReputationEvents(gomock.Any(), 4, 2, 10).
+			Return(user.ReputationPage{Events: []user.ReputationEvent{{ID: 9, UserID: 4, Delta: -2}}, Total: 11, Page: 2, PerPage: 10}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/reputation?page=2&per_page=10", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var page user.ReputationPage
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Equal(t, int64(11), page.Total)
+		assert.Equal(t, -2, page.Events[0].Delta)
+	})
+
+	t.Run("GET /users/:id/privileges", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetPrivileges(gomock.Any(), 4).
+			Return(user.Privileges{
+				UserID:    4,
+				UserScore: 60,
+				Granted:   []user.Privilege{{Name: "comment", Reputation: 50}},
+				Next:      []user.NextPrivilege{{Privilege: user.Privilege{Name: "vote_down", Reputation: 125}, Remaining: 65}},
+			}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/privileges", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var privileges user.Privileges
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&privileges))
+		assert.Equal(t, 65, privileges.Next[0].Remaining)
+	})
+
+	t.Run("GET /users/:id/privileges/:name", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			CheckPrivilege(gomock.Any(), 4, "comment").
+			Return(user.PrivilegeCheck{UserID: 4, Privilege: user.Privilege{Name: "comment", Reputation: 50}, Granted: true}, nil)
+		serviceMock.
+			EXPECT().
+			CheckPrivilege(gomock.Any(), 4, "fly").
+			Return(user.PrivilegeCheck{}, domainerr.NotFound("privilege %q does not exist", "fly"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/privileges/comment", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var check user.PrivilegeCheck
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&check))
+		assert.True(t, check.Granted)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/api/v1/users/4/privileges/fly", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("GET /badges", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetBadges(gomock.Any()).
+			Return([]user.Badge{{Name: "Autobiographer", Class: user.Bronze}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/badges", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var badges []user.Badge
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&badges))
+		assert.Equal(t, "Autobiographer", badges[0].Name)
+	})
+
+	t.Run("GET /users/:id/badges", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetAwards(gomock.Any(), 4).
+			Return([]user.Award{{UserID: 4, Badge: "Accepted", Class: user.Bronze, Key: "answer:7"}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/badges", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var awards []user.Award
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&awards))
+		assert.Equal(t, "answer:7", awards[0].Key)
+	})
+
+	t.Run("GET /users?ids=&expand=badges", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUsersByIDs(gomock.Any(), []int{4, 5}).
+			Return(user.Batch{Users: []user.User{{ID: 4}, {ID: 5}}, NotFound: []int{}}, nil)
+		serviceMock.
+			EXPECT().
+			GetAwardsByUserIDs(gomock.Any(), []int{4, 5}).
+			Return(map[int][]user.Award{4: {{UserID: 4, Badge: "Autobiographer", Class: user.Bronze}}, 5: {}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users?ids=4,5&fields=id&expand=badges", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var batch struct {
+			Users []struct {
+				ID     int
+				Badges []user.Award `json:"badges"`
+			} `json:"users"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+		assert.Equal(t, "Autobiographer", batch.Users[0].Badges[0].Badge)
+		assert.Empty(t, batch.Users[1].Badges)
+	})
+
+	t.Run("PUT /users/:id/following/:targetId", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().FollowUser(gomock.Any(), 4, 5).Return(true, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users/4/following/5", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+
+		req = httptest.NewRequest("PUT", "/api/v1/users/4/following/5", nil)
+		req.Header.Set("X-User-ID", "6")
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("PUT /users/:id/following/:targetId when blocked", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().FollowUser(gomock.Any(), 4, 5).Return(false, user.ErrBlocked)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users/4/following/5", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("DELETE /users/:id/blocks/:targetId", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().UnblockUser(gomock.Any(), 4, 5).Return(false, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("DELETE", "/api/v1/users/4/blocks/5", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+	})
+
+	t.Run("GET /users/:id/followers", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetFollowers(gomock.Any(), 4, "abc", 2).
+			Return(user.UserPage{
+				Users:      []user.User{{ID: 5, UserName: "five", Email: "five@example.com"}, {ID: 6}},
+				NextCursor: "def",
+			}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/followers?cursor=abc&limit=2", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var page struct {
+			Users      []user.PublicProfile `json:"users"`
+			NextCursor string               `json:"nextCursor"`
+		}
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+		assert.Len(t, page.Users, 2)
+		assert.Equal(t, "five", page.Users[0].UserName)
+		assert.Empty(t, page.Users[0].Email)
+		assert.Equal(t, "def", page.NextCursor)
+	})
+
+	t.Run("GET /users/:id/relationship/:targetId", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetRelationship(gomock.Any(), 4, 5).
+			Return(user.Relationship{UserID: 4, OtherID: 5, Following: true, FollowedBy: true, Mutual: true}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/4/relationship/5", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var relationship user.Relationship
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&relationship))
+		assert.True(t, relationship.Mutual)
+	})
+
+	t.Run("GET /users/:id of a user who blocked the caller is not found", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 5).
+			Return(user.User{}, domainerr.NotFound("user not found"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("GET", "/api/v1/users/5", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+	})
+
+	t.Run("POST /users/batch-get by a blocked caller leaves out the blocker", func(t *testing.T) {
+		store := blockingStore{
+			users:  []user.User{{ID: 5, Email: "five@example.com"}, {ID: 6}},
+			blocks: []user.Block{{BlockerID: 5, BlockedID: 4}},
+		}
+		app := CreateRoutes(usr.NewService(store), validator.New())
+		get := func(id string) batchView {
+			req := httptest.NewRequest("POST", "/api/v1/users/batch-get", strings.NewReader(`{"ids":[5,6]}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-ID", id)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, 200, resp.StatusCode)
+			var batch batchView
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&batch))
+			return batch
+		}
+
+		blocked := get("4")
+		assert.Len(t, blocked.Users, 1)
+		assert.Equal(t, []int{5}, blocked.NotFound)
+		other := get("7")
+		assert.Len(t, other.Users, 2)
+		assert.Empty(t, other.NotFound)
+	})
+
+	t.Run("GET /users/:id/mutes", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetMutedUsers(gomock.Any(), 4, "", 0).
+			Return(user.UserPage{Users: []user.User{{ID: 5}}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("GET", "/api/v1/users/4/mutes", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		req = httptest.NewRequest("GET", "/api/v1/users/4/mutes", nil)
+		req.Header.Set("X-User-ID", "5")
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 403, resp.StatusCode)
+	})
+
+	t.Run("PUT /users/:id/mutes/:targetId", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().MuteUser(gomock.Any(), 4, 5).Return(true, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("PUT", "/api/v1/users/4/mutes/5", nil)
+		req.Header.Set("X-User-ID", "4")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 204, resp.StatusCode)
+	})
+
+	t.Run("POST /blocks/check", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			CheckBlocks(gomock.Any(), []user.BlockCheck{{UserID: 7, BlockedBy: 9}}).
+			Return([]user.BlockCheck{{UserID: 7, BlockedBy: 9, Blocked: true}}, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		body := `{"checks":[{"userId":7,"blockedBy":9}]}`
+		req := httptest.NewRequest("POST", "/api/v1/blocks/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-User-Role", "service")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+		var answered BlockCheckResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&answered))
+		assert.True(t, answered.Checks[0].Blocked)
+
+		req = httptest.NewRequest("POST", "/api/v1/blocks/check", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err = app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 401, resp.StatusCode)
+	})
+
+	t.Run("POST /admin/reputation/recalculate", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.EXPECT().RecalculateUserScores(gomock.Any()).Return(3, nil)
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/admin/reputation/recalculate", nil)
+		req.Header.Set("X-User-Role", "admin")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 200, resp.StatusCode)
+
+		var body RecalculateResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, 3, body.Updated)
+	})
+	t.Run("Requests past their route timeout get a 504", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 7).
+			DoAndReturn(func(ctx context.Context, _ int) (user.User, error) {
+				<-ctx.Done()
+				return user.User{}, ctx.Err()
+			})
+
+		app := CreateRoutes(serviceMock, validator.New(), WithTimeouts(Timeouts{
+			Default: time.Second,
+			Routes: map[string]time.Duration{
+				"GET /users/:id": 20 * time.Millisecond,
+			},
+		}))
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/7", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 504, resp.StatusCode)
+	})
+	t.Run("Domain errors map to their status codes", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			GetUserByID(gomock.Any(), 404).
+			Return(user.User{}, domainerr.Wrap(domainerr.KindNotFound, errors.New("record not found"), "user not found"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/404", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 404, resp.StatusCode)
+
+		var body Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "user not found", body.Detail)
+		assert.Equal(t, "https://nuboverflow.com/problems/not-found", body.Type)
+		assert.Equal(t, "/api/v1/users/404", body.Instance)
+	})
+
+	t.Run("Non-numeric ids are a bad request", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		resp, err := app.Test(httptest.NewRequest("GET", "/api/v1/users/abc", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("POST /users with an existing email is a conflict", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			CreateUser(gomock.Any(), gomock.Any()).
+			Return(nil, domainerr.Conflict("a user with this email already exists"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users",
+			strings.NewReader(`{"username":"tester","password":"Secr3t!pass","email":"test@test.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 409, resp.StatusCode)
+	})
+
+	t.Run("POST /users with a malformed body is a bad request", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users", strings.NewReader(`{"username":`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+	})
+
+	t.Run("POST /users failing validation lists the invalid fields", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		req := httptest.NewRequest("POST", "/api/v1/users",
+			strings.NewReader(`{"username":"bob","password":"Secr3t!pass","email":"not-an-email"}`))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 400, resp.StatusCode)
+		assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+
+		var body Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, 400, body.Status)
+		assert.Equal(t, "Bad Request", body.Title)
+		assert.Equal(t, []FieldError{
+			{Field: "username", Rule: "min", Message: "username must be at least 4 characters in length"},
+			{Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		}, body.Errors)
+	})
+
+	t.Run("POST /users validation messages follow Accept-Language", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		cases := []struct {
+			acceptLanguage string
+			language       string
+			detail         string
+			message        string
+		}{
+			{"de-DE,de;q=0.9", "de", "Die Anfrage enthält ungültige Felder.", "password muss mindestens 8 Zeichen lang sein und einen Buchstaben, eine Ziffer oder ein Satzzeichen enthalten"},
+			{"es", "es", "La solicitud tiene campos no válidos.", "password debe tener al menos 8 caracteres e incluir una letra, un dígito o un signo de puntuación"},
+			{"fr-CA, en;q=0.5", "fr", "La requête contient des champs invalides.", "password doit contenir au moins 8 caractères, dont une lettre, un chiffre ou un signe de ponctuation"},
+			{"ja", "en", "The request has invalid fields.", "password must be at least 8 characters long and include a letter, digit or punctuation mark"},
+		}
+		for _, tc := range cases {
+			req := httptest.NewRequest("POST", "/api/v1/users",
+				strings.NewReader(`{"username":"bobby","password":"short","email":"bob@example.com"}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, 400, resp.StatusCode)
+			assert.Equal(t, tc.language, resp.Header.Get("Content-Language"))
+
+			var body Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tc.detail, body.Detail, tc.acceptLanguage)
+			assert.Equal(t, []FieldError{
+				{Field: "password", Rule: "passwd", Message: tc.message},
+			}, body.Errors, tc.acceptLanguage)
+		}
+	})
+
+	t.Run("Validation messages in German depend on the kind of field", func(t *testing.T) {
+		app := CreateRoutes(NewMockService(mockCtrl), validator.New())
+		cases := []struct {
+			target  string
+			headers map[string]string
+			body    string
+			errors  []FieldError
+		}{
+			{"/api/v1/users/batch-get", nil, `{"ids":[]}`, []FieldError{
+				{Field: "ids", Rule: "min", Message: "ids muss mindestens 1 Element enthalten"},
+			}},
+			{"/api/v1/users", nil, `{"username":"bob","password":"Secret-123","email":"bob@example.com"}`, []FieldError{
+				{Field: "username", Rule: "min", Message: "username muss mindestens 4 Zeichen lang sein"},
+			}},
+			{"/api/v1/users/5/reputation", map[string]string{"X-User-Role": "service", "X-Service-Name": "questions"}, `{"source":"questions","idempotencyKey":"k","reason":"r","delta":1,"referenceType":"comment"}`, []FieldError{
+				{Field: "referenceType", Rule: "oneof", Message: "referenceType muss einer der folgenden Werte sein: [question answer]"},
+				{Field: "referenceId", Rule: "required_with", Message: "referenceId ist ein Pflichtfeld, wenn ReferenceType vorhanden ist"},
+			}},
+		}
+		for _, tc := range cases {
+			req := httptest.NewRequest("POST", tc.target, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", "de")
+			for name, value := range tc.headers {
+				req.Header.Set(name, value)
+			}
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, 400, resp.StatusCode, tc.target)
+
+			var body Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tc.errors, body.Errors, tc.target)
+		}
+	})
+
+	t.Run("Unexpected errors are a 500 without details", func(t *testing.T) {
+		serviceMock := NewMockService(mockCtrl)
+		serviceMock.
+			EXPECT().
+			DeleteUser(gomock.Any(), 5).
+			Return(errors.New("dial tcp: connection refused"))
+
+		app := CreateRoutes(serviceMock, validator.New())
+		req := httptest.NewRequest("DELETE", "/api/v1/users/5", nil)
+		req.Header.Set("X-User-Role", "admin")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, 500, resp.StatusCode)
+
+		var body Problem
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "Internal Server Error", body.Title)
+		assert.Empty(t, body.Detail)
+	})
+}
+
+// blockingStore is a repository.Store holding users and the blocks between
+// them, enough to look users up on behalf of a viewer.
+type blockingStore struct {
+	repository.Store
+	users  []user.User
+	blocks []user.Block
+}
+
+func (s blockingStore) GetUsersByIDs(_ context.Context, ids []int) ([]user.User, error) {
+	var found []user.User
+	for _, u := range s.users {
+		for _, id := range ids {
+			if u.ID == id {
+				found = append(found, u)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s blockingStore) FindBlocks(_ context.Context, pairs []user.Block) ([]user.Block, error) {
+	var found []user.Block
+	for _, b := range s.blocks {
+		for _, pair := range pairs {
+			if b.BlockerID == pair.BlockerID && b.BlockedID == pair.BlockedID {
+				found = append(found, b)
+			}
+		}
+	}
+	return found, nil
+}
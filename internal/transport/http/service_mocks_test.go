@@ -0,0 +1,861 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/millbj92/nuboverflow-users/internal/user/service (interfaces: Service)
+
+// Package http is a generated GoMock package.
+package http
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	avatar "github.com/millbj92/nuboverflow-users/internal/avatar"
+	user "github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// AddReputationEvent mocks base method.
+func (m *MockService) AddReputationEvent(arg0 context.Context, arg1 user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReputationEvent", arg0, arg1)
+	ret0, _ := ret[0].(user.ReputationEvent)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddReputationEvent indicates an expected call of AddReputationEvent.
+func (mr *MockServiceMockRecorder) AddReputationEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReputationEvent", reflect.TypeOf((*MockService)(nil).AddReputationEvent), arg0, arg1)
+}
+
+// AddUserSkill mocks base method.
+func (m *MockService) AddUserSkill(arg0 context.Context, arg1 int, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddUserSkill indicates an expected call of AddUserSkill.
+func (mr *MockServiceMockRecorder) AddUserSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserSkill", reflect.TypeOf((*MockService)(nil).AddUserSkill), arg0, arg1, arg2)
+}
+
+// BlockUser mocks base method.
+func (m *MockService) BlockUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockUser indicates an expected call of BlockUser.
+func (mr *MockServiceMockRecorder) BlockUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockService)(nil).BlockUser), arg0, arg1, arg2)
+}
+
+// CheckBlocks mocks base method.
+func (m *MockService) CheckBlocks(arg0 context.Context, arg1 []user.BlockCheck) ([]user.BlockCheck, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckBlocks", arg0, arg1)
+	ret0, _ := ret[0].([]user.BlockCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckBlocks indicates an expected call of CheckBlocks.
+func (mr *MockServiceMockRecorder) CheckBlocks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckBlocks", reflect.TypeOf((*MockService)(nil).CheckBlocks), arg0, arg1)
+}
+
+// CheckPrivilege mocks base method.
+func (m *MockService) CheckPrivilege(arg0 context.Context, arg1 int, arg2 string) (user.PrivilegeCheck, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckPrivilege", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.PrivilegeCheck)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CheckPrivilege indicates an expected call of CheckPrivilege.
+func (mr *MockServiceMockRecorder) CheckPrivilege(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckPrivilege", reflect.TypeOf((*MockService)(nil).CheckPrivilege), arg0, arg1, arg2)
+}
+
+// CreateUser mocks base method.
+func (m *MockService) CreateUser(arg0 context.Context, arg1 *user.User) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockServiceMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockService)(nil).CreateUser), arg0, arg1)
+}
+
+// DeleteAvatar mocks base method.
+func (m *MockService) DeleteAvatar(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAvatar", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteAvatar indicates an expected call of DeleteAvatar.
+func (mr *MockServiceMockRecorder) DeleteAvatar(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAvatar", reflect.TypeOf((*MockService)(nil).DeleteAvatar), arg0, arg1)
+}
+
+// DeleteProfileField mocks base method.
+func (m *MockService) DeleteProfileField(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProfileField", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteProfileField indicates an expected call of DeleteProfileField.
+func (mr *MockServiceMockRecorder) DeleteProfileField(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfileField", reflect.TypeOf((*MockService)(nil).DeleteProfileField), arg0, arg1)
+}
+
+// DeleteUser mocks base method.
+func (m *MockService) DeleteUser(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockServiceMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockService)(nil).DeleteUser), arg0, arg1)
+}
+
+// EndorseSkill mocks base method.
+func (m *MockService) EndorseSkill(arg0 context.Context, arg1, arg2 int, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndorseSkill", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EndorseSkill indicates an expected call of EndorseSkill.
+func (mr *MockServiceMockRecorder) EndorseSkill(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndorseSkill", reflect.TypeOf((*MockService)(nil).EndorseSkill), arg0, arg1, arg2, arg3)
+}
+
+// FindUsers mocks base method.
+func (m *MockService) FindUsers(arg0 context.Context, arg1 user.Filter) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", arg0, arg1)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockServiceMockRecorder) FindUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockService)(nil).FindUsers), arg0, arg1)
+}
+
+// FollowUser mocks base method.
+func (m *MockService) FollowUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FollowUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FollowUser indicates an expected call of FollowUser.
+func (mr *MockServiceMockRecorder) FollowUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FollowUser", reflect.TypeOf((*MockService)(nil).FollowUser), arg0, arg1, arg2)
+}
+
+// GetAllUsers mocks base method.
+func (m *MockService) GetAllUsers(arg0 context.Context) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllUsers", arg0)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllUsers indicates an expected call of GetAllUsers.
+func (mr *MockServiceMockRecorder) GetAllUsers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllUsers", reflect.TypeOf((*MockService)(nil).GetAllUsers), arg0)
+}
+
+// GetAvatar mocks base method.
+func (m *MockService) GetAvatar(arg0 context.Context, arg1, arg2 int, arg3 avatar.Format) (avatar.Variant, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvatar", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(avatar.Variant)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAvatar indicates an expected call of GetAvatar.
+func (mr *MockServiceMockRecorder) GetAvatar(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvatar", reflect.TypeOf((*MockService)(nil).GetAvatar), arg0, arg1, arg2, arg3)
+}
+
+// GetAwards mocks base method.
+func (m *MockService) GetAwards(arg0 context.Context, arg1 int) ([]user.Award, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAwards", arg0, arg1)
+	ret0, _ := ret[0].([]user.Award)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAwards indicates an expected call of GetAwards.
+func (mr *MockServiceMockRecorder) GetAwards(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAwards", reflect.TypeOf((*MockService)(nil).GetAwards), arg0, arg1)
+}
+
+// GetAwardsByUserIDs mocks base method.
+func (m *MockService) GetAwardsByUserIDs(arg0 context.Context, arg1 []int) (map[int][]user.Award, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAwardsByUserIDs", arg0, arg1)
+	ret0, _ := ret[0].(map[int][]user.Award)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAwardsByUserIDs indicates an expected call of GetAwardsByUserIDs.
+func (mr *MockServiceMockRecorder) GetAwardsByUserIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAwardsByUserIDs", reflect.TypeOf((*MockService)(nil).GetAwardsByUserIDs), arg0, arg1)
+}
+
+// GetBadges mocks base method.
+func (m *MockService) GetBadges(arg0 context.Context) ([]user.Badge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBadges", arg0)
+	ret0, _ := ret[0].([]user.Badge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBadges indicates an expected call of GetBadges.
+func (mr *MockServiceMockRecorder) GetBadges(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBadges", reflect.TypeOf((*MockService)(nil).GetBadges), arg0)
+}
+
+// GetBlockedUsers mocks base method.
+func (m *MockService) GetBlockedUsers(arg0 context.Context, arg1 int, arg2 string, arg3 int) (user.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockedUsers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockedUsers indicates an expected call of GetBlockedUsers.
+func (mr *MockServiceMockRecorder) GetBlockedUsers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockedUsers", reflect.TypeOf((*MockService)(nil).GetBlockedUsers), arg0, arg1, arg2, arg3)
+}
+
+// GetFollowers mocks base method.
+func (m *MockService) GetFollowers(arg0 context.Context, arg1 int, arg2 string, arg3 int) (user.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockServiceMockRecorder) GetFollowers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockService)(nil).GetFollowers), arg0, arg1, arg2, arg3)
+}
+
+// GetFollowing mocks base method.
+func (m *MockService) GetFollowing(arg0 context.Context, arg1 int, arg2 string, arg3 int) (user.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockServiceMockRecorder) GetFollowing(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockService)(nil).GetFollowing), arg0, arg1, arg2, arg3)
+}
+
+// GetMutedUsers mocks base method.
+func (m *MockService) GetMutedUsers(arg0 context.Context, arg1 int, arg2 string, arg3 int) (user.UserPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMutedUsers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.UserPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMutedUsers indicates an expected call of GetMutedUsers.
+func (mr *MockServiceMockRecorder) GetMutedUsers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMutedUsers", reflect.TypeOf((*MockService)(nil).GetMutedUsers), arg0, arg1, arg2, arg3)
+}
+
+// GetPrivileges mocks base method.
+func (m *MockService) GetPrivileges(arg0 context.Context, arg1 int) (user.Privileges, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPrivileges", arg0, arg1)
+	ret0, _ := ret[0].(user.Privileges)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPrivileges indicates an expected call of GetPrivileges.
+func (mr *MockServiceMockRecorder) GetPrivileges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPrivileges", reflect.TypeOf((*MockService)(nil).GetPrivileges), arg0, arg1)
+}
+
+// GetProfileFields mocks base method.
+func (m *MockService) GetProfileFields(arg0 context.Context) ([]user.ProfileField, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileFields", arg0)
+	ret0, _ := ret[0].([]user.ProfileField)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileFields indicates an expected call of GetProfileFields.
+func (mr *MockServiceMockRecorder) GetProfileFields(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileFields", reflect.TypeOf((*MockService)(nil).GetProfileFields), arg0)
+}
+
+// GetRelationship mocks base method.
+func (m *MockService) GetRelationship(arg0 context.Context, arg1, arg2 int) (user.Relationship, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRelationship", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.Relationship)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRelationship indicates an expected call of GetRelationship.
+func (mr *MockServiceMockRecorder) GetRelationship(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRelationship", reflect.TypeOf((*MockService)(nil).GetRelationship), arg0, arg1, arg2)
+}
+
+// GetRenamedUser mocks base method.
+func (m *MockService) GetRenamedUser(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRenamedUser", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRenamedUser indicates an expected call of GetRenamedUser.
+func (mr *MockServiceMockRecorder) GetRenamedUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRenamedUser", reflect.TypeOf((*MockService)(nil).GetRenamedUser), arg0, arg1)
+}
+
+// GetReputationEvents mocks base method.
+func (m *MockService) GetReputationEvents(arg0 context.Context, arg1, arg2, arg3 int) (user.ReputationPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReputationEvents", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.ReputationPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReputationEvents indicates an expected call of GetReputationEvents.
+func (mr *MockServiceMockRecorder) GetReputationEvents(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReputationEvents", reflect.TypeOf((*MockService)(nil).GetReputationEvents), arg0, arg1, arg2, arg3)
+}
+
+// GetSkills mocks base method.
+func (m *MockService) GetSkills(arg0 context.Context, arg1 string, arg2 int) ([]user.SkillSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSkills", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.SkillSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSkills indicates an expected call of GetSkills.
+func (mr *MockServiceMockRecorder) GetSkills(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSkills", reflect.TypeOf((*MockService)(nil).GetSkills), arg0, arg1, arg2)
+}
+
+// GetSkillsByUserIDs mocks base method.
+func (m *MockService) GetSkillsByUserIDs(arg0 context.Context, arg1 []int) (map[int][]user.UserSkill, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSkillsByUserIDs", arg0, arg1)
+	ret0, _ := ret[0].(map[int][]user.UserSkill)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSkillsByUserIDs indicates an expected call of GetSkillsByUserIDs.
+func (mr *MockServiceMockRecorder) GetSkillsByUserIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSkillsByUserIDs", reflect.TypeOf((*MockService)(nil).GetSkillsByUserIDs), arg0, arg1)
+}
+
+// GetTopUsersForSkill mocks base method.
+func (m *MockService) GetTopUsersForSkill(arg0 context.Context, arg1 string, arg2 int) ([]user.SkilledUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopUsersForSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.SkilledUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopUsersForSkill indicates an expected call of GetTopUsersForSkill.
+func (mr *MockServiceMockRecorder) GetTopUsersForSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopUsersForSkill", reflect.TypeOf((*MockService)(nil).GetTopUsersForSkill), arg0, arg1, arg2)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockService) GetUserByEmail(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockServiceMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockService)(nil).GetUserByEmail), arg0, arg1)
+}
+
+// GetUserByID mocks base method.
+func (m *MockService) GetUserByID(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockServiceMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockService)(nil).GetUserByID), arg0, arg1)
+}
+
+// GetUserByUserName mocks base method.
+func (m *MockService) GetUserByUserName(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUserName", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUserName indicates an expected call of GetUserByUserName.
+func (mr *MockServiceMockRecorder) GetUserByUserName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserName", reflect.TypeOf((*MockService)(nil).GetUserByUserName), arg0, arg1)
+}
+
+// GetUserNameHistory mocks base method.
+func (m *MockService) GetUserNameHistory(arg0 context.Context, arg1 int) ([]user.UserNameChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserNameHistory", arg0, arg1)
+	ret0, _ := ret[0].([]user.UserNameChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserNameHistory indicates an expected call of GetUserNameHistory.
+func (mr *MockServiceMockRecorder) GetUserNameHistory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserNameHistory", reflect.TypeOf((*MockService)(nil).GetUserNameHistory), arg0, arg1)
+}
+
+// GetUserSkills mocks base method.
+func (m *MockService) GetUserSkills(arg0 context.Context, arg1 int) ([]user.UserSkill, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSkills", arg0, arg1)
+	ret0, _ := ret[0].([]user.UserSkill)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSkills indicates an expected call of GetUserSkills.
+func (mr *MockServiceMockRecorder) GetUserSkills(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSkills", reflect.TypeOf((*MockService)(nil).GetUserSkills), arg0, arg1)
+}
+
+// GetUserStats mocks base method.
+func (m *MockService) GetUserStats(arg0 context.Context, arg1 []int) (map[int]user.Stats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserStats", arg0, arg1)
+	ret0, _ := ret[0].(map[int]user.Stats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserStats indicates an expected call of GetUserStats.
+func (mr *MockServiceMockRecorder) GetUserStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserStats", reflect.TypeOf((*MockService)(nil).GetUserStats), arg0, arg1)
+}
+
+// GetUsersByIDs mocks base method.
+func (m *MockService) GetUsersByIDs(arg0 context.Context, arg1 []int) (user.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByIDs", arg0, arg1)
+	ret0, _ := ret[0].(user.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByIDs indicates an expected call of GetUsersByIDs.
+func (mr *MockServiceMockRecorder) GetUsersByIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIDs", reflect.TypeOf((*MockService)(nil).GetUsersByIDs), arg0, arg1)
+}
+
+// MuteUser mocks base method.
+func (m *MockService) MuteUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MuteUser indicates an expected call of MuteUser.
+func (mr *MockServiceMockRecorder) MuteUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteUser", reflect.TypeOf((*MockService)(nil).MuteUser), arg0, arg1, arg2)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockService) PurgeDeletedUsers(arg0 context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", arg0)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockServiceMockRecorder) PurgeDeletedUsers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockService)(nil).PurgeDeletedUsers), arg0)
+}
+
+// RecalculateUserScores mocks base method.
+func (m *MockService) RecalculateUserScores(arg0 context.Context) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecalculateUserScores", arg0)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecalculateUserScores indicates an expected call of RecalculateUserScores.
+func (mr *MockServiceMockRecorder) RecalculateUserScores(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecalculateUserScores", reflect.TypeOf((*MockService)(nil).RecalculateUserScores), arg0)
+}
+
+// RemoveUserSkill mocks base method.
+func (m *MockService) RemoveUserSkill(arg0 context.Context, arg1 int, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUserSkill indicates an expected call of RemoveUserSkill.
+func (mr *MockServiceMockRecorder) RemoveUserSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserSkill", reflect.TypeOf((*MockService)(nil).RemoveUserSkill), arg0, arg1, arg2)
+}
+
+// RenameUser mocks base method.
+func (m *MockService) RenameUser(arg0 context.Context, arg1 int, arg2 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameUser indicates an expected call of RenameUser.
+func (mr *MockServiceMockRecorder) RenameUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameUser", reflect.TypeOf((*MockService)(nil).RenameUser), arg0, arg1, arg2)
+}
+
+// RestoreUser mocks base method.
+func (m *MockService) RestoreUser(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockServiceMockRecorder) RestoreUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockService)(nil).RestoreUser), arg0, arg1)
+}
+
+// SaveProfileField mocks base method.
+func (m *MockService) SaveProfileField(arg0 context.Context, arg1 user.ProfileField) (user.ProfileField, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveProfileField", arg0, arg1)
+	ret0, _ := ret[0].(user.ProfileField)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveProfileField indicates an expected call of SaveProfileField.
+func (mr *MockServiceMockRecorder) SaveProfileField(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveProfileField", reflect.TypeOf((*MockService)(nil).SaveProfileField), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockService) SearchUsers(arg0 context.Context, arg1 user.SearchQuery) (user.SearchPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1)
+	ret0, _ := ret[0].(user.SearchPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockServiceMockRecorder) SearchUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockService)(nil).SearchUsers), arg0, arg1)
+}
+
+// SetAvatar mocks base method.
+func (m *MockService) SetAvatar(arg0 context.Context, arg1 int, arg2 []byte) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetAvatar", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetAvatar indicates an expected call of SetAvatar.
+func (mr *MockServiceMockRecorder) SetAvatar(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAvatar", reflect.TypeOf((*MockService)(nil).SetAvatar), arg0, arg1, arg2)
+}
+
+// SetProfileFields mocks base method.
+func (m *MockService) SetProfileFields(arg0 context.Context, arg1 int, arg2 map[string]string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProfileFields", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetProfileFields indicates an expected call of SetProfileFields.
+func (mr *MockServiceMockRecorder) SetProfileFields(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProfileFields", reflect.TypeOf((*MockService)(nil).SetProfileFields), arg0, arg1, arg2)
+}
+
+// StartGithubVerification mocks base method.
+func (m *MockService) StartGithubVerification(arg0 context.Context, arg1 int) (user.GithubChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartGithubVerification", arg0, arg1)
+	ret0, _ := ret[0].(user.GithubChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartGithubVerification indicates an expected call of StartGithubVerification.
+func (mr *MockServiceMockRecorder) StartGithubVerification(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartGithubVerification", reflect.TypeOf((*MockService)(nil).StartGithubVerification), arg0, arg1)
+}
+
+// SuggestUsers mocks base method.
+func (m *MockService) SuggestUsers(arg0 context.Context, arg1 string, arg2 int) ([]user.Suggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestUsers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.Suggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestUsers indicates an expected call of SuggestUsers.
+func (mr *MockServiceMockRecorder) SuggestUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestUsers", reflect.TypeOf((*MockService)(nil).SuggestUsers), arg0, arg1, arg2)
+}
+
+// UnblockUser mocks base method.
+func (m *MockService) UnblockUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnblockUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnblockUser indicates an expected call of UnblockUser.
+func (mr *MockServiceMockRecorder) UnblockUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockService)(nil).UnblockUser), arg0, arg1, arg2)
+}
+
+// UnendorseSkill mocks base method.
+func (m *MockService) UnendorseSkill(arg0 context.Context, arg1, arg2 int, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnendorseSkill", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnendorseSkill indicates an expected call of UnendorseSkill.
+func (mr *MockServiceMockRecorder) UnendorseSkill(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnendorseSkill", reflect.TypeOf((*MockService)(nil).UnendorseSkill), arg0, arg1, arg2, arg3)
+}
+
+// UnfollowUser mocks base method.
+func (m *MockService) UnfollowUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnfollowUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnfollowUser indicates an expected call of UnfollowUser.
+func (mr *MockServiceMockRecorder) UnfollowUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnfollowUser", reflect.TypeOf((*MockService)(nil).UnfollowUser), arg0, arg1, arg2)
+}
+
+// UnmuteUser mocks base method.
+func (m *MockService) UnmuteUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmuteUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnmuteUser indicates an expected call of UnmuteUser.
+func (mr *MockServiceMockRecorder) UnmuteUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmuteUser", reflect.TypeOf((*MockService)(nil).UnmuteUser), arg0, arg1, arg2)
+}
+
+// UpdatePrivacy mocks base method.
+func (m *MockService) UpdatePrivacy(arg0 context.Context, arg1 int, arg2 user.Privacy) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePrivacy", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePrivacy indicates an expected call of UpdatePrivacy.
+func (mr *MockServiceMockRecorder) UpdatePrivacy(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePrivacy", reflect.TypeOf((*MockService)(nil).UpdatePrivacy), arg0, arg1, arg2)
+}
+
+// UpdateUser mocks base method.
+func (m *MockService) UpdateUser(arg0 context.Context, arg1 user.User) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockServiceMockRecorder) UpdateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockService)(nil).UpdateUser), arg0, arg1)
+}
+
+// VerifyGithub mocks base method.
+func (m *MockService) VerifyGithub(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyGithub", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyGithub indicates an expected call of VerifyGithub.
+func (mr *MockServiceMockRecorder) VerifyGithub(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyGithub", reflect.TypeOf((*MockService)(nil).VerifyGithub), arg0, arg1)
+}
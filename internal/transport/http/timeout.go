@@ -0,0 +1,49 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Timeouts sets the deadline of the context handed to the service by each
+// route. Routes is keyed by method and path as registered under /api/v1,
+// e.g. "GET /users/search"; routes without an entry use Default.
+type Timeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// DefaultTimeouts gives most routes five seconds, full-text search ten,
+// storing the variants of an uploaded avatar thirty and rebuilding every
+// user's reputation five minutes.
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Default: 5 * time.Second,
+		Routes: map[string]time.Duration{
+			"GET /users/search":                  10 * time.Second,
+			"PUT /users/:id/avatar":              30 * time.Second,
+			"POST /admin/reputation/recalculate": 5 * time.Minute,
+		},
+	}
+}
+
+// For returns the timeout for the route registered as method and path.
+func (t Timeouts) For(method, path string) time.Duration {
+	if d, ok := t.Routes[method+" "+path]; ok {
+		return d
+	}
+	return t.Default
+}
+
+// Timeout bounds the request's user context by d, so that service and store
+// calls are cancelled once it passes. ErrorHandler reports those as a 504.
+func Timeout(d time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx, cancel := context.WithTimeout(c.UserContext(), d)
+		defer cancel()
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
@@ -0,0 +1,224 @@
+package http
+
+import (
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	"github.com/gofiber/fiber/v2"
+)
+
+const defaultLocale = "en"
+
+// supportedLocales are matched against Accept-Language in this order of preference.
+var supportedLocales = []string{"en", "es", "de", "fr"}
+
+// translatorKey is the fiber.Ctx local holding the request's ut.Translator.
+const translatorKey = "translator"
+
+// defaultTranslations registers go-playground's messages for the built-in
+// rules. It ships no German, which customTranslations covers instead.
+var defaultTranslations = map[string]func(*validator.Validate, ut.Translator) error{
+	"en": en_translations.RegisterDefaultTranslations,
+	"es": es_translations.RegisterDefaultTranslations,
+	"fr": fr_translations.RegisterDefaultTranslations,
+}
+
+// customTranslations holds messages for our own validation rules, and for
+// the built-in rules we use that go-playground has no translations for in a
+// locale. {0} is the field name and {1} the rule's parameter.
+var customTranslations = map[string]map[string]string{
+	"en": {
+		"passwd":        "{0} must be at least 8 characters long and include a letter, digit or punctuation mark",
+		"required_with": "{0} is required when {1} is present",
+	},
+	"es": {
+		"passwd":        "{0} debe tener al menos 8 caracteres e incluir una letra, un dígito o un signo de puntuación",
+		"required_with": "{0} es obligatorio cuando {1} está presente",
+	},
+	"de": {
+		"required":      "{0} ist ein Pflichtfeld",
+		"required_with": "{0} ist ein Pflichtfeld, wenn {1} vorhanden ist",
+		"email":         "{0} muss eine gültige E-Mail-Adresse sein",
+		"oneof":         "{0} muss einer der folgenden Werte sein: [{1}]",
+		"passwd":        "{0} muss mindestens 8 Zeichen lang sein und einen Buchstaben, eine Ziffer oder ein Satzzeichen enthalten",
+	},
+	"fr": {
+		"passwd":        "{0} doit contenir au moins 8 caractères, dont une lettre, un chiffre ou un signe de ponctuation",
+		"required_with": "{0} est obligatoire lorsque {1} est présent",
+	},
+}
+
+// sizeMessages are the messages for a min or max rule, which go-playground
+// words by the kind of field: strings are measured in characters, slices and
+// maps in items, and numbers by their value. {1} is the number of
+// characters or items, or the number itself.
+type sizeMessages struct {
+	characters string
+	items      string
+	number     string
+}
+
+// sizeTranslations holds min and max messages for the locales go-playground
+// has no translations for.
+var sizeTranslations = map[string]map[string]sizeMessages{
+	"de": {
+		"min": {
+			characters: "{0} muss mindestens {1} lang sein",
+			items:      "{0} muss mindestens {1} enthalten",
+			number:     "{0} muss {1} oder größer sein",
+		},
+		"max": {
+			characters: "{0} darf höchstens {1} lang sein",
+			items:      "{0} darf höchstens {1} enthalten",
+			number:     "{0} darf höchstens {1} sein",
+		},
+	},
+}
+
+// sizeUnits counts characters and items in sizeTranslations, in the
+// singular and the plural. {0} is the count.
+var sizeUnits = map[string]struct{ character, item [2]string }{
+	"de": {
+		character: [2]string{"{0} Zeichen", "{0} Zeichen"},
+		item:      [2]string{"{0} Element", "{0} Elemente"},
+	},
+}
+
+// validationFailedDetail is the problem detail for a request with invalid fields.
+var validationFailedDetail = map[string]string{
+	"en": "The request has invalid fields.",
+	"es": "La solicitud tiene campos no válidos.",
+	"de": "Die Anfrage enthält ungültige Felder.",
+	"fr": "La requête contient des champs invalides.",
+}
+
+// registerTranslations sets up validation messages for every supported locale.
+// It must run after registerValidators so the custom rules exist.
+func registerTranslations(v *validator.Validate) *ut.UniversalTranslator {
+	uni := ut.New(en.New(), en.New(), es.New(), de.New(), fr.New())
+	for _, locale := range supportedLocales {
+		trans, _ := uni.GetTranslator(locale)
+		if register, ok := defaultTranslations[locale]; ok {
+			if err := register(v, trans); err != nil {
+				log.Printf("Failed to register %s validation messages: %s", locale, err)
+			}
+		}
+		for tag, message := range customTranslations[locale] {
+			if err := v.RegisterTranslation(tag, trans, addTranslation(tag, message), translateField); err != nil {
+				log.Printf("Failed to register %s message for %s: %s", locale, tag, err)
+			}
+		}
+		for tag, messages := range sizeTranslations[locale] {
+			register := addSizeTranslation(tag, messages, sizeUnits[locale].character, sizeUnits[locale].item)
+			if err := v.RegisterTranslation(tag, trans, register, translateSize(tag)); err != nil {
+				log.Printf("Failed to register %s message for %s: %s", locale, tag, err)
+			}
+		}
+	}
+	return uni
+}
+
+func addTranslation(tag, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
+	}
+}
+
+func translateField(trans ut.Translator, fe validator.FieldError) string {
+	message, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.Error()
+	}
+	return message
+}
+
+func addSizeTranslation(tag string, messages sizeMessages, character, item [2]string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		for key, message := range map[string]string{
+			tag + "-characters": messages.characters,
+			tag + "-items":      messages.items,
+			tag + "-number":     messages.number,
+		} {
+			if err := trans.Add(key, message, true); err != nil {
+				return err
+			}
+		}
+		for key, forms := range map[string][2]string{
+			tag + "-character": character,
+			tag + "-item":      item,
+		} {
+			if err := trans.AddCardinal(key, forms[0], locales.PluralRuleOne, true); err != nil {
+				return err
+			}
+			if err := trans.AddCardinal(key, forms[1], locales.PluralRuleOther, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// translateSize describes a failed min or max rule by the kind of field, as
+// go-playground does for the locales it translates.
+func translateSize(tag string) validator.TranslationFunc {
+	return func(trans ut.Translator, fe validator.FieldError) string {
+		n, err := strconv.ParseFloat(fe.Param(), 64)
+		if err != nil {
+			return fe.Error()
+		}
+		var digits uint64
+		if i := strings.IndexByte(fe.Param(), '.'); i >= 0 {
+			digits = uint64(len(fe.Param()) - i - 1)
+		}
+		amount := trans.FmtNumber(n, digits)
+
+		kind := fe.Kind()
+		if kind == reflect.Ptr {
+			kind = fe.Type().Elem().Kind()
+		}
+		key := tag + "-number"
+		switch kind {
+		case reflect.String:
+			key = tag + "-characters"
+			amount, err = trans.C(tag+"-character", n, digits, amount)
+		case reflect.Slice, reflect.Map, reflect.Array:
+			key = tag + "-items"
+			amount, err = trans.C(tag+"-item", n, digits, amount)
+		}
+		if err != nil {
+			return fe.Error()
+		}
+		message, err := trans.T(key, fe.Field(), amount)
+		if err != nil {
+			return fe.Error()
+		}
+		return message
+	}
+}
+
+// Localize picks the translator for the best match of the request's
+// Accept-Language, falling back to English, for ErrorHandler to describe
+// validation errors with.
+func Localize(uni *ut.UniversalTranslator) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		locale := c.AcceptsLanguages(supportedLocales...)
+		if locale == "" {
+			locale = defaultLocale
+		}
+		trans, _ := uni.GetTranslator(locale)
+		c.Locals(translatorKey, trans)
+		return c.Next()
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// view returns the representation of u that caller may see: admins get the
+// AdminView, users their own SelfProfile and everyone else the PublicProfile.
+func view(caller Caller, u user.User) interface{} {
+	switch {
+	case caller.IsAdmin():
+		return u.AdminView()
+	case caller.Is(u.ID):
+		return u.SelfProfile()
+	default:
+		return u.PublicProfile()
+	}
+}
+
+func views(caller Caller, users []user.User) []interface{} {
+	out := make([]interface{}, len(users))
+	for i, u := range users {
+		out[i] = view(caller, u)
+	}
+	return out
+}
+
+// varyByCaller marks a response as depending on who asked for it.
+func varyByCaller(c *fiber.Ctx) {
+	c.Vary(userIDHeader, roleHeader)
+}
+
+// sendUser responds with body, a representation of the user u as the caller
+// may see it, tagged with its ETag. A GET whose If-None-Match matches the tag
+// gets a 304 instead.
+func sendUser(c *fiber.Ctx, u user.User, body interface{}) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	tag := etag(u, raw)
+	c.Set(fiber.HeaderETag, tag)
+	varyByCaller(c)
+	if c.Method() == fiber.MethodGet && noneMatch(c.Get(fiber.HeaderIfNoneMatch), tag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(raw)
+}
+
+// batchView is a user.Batch with each user as the caller may see them.
+type batchView struct {
+	Users    []interface{} `json:"users"`
+	NotFound []int         `json:"notFound"`
+}
+
+// userPageView is a user.UserPage with each user as the caller may see them.
+type userPageView struct {
+	Users      []interface{} `json:"users"`
+	NextCursor string        `json:"nextCursor,omitempty"`
+}
+
+// searchResultView is a user.SearchResult with the user as the caller may see them.
+type searchResultView struct {
+	User       interface{}       `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+type searchPageView struct {
+	Results []searchResultView `json:"results"`
+	Total   int64              `json:"total"`
+	Page    int                `json:"page"`
+	PerPage int                `json:"perPage"`
+}
+
+func searchPageFor(caller Caller, page user.SearchPage) searchPageView {
+	out := searchPageView{
+		Results: make([]searchResultView, 0, len(page.Results)),
+		Total:   page.Total,
+		Page:    page.Page,
+		PerPage: page.PerPage,
+	}
+	for _, result := range page.Results {
+		highlights := result.Highlights
+		if !caller.IsAdmin() && !caller.Is(result.User.ID) && !result.User.Privacy.ShowWorkPlace {
+			// The search didn't match the hidden workplace, but the snippets
+			// are made for every field; don't give it away through its own.
+			highlights = make(map[string]string, len(result.Highlights))
+			for field, snippet := range result.Highlights {
+				if field != "workplace" {
+					highlights[field] = snippet
+				}
+			}
+		}
+		out.Results = append(out.Results, searchResultView{
+			User:       view(caller, result.User),
+			Score:      result.Score,
+			Highlights: highlights,
+		})
+	}
+	return out
+}
+
+// skilledUserView is a user.SkilledUser with the user as the caller may see them.
+type skilledUserView struct {
+	User         interface{} `json:"user"`
+	Endorsements int         `json:"endorsements"`
+}
+
+func skilledUsersFor(caller Caller, users []user.SkilledUser) []skilledUserView {
+	out := make([]skilledUserView, len(users))
+	for i, u := range users {
+		out[i] = skilledUserView{User: view(caller, u.User), Endorsements: u.Endorsements}
+	}
+	return out
+}
+
+// visibleMatches drops the users that only match filter on fields their
+// privacy settings hide from caller, so filters cannot reveal hidden values.
+func visibleMatches(caller Caller, filter user.Filter, users []user.User) []user.User {
+	if caller.IsAdmin() {
+		return users
+	}
+	visible := make([]user.User, 0, len(users))
+	for _, u := range users {
+		hidden := (filter.Email != "" && !u.Privacy.ShowEmail) ||
+			(filter.Github != "" && !u.Privacy.ShowGithub) ||
+			(filter.Linkedin != "" && !u.Privacy.ShowLinkedin) ||
+			(filter.WorkPlace != "" && !u.Privacy.ShowWorkPlace) ||
+			matchesPrivateProfileField(filter, u)
+		if !hidden || caller.Is(u.ID) {
+			visible = append(visible, u)
+		}
+	}
+	return visible
+}
+
+// matchesPrivateProfileField reports whether filter matches u on a profile
+// field whose values are private.
+func matchesPrivateProfileField(filter user.Filter, u user.User) bool {
+	for _, value := range u.ProfileFields {
+		if _, ok := filter.ProfileFields[value.Field]; ok && value.Visibility != user.ProfileFieldPublic {
+			return true
+		}
+	}
+	return false
+}
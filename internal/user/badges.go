@@ -0,0 +1,40 @@
+package user
+
+import "time"
+
+// BadgeClass is how hard a badge is to earn.
+type BadgeClass string
+
+const (
+	Bronze BadgeClass = "bronze"
+	Silver BadgeClass = "silver"
+	Gold   BadgeClass = "gold"
+)
+
+// Badge describes an achievement users can be awarded. One-time badges are
+// awarded to a user at most once; repeatable ones once per thing earning them.
+type Badge struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Class       BadgeClass `json:"class"`
+	Repeatable  bool       `json:"repeatable"`
+}
+
+// Award records a user earning a badge.
+type Award struct {
+	ID     int        `json:"-"`
+	UserID int        `gorm:"not null;uniqueIndex:idx_awards_once" json:"userId"`
+	Badge  string     `gorm:"size:64;not null;uniqueIndex:idx_awards_once" json:"badge"`
+	Class  BadgeClass `gorm:"size:16;not null" json:"class"`
+	// Key tells repeat awards of a repeatable badge apart, such as by the
+	// answer each was earned for. It is empty for one-time badges.
+	Key       string    `gorm:"column:award_key;size:127;not null;default:'';uniqueIndex:idx_awards_once" json:"key,omitempty"`
+	CreatedAt time.Time `json:"awardedAt"`
+}
+
+// BadgeCounts counts a user's awards by class.
+type BadgeCounts struct {
+	Gold   int64 `json:"gold"`
+	Silver int64 `json:"silver"`
+	Bronze int64 `json:"bronze"`
+}
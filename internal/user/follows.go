@@ -0,0 +1,98 @@
+package user
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// ErrBlocked is returned when following a user who blocked you, or whom you
+// blocked.
+var ErrBlocked = domainerr.Forbidden("users cannot follow users they blocked or were blocked by")
+
+// Follow records FollowerID following FolloweeID. Its indexes serve the
+// follower and following lists, which go newest first.
+type Follow struct {
+	FollowerID int       `gorm:"primaryKey;autoIncrement:false;index:idx_follows_following,priority:1" json:"followerId"`
+	FolloweeID int       `gorm:"primaryKey;autoIncrement:false;index:idx_follows_followers,priority:1" json:"followeeId"`
+	CreatedAt  time.Time `gorm:"index:idx_follows_following,priority:2;index:idx_follows_followers,priority:2" json:"followedAt"`
+}
+
+// Block records BlockerID blocking BlockedID. Neither can follow the other
+// while it lasts, and BlockedID can no longer look BlockerID up.
+type Block struct {
+	BlockerID int       `gorm:"primaryKey;autoIncrement:false;index:idx_blocks_blocker,priority:1" json:"blockerId"`
+	BlockedID int       `gorm:"primaryKey;autoIncrement:false;index" json:"blockedId"`
+	CreatedAt time.Time `gorm:"index:idx_blocks_blocker,priority:2" json:"blockedAt"`
+}
+
+// Mute records MuterID muting MutedID. Unlike a block it changes nothing for
+// MutedID, who isn't told: other services use it to keep MutedID's posts and
+// notifications away from MuterID.
+type Mute struct {
+	MuterID   int       `gorm:"primaryKey;autoIncrement:false;index:idx_mutes_muter,priority:1" json:"muterId"`
+	MutedID   int       `gorm:"primaryKey;autoIncrement:false;index" json:"mutedId"`
+	CreatedAt time.Time `gorm:"index:idx_mutes_muter,priority:2" json:"mutedAt"`
+}
+
+// BlockCheck asks whether the user with UserID is blocked by the one with
+// BlockedBy. Blocked holds the answer.
+type BlockCheck struct {
+	UserID    int  `json:"userId"`
+	BlockedBy int  `json:"blockedBy"`
+	Blocked   bool `json:"blocked"`
+}
+
+// Relationship is how one user relates to another.
+type Relationship struct {
+	UserID     int  `json:"userId"`
+	OtherID    int  `json:"otherId"`
+	Following  bool `json:"following"`
+	FollowedBy bool `json:"followedBy"`
+	Mutual     bool `json:"mutual"`
+}
+
+// Cursor marks where a page of follows, blocks or mutes ended, newest first:
+// the next page starts after the one made at CreatedAt by or of UserID. The
+// zero Cursor starts at the newest.
+type Cursor struct {
+	CreatedAt time.Time
+	UserID    int
+}
+
+// String encodes c for clients to pass back as is.
+func (c Cursor) String() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + ":" + strconv.Itoa(c.UserID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a Cursor encoded by String.
+func ParseCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, err
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return Cursor{}, errors.New("malformed cursor")
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return Cursor{}, err
+	}
+	id, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Cursor{}, err
+	}
+	return Cursor{CreatedAt: time.Unix(0, nanos), UserID: id}, nil
+}
+
+// UserPage is a page of users. NextCursor is set when there are more.
+type UserPage struct {
+	Users      []User `json:"users"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// ErrGithubChanged is returned when verifying a Github account the user no
+// longer links to.
+var ErrGithubChanged = domainerr.Conflict("github was changed since its verification started")
+
+// GithubChallenge is a user's pending verification that they own the Github
+// account Handle: they prove it by publishing Token in a public gist of that
+// account before ExpiresAt.
+type GithubChallenge struct {
+	UserID    int       `gorm:"primaryKey;autoIncrement:false" json:"userId"`
+	Handle    string    `gorm:"size:39" json:"handle"`
+	Token     string    `gorm:"size:64" json:"token"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// GithubVerified reports whether the user proved they own the Github account
+// their profile links to. Linking another account undoes it.
+func (u User) GithubVerified() bool {
+	return u.Github != "" && u.VerifiedGithub == u.Github
+}
@@ -0,0 +1,85 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Privilege is something users can do once their UserScore reaches Reputation.
+type Privilege struct {
+	Name       string `json:"name"`
+	Reputation int    `json:"reputation"`
+}
+
+// DefaultPrivileges are the privileges used when no others are configured.
+var DefaultPrivileges = []Privilege{
+	{Name: "create_posts", Reputation: 1},
+	{Name: "vote_up", Reputation: 15},
+	{Name: "flag_posts", Reputation: 15},
+	{Name: "comment", Reputation: 50},
+	{Name: "vote_down", Reputation: 125},
+	{Name: "edit", Reputation: 2000},
+	{Name: "close_questions", Reputation: 3000},
+	{Name: "moderator_tools", Reputation: 10000},
+}
+
+// ParsePrivileges parses privileges written as comma-separated name=reputation
+// pairs, e.g. "comment=50,vote_down=125,edit=2000".
+func ParsePrivileges(raw string) ([]Privilege, error) {
+	var privileges []Privilege
+	seen := make(map[string]bool)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, fmt.Errorf("privilege %q is not name=reputation", pair)
+		}
+		reputation, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || reputation < 0 {
+			return nil, fmt.Errorf("privilege %q needs a reputation of 0 or more", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("privilege %q is listed twice", name)
+		}
+		seen[name] = true
+		privileges = append(privileges, Privilege{Name: name, Reputation: reputation})
+	}
+	if len(privileges) == 0 {
+		return nil, fmt.Errorf("no privileges in %q", raw)
+	}
+	sort.SliceStable(privileges, func(i, j int) bool {
+		return privileges[i].Reputation < privileges[j].Reputation
+	})
+	return privileges, nil
+}
+
+// NextPrivilege is a privilege a user doesn't have yet, and how much more
+// reputation they need for it.
+type NextPrivilege struct {
+	Privilege
+	Remaining int `json:"remaining"`
+}
+
+// Privileges are what a user with UserScore can and cannot do yet. Granted
+// and Next are ordered by the reputation they need.
+type Privileges struct {
+	UserID    int             `json:"userId"`
+	UserScore int             `json:"userScore"`
+	Granted   []Privilege     `json:"granted"`
+	Next      []NextPrivilege `json:"next"`
+}
+
+// PrivilegeCheck is whether a user has one privilege.
+type PrivilegeCheck struct {
+	UserID    int       `json:"userId"`
+	Privilege Privilege `json:"privilege"`
+	Granted   bool      `json:"granted"`
+	// Remaining is how much more reputation the user needs, or 0 if Granted.
+	Remaining int `json:"remaining"`
+}
@@ -0,0 +1,298 @@
+package user
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// ProfileFieldType is the kind of value a ProfileField holds.
+type ProfileFieldType string
+
+const (
+	ProfileFieldText    ProfileFieldType = "text"
+	ProfileFieldNumber  ProfileFieldType = "number"
+	ProfileFieldBoolean ProfileFieldType = "boolean"
+	// ProfileFieldDate values are dates written as 2006-01-02.
+	ProfileFieldDate ProfileFieldType = "date"
+	// ProfileFieldURL values are http or https URLs.
+	ProfileFieldURL ProfileFieldType = "url"
+	// ProfileFieldChoice values are one of the field's Choices.
+	ProfileFieldChoice ProfileFieldType = "choice"
+)
+
+// ProfileFieldVisibility is who, besides the user and admins, sees a user's
+// value for a ProfileField.
+type ProfileFieldVisibility string
+
+const (
+	// ProfileFieldPublic values are shown to everyone.
+	ProfileFieldPublic ProfileFieldVisibility = "public"
+	// ProfileFieldPrivate values are only shown to the user and admins.
+	ProfileFieldPrivate ProfileFieldVisibility = "private"
+)
+
+// MaxProfileFieldValueLength is the longest value, in characters, a
+// ProfileField can hold.
+const MaxProfileFieldValueLength = 255
+
+// profileFieldName is what the names of profile fields look like, so they can
+// go in query parameters as they are.
+var profileFieldName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+// ProfileField is an extra field admins add to user profiles, such as
+// location or pronouns. Users' values for it are ProfileFieldValues.
+//
+// The rules after Visibility constrain the values users can set: MinLength,
+// MaxLength and Pattern those of text fields, Min and Max those of number
+// fields. Changing them leaves values set before as they are.
+type ProfileField struct {
+	Name  string `gorm:"primaryKey;size:64" json:"name"`
+	Label string `gorm:"size:100;not null" json:"label"`
+	// Position orders the fields in forms, lowest first.
+	Position int              `gorm:"not null" json:"position"`
+	Type     ProfileFieldType `gorm:"size:16;not null" json:"type"`
+	// Required fields cannot be left out when users set their profile
+	// fields. Users who never set them are not asked to.
+	Required   bool                   `gorm:"not null" json:"required"`
+	Visibility ProfileFieldVisibility `gorm:"size:16;not null" json:"visibility"`
+
+	MinLength int       `gorm:"not null" json:"minLength,omitempty"`
+	MaxLength int       `gorm:"not null" json:"maxLength,omitempty"`
+	Pattern   string    `gorm:"size:255;not null" json:"pattern,omitempty"`
+	Min       *float64  `json:"min,omitempty"`
+	Max       *float64  `json:"max,omitempty"`
+	Choices   Choices   `gorm:"type:text" json:"choices,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Choices are the values a choice field allows. They are stored as JSON.
+type Choices []string
+
+// Value implements driver.Valuer.
+func (c Choices) Value() (driver.Value, error) {
+	if c == nil {
+		return "[]", nil
+	}
+	raw, err := json.Marshal(c)
+	return string(raw), err
+}
+
+// Scan implements sql.Scanner.
+func (c *Choices) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Choices", src)
+	}
+	var choices []string
+	if err := json.Unmarshal(raw, &choices); err != nil {
+		return err
+	}
+	if len(choices) == 0 {
+		choices = nil
+	}
+	*c = choices
+	return nil
+}
+
+// Validate checks that f is a field users can hold values for, defaulting
+// its visibility to public.
+func (f *ProfileField) Validate() error {
+	if !profileFieldName.MatchString(f.Name) {
+		return domainerr.Validation("profile field names are 1 to 64 lowercase letters, digits and underscores, starting with a letter")
+	}
+	f.Label = strings.TrimSpace(f.Label)
+	if f.Label == "" || utf8.RuneCountInString(f.Label) > 100 {
+		return domainerr.Validation("profile field labels are 1 to 100 characters")
+	}
+	switch f.Visibility {
+	case "":
+		f.Visibility = ProfileFieldPublic
+	case ProfileFieldPublic, ProfileFieldPrivate:
+	default:
+		return domainerr.Validation("profile field visibility must be %s or %s", ProfileFieldPublic, ProfileFieldPrivate)
+	}
+
+	switch f.Type {
+	case ProfileFieldText, ProfileFieldNumber, ProfileFieldBoolean, ProfileFieldDate, ProfileFieldURL, ProfileFieldChoice:
+	default:
+		return domainerr.Validation("profile field type must be one of: text, number, boolean, date, url, choice")
+	}
+	if f.Type != ProfileFieldText && (f.MinLength != 0 || f.MaxLength != 0 || f.Pattern != "") {
+		return domainerr.Validation("only text fields take minLength, maxLength and pattern")
+	}
+	if f.Type != ProfileFieldNumber && (f.Min != nil || f.Max != nil) {
+		return domainerr.Validation("only number fields take min and max")
+	}
+	if f.Type != ProfileFieldChoice && len(f.Choices) > 0 {
+		return domainerr.Validation("only choice fields take choices")
+	}
+
+	if f.MinLength < 0 || f.MaxLength < 0 || f.MaxLength > MaxProfileFieldValueLength ||
+		(f.MaxLength != 0 && f.MinLength > f.MaxLength) {
+		return domainerr.Validation("minLength and maxLength must be between 0 and %d, with minLength at most maxLength", MaxProfileFieldValueLength)
+	}
+	if f.Pattern != "" {
+		if _, err := f.compiledPattern(); err != nil {
+			return domainerr.Wrap(domainerr.KindValidation, err, "pattern is not a valid regular expression")
+		}
+	}
+	if (f.Min != nil && !finite(*f.Min)) || (f.Max != nil && !finite(*f.Max)) ||
+		(f.Min != nil && f.Max != nil && *f.Min > *f.Max) {
+		return domainerr.Validation("min and max must be numbers, with min at most max")
+	}
+	if f.Type == ProfileFieldChoice {
+		if len(f.Choices) == 0 {
+			return domainerr.Validation("choice fields need at least one choice")
+		}
+		seen := make(map[string]bool, len(f.Choices))
+		for i, choice := range f.Choices {
+			choice = strings.TrimSpace(choice)
+			key := strings.ToLower(choice)
+			if choice == "" || utf8.RuneCountInString(choice) > MaxProfileFieldValueLength || seen[key] {
+				return domainerr.Validation("choices must be distinct and 1 to %d characters", MaxProfileFieldValueLength)
+			}
+			seen[key] = true
+			f.Choices[i] = choice
+		}
+	}
+	return nil
+}
+
+// Normalize checks that value is one f allows and returns it in the form it
+// is stored and matched in: trimmed, numbers and booleans in their shortest
+// form and choices as the field spells them.
+func (f *ProfileField) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", domainerr.Validation("%s cannot be empty", f.Name)
+	}
+	if utf8.RuneCountInString(value) > MaxProfileFieldValueLength {
+		return "", domainerr.Validation("%s can be at most %d characters", f.Name, MaxProfileFieldValueLength)
+	}
+	switch f.Type {
+	case ProfileFieldText:
+		n := utf8.RuneCountInString(value)
+		if n < f.MinLength || (f.MaxLength != 0 && n > f.MaxLength) {
+			return "", domainerr.Validation("%s must be between %d and %d characters", f.Name, f.MinLength, f.maxLength())
+		}
+		if f.Pattern != "" {
+			pattern, err := f.compiledPattern()
+			if err != nil {
+				return "", err
+			}
+			if !pattern.MatchString(value) {
+				return "", domainerr.Validation("%s is not in the expected format", f.Name)
+			}
+		}
+		return value, nil
+	case ProfileFieldNumber:
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || !finite(n) {
+			return "", domainerr.Validation("%s must be a number", f.Name)
+		}
+		if (f.Min != nil && n < *f.Min) || (f.Max != nil && n > *f.Max) {
+			return "", domainerr.Validation("%s must be between %s and %s", f.Name, bound(f.Min, "-∞"), bound(f.Max, "∞"))
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case ProfileFieldBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", domainerr.Validation("%s must be true or false", f.Name)
+		}
+		return strconv.FormatBool(b), nil
+	case ProfileFieldDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return "", domainerr.Validation("%s must be a date written as YYYY-MM-DD", f.Name)
+		}
+		return value, nil
+	case ProfileFieldURL:
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", domainerr.Validation("%s must be an http or https URL", f.Name)
+		}
+		return value, nil
+	case ProfileFieldChoice:
+		for _, choice := range f.Choices {
+			if strings.EqualFold(choice, value) {
+				return choice, nil
+			}
+		}
+		return "", domainerr.Validation("%s must be one of: %s", f.Name, strings.Join(f.Choices, ", "))
+	default:
+		return "", fmt.Errorf("profile field %s has unknown type %q", f.Name, f.Type)
+	}
+}
+
+// compiledPattern returns Pattern compiled to match whole values.
+func (f *ProfileField) compiledPattern() (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + f.Pattern + `)$`)
+}
+
+func (f *ProfileField) maxLength() int {
+	if f.MaxLength == 0 {
+		return MaxProfileFieldValueLength
+	}
+	return f.MaxLength
+}
+
+func finite(n float64) bool {
+	return !math.IsNaN(n) && !math.IsInf(n, 0)
+}
+
+func bound(n *float64, none string) string {
+	if n == nil {
+		return none
+	}
+	return strconv.FormatFloat(*n, 'f', -1, 64)
+}
+
+// UnknownProfileField is the error for naming a profile field that was never
+// defined.
+func UnknownProfileField(name string) error {
+	return domainerr.Validation("unknown profile field %q", name)
+}
+
+// ProfileFieldValue is a user's value for the ProfileField named Field. Its
+// index serves filtering users by value.
+type ProfileFieldValue struct {
+	UserID int    `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	Field  string `gorm:"primaryKey;size:64;index:idx_profile_field_values_match,priority:1" json:"field"`
+	Value  string `gorm:"size:255;not null;index:idx_profile_field_values_match,priority:2" json:"value"`
+	// Visibility is the field's, loaded along with the value.
+	Visibility ProfileFieldVisibility `gorm:"-" json:"-"`
+}
+
+// profileFieldMap returns the values of u's profile fields, by field name,
+// leaving out private ones unless private is set. It is nil if there are none.
+func (u User) profileFieldMap(private bool) map[string]string {
+	var fields map[string]string
+	for _, value := range u.ProfileFields {
+		if value.Visibility != ProfileFieldPublic && !private {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string, len(u.ProfileFields))
+		}
+		fields[value.Field] = value.Value
+	}
+	return fields
+}
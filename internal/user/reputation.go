@@ -0,0 +1,32 @@
+package user
+
+import "time"
+
+// ReputationEvent is an entry in the append-only ledger of reputation a user
+// gained or lost. Other services post them, and a user's UserScore is the sum
+// of their Deltas.
+type ReputationEvent struct {
+	ID     int `json:"id"`
+	UserID int `gorm:"index;not null" json:"userId"`
+	// Source is the service that posted the event, such as "questions".
+	Source string `gorm:"size:64;not null;uniqueIndex:idx_reputation_events_idempotency" json:"source"`
+	// IdempotencyKey identifies the event to its Source, which can safely
+	// post it again: only the first post counts.
+	IdempotencyKey string `gorm:"size:127;not null;uniqueIndex:idx_reputation_events_idempotency" json:"idempotencyKey"`
+	// Reason says why, such as "answer_upvoted".
+	Reason string `gorm:"size:64;not null" json:"reason"`
+	Delta  int    `gorm:"not null" json:"delta"`
+	// ReferenceType and ReferenceID point at what the event is about, such
+	// as the answer that was upvoted.
+	ReferenceType string    `gorm:"size:32" json:"referenceType,omitempty"`
+	ReferenceID   int       `json:"referenceId,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// ReputationPage is one page of a user's reputation events, newest first.
+type ReputationPage struct {
+	Events  []ReputationEvent `json:"events"`
+	Total   int64             `json:"total"`
+	Page    int               `json:"page"`
+	PerPage int               `json:"perPage"`
+}
@@ -0,0 +1,40 @@
+package user
+
+// SearchQuery describes a full-text search over user profiles.
+// Page is 1-based.
+type SearchQuery struct {
+	Query   string
+	Page    int
+	PerPage int
+	// Viewer is the ID of the user searching, if any. Users who blocked them
+	// are left out of the results and the Total.
+	Viewer int
+	// SearchHidden also searches the workplaces users hide, for admins.
+	// Otherwise only the Viewer's own hidden workplace is searched.
+	SearchHidden bool
+}
+
+// Offset returns the number of results to skip for the requested page.
+func (q SearchQuery) Offset() int {
+	if q.Page < 1 {
+		return 0
+	}
+	return (q.Page - 1) * q.PerPage
+}
+
+// SearchResult is a single ranked match. Highlights maps a field name
+// (username, bio, profession, workplace) to a snippet with the matched
+// terms wrapped in <em> tags.
+type SearchResult struct {
+	User       User              `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights,omitempty"`
+}
+
+// SearchPage is one page of search results ordered by descending Score.
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Total   int64          `json:"total"`
+	Page    int            `json:"page"`
+	PerPage int            `json:"perPage"`
+}
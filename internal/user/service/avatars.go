@@ -0,0 +1,131 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// errNoAvatarStore is returned when uploading avatars without a BlobStore
+// configured with WithAvatarStore.
+var errNoAvatarStore = errors.New("no avatar store is configured")
+
+// WithAvatarStore sets where the files of uploaded avatars are kept. Without
+// one, avatars cannot be uploaded and every user has an identicon.
+func WithAvatarStore(blobs avatar.BlobStore) Option {
+	return func(s *service) {
+		s.avatarStore = blobs
+	}
+}
+
+// WithAvatarProcessor sets how uploaded avatars are checked and which
+// variants are made of them, replacing avatar.NewProcessor().
+func WithAvatarProcessor(processor *avatar.Processor) Option {
+	return func(s *service) {
+		s.avatars = processor
+	}
+}
+
+// SetAvatar makes the picture data a user's avatar: it is checked and
+// processed into variants, which are stored before the user is pointed at
+// them. The files of the avatar it replaces are then removed.
+func (s *service) SetAvatar(ctx context.Context, id int, data []byte) (user.User, error) {
+	if s.avatarStore == nil {
+		return user.User{}, errNoAvatarStore
+	}
+	current, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	processed, err := s.avatars.Process(data)
+	if err != nil {
+		return user.User{}, err
+	}
+	// Files stored for an avatar the user is not pointed at are removed
+	// again, unless the user already had that very avatar.
+	discard := func() {
+		if current.AvatarHash != processed.Hash {
+			s.removeAvatarFiles(ctx, id, processed.Hash)
+		}
+	}
+	for _, v := range processed.Variants {
+		if err := s.avatarStore.Put(ctx, avatar.Key(id, processed.Hash, v.Size, v.Format), v.Data); err != nil {
+			log.Printf("SERVICE ERROR: %s", err.Error())
+			discard()
+			return user.User{}, err
+		}
+	}
+	updated, replaced, err := s.Store.UpdateAvatar(ctx, id, processed.Hash)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		discard()
+		return user.User{}, err
+	}
+	// The hash the store replaced, rather than the one read above, so that of
+	// two uploads racing, the one that lost has its files removed too.
+	if replaced != processed.Hash {
+		s.removeAvatarFiles(ctx, id, replaced)
+	}
+	return updated, nil
+}
+
+// DeleteAvatar removes a user's avatar, leaving them with their identicon.
+func (s *service) DeleteAvatar(ctx context.Context, id int) (user.User, error) {
+	current, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if current.AvatarHash == "" {
+		return current, nil
+	}
+	updated, replaced, err := s.Store.UpdateAvatar(ctx, id, "")
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.User{}, err
+	}
+	s.removeAvatarFiles(ctx, id, replaced)
+	return updated, nil
+}
+
+// GetAvatar returns a user's avatar in format at the smallest stored size of
+// at least size. Users who have not uploaded one, or whose files are
+// missing, get their identicon at that size instead.
+func (s *service) GetAvatar(ctx context.Context, id, size int, format avatar.Format) (avatar.Variant, error) {
+	usr, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return avatar.Variant{}, err
+	}
+	size = s.avatars.Fit(size)
+	if usr.AvatarHash != "" && s.avatarStore != nil {
+		data, err := s.avatarStore.Get(ctx, avatar.Key(id, usr.AvatarHash, size, format))
+		if err == nil {
+			return avatar.Variant{Size: size, Format: format, Data: data}, nil
+		}
+		if !errors.Is(err, avatar.ErrBlobNotFound) {
+			log.Printf("SERVICE ERROR: %s", err.Error())
+			return avatar.Variant{}, err
+		}
+		log.Printf("Avatar %s of user %d is missing, serving their identicon.", usr.AvatarHash, id)
+	}
+	data, err := avatar.Encode(avatar.Identicon(usr.Email, size), format)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return avatar.Variant{}, err
+	}
+	return avatar.Variant{Size: size, Format: format, Data: data}, nil
+}
+
+// removeAvatarFiles deletes the variants of the avatar with hash, if any,
+// in whatever sizes they were made. The user does not point at them, so
+// failures are only logged.
+func (s *service) removeAvatarFiles(ctx context.Context, id int, hash string) {
+	if hash == "" || s.avatarStore == nil {
+		return
+	}
+	if err := s.avatarStore.DeletePrefix(ctx, avatar.KeyPrefix(id, hash)); err != nil {
+		log.Printf("Failed to delete avatar files of user %d: %s", id, err)
+	}
+}
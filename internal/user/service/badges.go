@@ -0,0 +1,58 @@
+package user
+
+import (
+	"context"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/badges"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// GetBadges returns every badge users can earn.
+func (s *service) GetBadges(ctx context.Context) ([]user.Badge, error) {
+	return s.badges.Badges(), nil
+}
+
+// GetAwards returns the badges a user was awarded, newest first.
+func (s *service) GetAwards(ctx context.Context, id int) ([]user.Award, error) {
+	if _, err := s.Store.GetUserByID(ctx, id); err != nil {
+		return []user.Award{}, err
+	}
+	awards, err := s.Store.GetAwards(ctx, []int{id})
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.Award{}, err
+	}
+	return awards, nil
+}
+
+// GetAwardsByUserIDs returns the awards of each of the given users, newest
+// first, without checking that they exist.
+func (s *service) GetAwardsByUserIDs(ctx context.Context, ids []int) (map[int][]user.Award, error) {
+	awards, err := s.Store.GetAwards(ctx, ids)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	byUser := make(map[int][]user.Award, len(ids))
+	for _, id := range ids {
+		byUser[id] = []user.Award{}
+	}
+	for _, award := range awards {
+		byUser[award.UserID] = append(byUser[award.UserID], award)
+	}
+	return byUser, nil
+}
+
+// award stores the badges event earns its user. What earned them has already
+// been saved, so failing to is only logged; the badges are awarded the next
+// time something earns them.
+func (s *service) award(ctx context.Context, event badges.Event) {
+	awards := s.badges.Evaluate(event)
+	if len(awards) == 0 {
+		return
+	}
+	if _, err := s.Store.AddAwards(ctx, awards); err != nil {
+		log.Printf("SERVICE ERROR: awarding badges to user %d: %s", event.User.ID, err.Error())
+	}
+}
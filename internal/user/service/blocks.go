@@ -0,0 +1,189 @@
+package user
+
+import (
+	"context"
+	"log"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// MaxBlockChecks is the most checks CheckBlocks answers at once.
+const MaxBlockChecks = 500
+
+type viewerKey struct{}
+
+// WithViewer returns a context under which users are looked up on behalf of
+// the user with id: users who blocked them are not found.
+func WithViewer(ctx context.Context, id int) context.Context {
+	return context.WithValue(ctx, viewerKey{}, id)
+}
+
+// viewerFrom returns the ID set by WithViewer, or 0 if there is none.
+func viewerFrom(ctx context.Context) int {
+	id, _ := ctx.Value(viewerKey{}).(int)
+	return id
+}
+
+// hideFromViewer returns NotFound if usr blocked the viewer set on ctx.
+func (s *service) hideFromViewer(ctx context.Context, usr user.User) error {
+	hidden, err := s.blockersOfViewer(ctx, []int{usr.ID})
+	if err != nil {
+		return err
+	}
+	if hidden[usr.ID] {
+		return domainerr.NotFound("user not found")
+	}
+	return nil
+}
+
+// withoutBlockers returns users without those who blocked the viewer set on
+// ctx.
+func (s *service) withoutBlockers(ctx context.Context, users []user.User) ([]user.User, error) {
+	ids := make([]int, len(users))
+	for i, usr := range users {
+		ids[i] = usr.ID
+	}
+	hidden, err := s.blockersOfViewer(ctx, ids)
+	if err != nil {
+		return []user.User{}, err
+	}
+	if len(hidden) == 0 {
+		return users, nil
+	}
+	visible := make([]user.User, 0, len(users))
+	for _, usr := range users {
+		if !hidden[usr.ID] {
+			visible = append(visible, usr)
+		}
+	}
+	return visible, nil
+}
+
+// blockersOfViewer returns which of the users with ids blocked the viewer set
+// on ctx. Without a viewer, none did.
+func (s *service) blockersOfViewer(ctx context.Context, ids []int) (map[int]bool, error) {
+	viewer := viewerFrom(ctx)
+	if viewer == 0 {
+		return nil, nil
+	}
+	pairs := make([]user.Block, 0, len(ids))
+	for _, id := range ids {
+		if id != viewer {
+			pairs = append(pairs, user.Block{BlockerID: id, BlockedID: viewer})
+		}
+	}
+	blocks, err := s.Store.FindBlocks(ctx, pairs)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	blockers := make(map[int]bool, len(blocks))
+	for _, b := range blocks {
+		blockers[b.BlockerID] = true
+	}
+	return blockers, nil
+}
+
+// BlockUser makes blockerID block blockedID, ending any follows between them.
+// It returns false if they already did.
+func (s *service) BlockUser(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	if blockerID == blockedID {
+		return false, ErrSelfRelation
+	}
+	blocked, err := s.Store.BlockUser(ctx, blockerID, blockedID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return blocked, nil
+}
+
+// UnblockUser undoes BlockUser. It returns false if there was no block.
+func (s *service) UnblockUser(ctx context.Context, blockerID, blockedID int) (bool, error) {
+	unblocked, err := s.Store.UnblockUser(ctx, blockerID, blockedID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return unblocked, nil
+}
+
+// GetBlockedUsers returns a page of the users a user blocked, most recent
+// first.
+func (s *service) GetBlockedUsers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error) {
+	// Only the user sees their blocks, including of users who blocked them back.
+	ctx = WithViewer(ctx, 0)
+	return s.userPage(ctx, id, cursor, limit, func(ctx context.Context, after user.Cursor, limit int) ([]edge, error) {
+		blocks, err := s.Store.GetBlocks(ctx, id, after, limit)
+		edges := make([]edge, len(blocks))
+		for i, b := range blocks {
+			edges[i] = edge{userID: b.BlockedID, createdAt: b.CreatedAt}
+		}
+		return edges, err
+	})
+}
+
+// CheckBlocks answers whether each check's user is blocked by the other, in
+// one query however many checks there are.
+func (s *service) CheckBlocks(ctx context.Context, checks []user.BlockCheck) ([]user.BlockCheck, error) {
+	if len(checks) > MaxBlockChecks {
+		return nil, domainerr.Validation("at most %d blocks can be checked at once", MaxBlockChecks)
+	}
+	pairs := make([]user.Block, len(checks))
+	for i, check := range checks {
+		pairs[i] = user.Block{BlockerID: check.BlockedBy, BlockedID: check.UserID}
+	}
+	blocks, err := s.Store.FindBlocks(ctx, pairs)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	exists := make(map[user.Block]bool, len(blocks))
+	for _, b := range blocks {
+		exists[user.Block{BlockerID: b.BlockerID, BlockedID: b.BlockedID}] = true
+	}
+	answered := make([]user.BlockCheck, len(checks))
+	for i, check := range checks {
+		check.Blocked = exists[pairs[i]]
+		answered[i] = check
+	}
+	return answered, nil
+}
+
+// MuteUser makes muterID mute mutedID. It returns false if they already did.
+func (s *service) MuteUser(ctx context.Context, muterID, mutedID int) (bool, error) {
+	if muterID == mutedID {
+		return false, ErrSelfRelation
+	}
+	muted, err := s.Store.MuteUser(ctx, muterID, mutedID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return muted, nil
+}
+
+// UnmuteUser undoes MuteUser. It returns false if there was no mute.
+func (s *service) UnmuteUser(ctx context.Context, muterID, mutedID int) (bool, error) {
+	unmuted, err := s.Store.UnmuteUser(ctx, muterID, mutedID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return unmuted, nil
+}
+
+// GetMutedUsers returns a page of the users a user muted, most recent first.
+func (s *service) GetMutedUsers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error) {
+	// As for blocks, show muted users who blocked the viewer.
+	ctx = WithViewer(ctx, 0)
+	return s.userPage(ctx, id, cursor, limit, func(ctx context.Context, after user.Cursor, limit int) ([]edge, error) {
+		mutes, err := s.Store.GetMutes(ctx, id, after, limit)
+		edges := make([]edge, len(mutes))
+		for i, m := range mutes {
+			edges[i] = edge{userID: m.MutedID, createdAt: m.CreatedAt}
+		}
+		return edges, err
+	})
+}
@@ -0,0 +1,183 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/repository"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+const (
+	// defaultFollowsLimit and maxFollowsLimit bound the pages of followers,
+	// following, blocked and muted users.
+	defaultFollowsLimit = 20
+	maxFollowsLimit     = 100
+)
+
+// ErrSelfRelation is returned when users try to follow, block or mute
+// themselves.
+var ErrSelfRelation = domainerr.Validation("users cannot follow, block or mute themselves")
+
+// FollowUser makes followerID follow followeeID. It returns false if they
+// already did.
+func (s *service) FollowUser(ctx context.Context, followerID, followeeID int) (bool, error) {
+	if followerID == followeeID {
+		return false, ErrSelfRelation
+	}
+	followed, err := s.Store.Follow(ctx, followerID, followeeID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return followed, nil
+}
+
+// UnfollowUser makes followerID stop following followeeID. It returns false
+// if they didn't.
+func (s *service) UnfollowUser(ctx context.Context, followerID, followeeID int) (bool, error) {
+	unfollowed, err := s.Store.Unfollow(ctx, followerID, followeeID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return unfollowed, nil
+}
+
+// GetFollowers returns a page of the users following a user, most recent
+// follows first. cursor is the NextCursor of the previous page, or empty for
+// the first.
+func (s *service) GetFollowers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error) {
+	return s.userPage(ctx, id, cursor, limit, func(ctx context.Context, after user.Cursor, limit int) ([]edge, error) {
+		follows, err := s.Store.GetFollowers(ctx, id, after, limit)
+		edges := make([]edge, len(follows))
+		for i, f := range follows {
+			edges[i] = edge{userID: f.FollowerID, createdAt: f.CreatedAt}
+		}
+		return edges, err
+	})
+}
+
+// GetFollowing returns a page of the users a user follows, most recent
+// follows first.
+func (s *service) GetFollowing(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error) {
+	return s.userPage(ctx, id, cursor, limit, func(ctx context.Context, after user.Cursor, limit int) ([]edge, error) {
+		follows, err := s.Store.GetFollowing(ctx, id, after, limit)
+		edges := make([]edge, len(follows))
+		for i, f := range follows {
+			edges[i] = edge{userID: f.FolloweeID, createdAt: f.CreatedAt}
+		}
+		return edges, err
+	})
+}
+
+// edge is a follow, block or mute as paging through them sees it: the user
+// on the other end, and when it was made.
+type edge struct {
+	userID    int
+	createdAt time.Time
+}
+
+// userPage loads a page of the edges list returns for the user with id, and
+// the users on their other end. Users who have been deleted since, or who
+// blocked the viewer, are left out.
+func (s *service) userPage(
+	ctx context.Context,
+	id int,
+	cursor string,
+	limit int,
+	list func(ctx context.Context, after user.Cursor, limit int) ([]edge, error),
+) (user.UserPage, error) {
+	var after user.Cursor
+	if cursor != "" {
+		var err error
+		if after, err = user.ParseCursor(cursor); err != nil {
+			return user.UserPage{}, domainerr.Validation("cursor is not valid")
+		}
+	}
+	if limit < 1 {
+		limit = defaultFollowsLimit
+	} else if limit > maxFollowsLimit {
+		limit = maxFollowsLimit
+	}
+	// The user is only needed to tell a missing user from one without
+	// follows, so any column will do.
+	if _, err := s.GetUserByID(repository.WithFields(ctx, "id"), id); err != nil {
+		return user.UserPage{}, err
+	}
+
+	// One more than asked for tells whether there is another page.
+	edges, err := list(ctx, after, limit+1)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.UserPage{}, err
+	}
+	page := user.UserPage{Users: []user.User{}}
+	if len(edges) > limit {
+		edges = edges[:limit]
+		last := edges[limit-1]
+		page.NextCursor = user.Cursor{CreatedAt: last.createdAt, UserID: last.userID}.String()
+	}
+	if len(edges) == 0 {
+		return page, nil
+	}
+
+	ids := make([]int, len(edges))
+	for i, e := range edges {
+		ids[i] = e.userID
+	}
+	users, err := s.Store.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.UserPage{}, err
+	}
+	hidden, err := s.blockersOfViewer(ctx, ids)
+	if err != nil {
+		return user.UserPage{}, err
+	}
+	byID := make(map[int]user.User, len(users))
+	for _, usr := range users {
+		byID[usr.ID] = usr
+	}
+	for _, id := range ids {
+		if usr, ok := byID[id]; ok && !hidden[id] {
+			page.Users = append(page.Users, usr)
+		}
+	}
+	return page, nil
+}
+
+// GetRelationship returns how the user with id relates to the one with otherID.
+func (s *service) GetRelationship(ctx context.Context, id, otherID int) (user.Relationship, error) {
+	users, err := s.Store.GetUsersByIDs(repository.WithFields(ctx, "id"), []int{id, otherID})
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.Relationship{}, err
+	}
+	relationship := user.Relationship{UserID: id, OtherID: otherID}
+	if id == otherID {
+		if len(users) == 0 {
+			return user.Relationship{}, domainerr.NotFound("user not found")
+		}
+		return relationship, nil
+	}
+	if len(users) < 2 {
+		return user.Relationship{}, domainerr.NotFound("user not found")
+	}
+	follows, err := s.Store.GetFollowsBetween(ctx, id, otherID)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.Relationship{}, err
+	}
+	for _, f := range follows {
+		if f.FollowerID == id {
+			relationship.Following = true
+		} else {
+			relationship.FollowedBy = true
+		}
+	}
+	relationship.Mutual = relationship.Following && relationship.FollowedBy
+	return relationship, nil
+}
@@ -0,0 +1,118 @@
+package user
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// snippetContext is how many bytes of text to keep on either side of the first match.
+const snippetContext = 60
+
+type span struct {
+	start, end int
+}
+
+// searchTerms splits a search query into lower-cased words.
+func searchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// wordSpans returns the byte ranges of every word in text.
+func wordSpans(text string) []span {
+	var spans []span
+	start := -1
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			spans = append(spans, span{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		spans = append(spans, span{start, len(text)})
+	}
+	return spans
+}
+
+// highlight returns an HTML-escaped snippet of text around the first word
+// starting with one of terms, with every such word wrapped in <em> tags.
+// It returns "" when nothing matches.
+func highlight(text string, terms []string) string {
+	var matches []span
+	for _, w := range wordSpans(text) {
+		word := strings.ToLower(text[w.start:w.end])
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches = append(matches, w)
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+
+	from := matches[0].start - snippetContext
+	if from < 0 {
+		from = 0
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	to := matches[0].end + snippetContext
+	if to > len(text) {
+		to = len(text)
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("…")
+	}
+	pos := from
+	for _, m := range matches {
+		if m.end > to {
+			break
+		}
+		b.WriteString(html.EscapeString(text[pos:m.start]))
+		b.WriteString("<em>")
+		b.WriteString(html.EscapeString(text[m.start:m.end]))
+		b.WriteString("</em>")
+		pos = m.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// highlights builds the highlight snippets for every searchable field of usr that matches terms.
+func highlights(usr user.User, terms []string) map[string]string {
+	fields := map[string]string{
+		"username":   usr.UserName,
+		"bio":        usr.Bio,
+		"profession": usr.Profession,
+		"workplace":  usr.WorkPlace,
+	}
+	result := map[string]string{}
+	for name, text := range fields {
+		if snippet := highlight(text, terms); snippet != "" {
+			result[name] = snippet
+		}
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// DefaultGithubChallengeTTL is how long users have to publish the token of a
+// Github verification when no other time is configured.
+const DefaultGithubChallengeTTL = 24 * time.Hour
+
+var (
+	// ErrNoGithub is returned when verifying the Github account of a user who
+	// has not linked one.
+	ErrNoGithub = domainerr.Validation("link a Github account before verifying it")
+	// ErrGithubAlreadyVerified is returned when verifying a Github account
+	// that already is.
+	ErrGithubAlreadyVerified = domainerr.Conflict("github is already verified")
+	// ErrGithubChallengeExpired is returned when completing a Github
+	// verification after its token stopped being accepted.
+	ErrGithubChallengeExpired = domainerr.Conflict("github verification expired, start a new one")
+	// ErrGithubTokenNotFound is returned when completing a Github verification
+	// whose token is not in any of the account's newest public gists.
+	ErrGithubTokenNotFound = domainerr.Validation("the verification token is not in any of the newest public gists of the Github account")
+)
+
+// GithubVerifier finds tokens in the public gists of a Github account.
+// *github.Verifier is one.
+type GithubVerifier interface {
+	HasToken(ctx context.Context, handle, token string) (bool, error)
+}
+
+// WithGithubVerifier sets how Github verifications are checked, replacing a
+// github.Verifier calling Github's API.
+func WithGithubVerifier(verifier GithubVerifier) Option {
+	return func(s *service) {
+		s.githubVerifier = verifier
+	}
+}
+
+// WithGithubChallengeTTL sets how long users have to publish the token of a
+// Github verification.
+func WithGithubChallengeTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.githubChallengeTTL = ttl
+	}
+}
+
+// normalizeLinks replaces the Github and Linkedin links of usr, if set, by
+// the handle and profile name they name.
+func normalizeLinks(usr *user.User) error {
+	var err error
+	if usr.Github != "" {
+		if usr.Github, err = canonical.GithubHandle(usr.Github); err != nil {
+			return err
+		}
+	}
+	if usr.Linkedin != "" {
+		if usr.Linkedin, err = canonical.LinkedinProfile(usr.Linkedin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeChangedLinks is normalizeLinks for the links of usr that differ
+// from those of current, so that users can send back links stored before
+// links were normalized, even ones that are no longer valid.
+func normalizeChangedLinks(usr *user.User, current user.User) error {
+	var err error
+	if usr.Github != "" && usr.Github != current.Github {
+		if usr.Github, err = canonical.GithubHandle(usr.Github); err != nil {
+			return err
+		}
+	}
+	if usr.Linkedin != "" && usr.Linkedin != current.Linkedin {
+		if usr.Linkedin, err = canonical.LinkedinProfile(usr.Linkedin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeFilterLinks is normalizeLinks for a Filter, so that a link finds
+// users however it is written. Links that are not valid are left alone and
+// match nobody.
+func normalizeFilterLinks(filter *user.Filter) {
+	if handle, err := canonical.GithubHandle(filter.Github); err == nil {
+		filter.Github = handle
+	}
+	if name, err := canonical.LinkedinProfile(filter.Linkedin); err == nil {
+		filter.Linkedin = name
+	}
+}
+
+// StartGithubVerification gives a user a token to publish in a public gist
+// of the Github account they link to, replacing any they were given before.
+func (s *service) StartGithubVerification(ctx context.Context, id int) (user.GithubChallenge, error) {
+	usr, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.GithubChallenge{}, err
+	}
+	if usr.Github == "" {
+		return user.GithubChallenge{}, ErrNoGithub
+	}
+	if usr.GithubVerified() {
+		return user.GithubChallenge{}, ErrGithubAlreadyVerified
+	}
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		return user.GithubChallenge{}, err
+	}
+	now := time.Now()
+	challenge := user.GithubChallenge{
+		UserID:    id,
+		Handle:    usr.Github,
+		Token:     "nuboverflow-verify-" + hex.EncodeToString(secret),
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.githubChallengeTTL),
+	}
+	if err := s.Store.SaveGithubChallenge(ctx, challenge); err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.GithubChallenge{}, err
+	}
+	return challenge, nil
+}
+
+// VerifyGithub completes a user's Github verification if the token they were
+// given is in a public gist of the account, marking it verified.
+func (s *service) VerifyGithub(ctx context.Context, id int) (user.User, error) {
+	challenge, err := s.Store.GetGithubChallenge(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return user.User{}, ErrGithubChallengeExpired
+	}
+	usr, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if usr.Github != challenge.Handle {
+		return user.User{}, user.ErrGithubChanged
+	}
+	found, err := s.githubVerifier.HasToken(ctx, challenge.Handle, challenge.Token)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.User{}, err
+	}
+	if !found {
+		return user.User{}, ErrGithubTokenNotFound
+	}
+	verified, err := s.Store.VerifyGithub(ctx, id, challenge.Handle)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.User{}, err
+	}
+	return verified, nil
+}
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"context"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// GetPrivileges returns the privileges a user has and the ones they are
+// still working towards.
+func (s *service) GetPrivileges(ctx context.Context, id int) (user.Privileges, error) {
+	usr, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.Privileges{}, err
+	}
+	privileges := user.Privileges{
+		UserID:    usr.ID,
+		UserScore: usr.UserScore,
+		Granted:   []user.Privilege{},
+		Next:      []user.NextPrivilege{},
+	}
+	for _, privilege := range s.privileges {
+		if usr.UserScore >= privilege.Reputation {
+			privileges.Granted = append(privileges.Granted, privilege)
+		} else {
+			privileges.Next = append(privileges.Next, user.NextPrivilege{
+				Privilege: privilege,
+				Remaining: privilege.Reputation - usr.UserScore,
+			})
+		}
+	}
+	return privileges, nil
+}
+
+// CheckPrivilege reports whether a user has the privilege called name, for
+// other services to gate what users do.
+func (s *service) CheckPrivilege(ctx context.Context, id int, name string) (user.PrivilegeCheck, error) {
+	privilege, ok := s.privilege(name)
+	if !ok {
+		return user.PrivilegeCheck{}, domainerr.NotFound("privilege %q does not exist", name)
+	}
+	usr, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.PrivilegeCheck{}, err
+	}
+	check := user.PrivilegeCheck{
+		UserID:    usr.ID,
+		Privilege: privilege,
+		Granted:   usr.UserScore >= privilege.Reputation,
+	}
+	if !check.Granted {
+		check.Remaining = privilege.Reputation - usr.UserScore
+	}
+	return check, nil
+}
+
+func (s *service) privilege(name string) (user.Privilege, bool) {
+	for _, privilege := range s.privileges {
+		if privilege.Name == name {
+			return privilege, true
+		}
+	}
+	return user.Privilege{}, false
+}
@@ -0,0 +1,138 @@
+package user
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// GetProfileFields returns the fields admins added to profiles, in the order
+// forms should show them.
+func (s *service) GetProfileFields(ctx context.Context) ([]user.ProfileField, error) {
+	fields, err := s.Store.GetProfileFields(ctx)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.ProfileField{}, err
+	}
+	return fields, nil
+}
+
+// SaveProfileField adds a field to profiles, or replaces the definition of the
+// one with its name. Values users already set are kept, even if they no
+// longer follow its rules.
+func (s *service) SaveProfileField(ctx context.Context, field user.ProfileField) (user.ProfileField, error) {
+	if err := field.Validate(); err != nil {
+		return user.ProfileField{}, err
+	}
+	saved, affected, err := s.Store.SaveProfileField(ctx, field)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.ProfileField{}, err
+	}
+	if len(affected) > 0 {
+		log.Printf("Profile field %s changed visibility for the values of %d users.", saved.Name, len(affected))
+	}
+	return saved, nil
+}
+
+// DeleteProfileField removes a field from profiles, along with every value
+// users set for it.
+func (s *service) DeleteProfileField(ctx context.Context, name string) error {
+	if _, err := s.Store.DeleteProfileField(ctx, name); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetProfileFields replaces a user's profile field values, by field name.
+// Each must be a value its field allows, and every required field must have
+// one. Empty values leave a field unset.
+func (s *service) SetProfileFields(ctx context.Context, id int, values map[string]string) (user.User, error) {
+	fields, err := s.profileFieldsByName(ctx)
+	if err != nil {
+		return user.User{}, err
+	}
+	normalized := make(map[string]string, len(values))
+	for _, name := range sortedKeys(values) {
+		field, ok := fields[name]
+		if !ok {
+			return user.User{}, user.UnknownProfileField(name)
+		}
+		if values[name] == "" {
+			continue
+		}
+		if normalized[name], err = field.Normalize(values[name]); err != nil {
+			return user.User{}, err
+		}
+	}
+	var missing []string
+	for name, field := range fields {
+		if _, ok := normalized[name]; field.Required && !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return user.User{}, domainerr.Validation("required profile fields are missing: %s", strings.Join(missing, ", "))
+	}
+	usr, err := s.Store.SetProfileFieldValues(ctx, id, normalized)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.User{}, err
+	}
+	return usr, nil
+}
+
+// normalizeFilterProfileFields checks that a Filter only names profile fields
+// that exist, and writes its values the way they are stored so that they
+// match however they were written. Values a field does not allow are left
+// alone and match nobody.
+func (s *service) normalizeFilterProfileFields(ctx context.Context, filter *user.Filter) error {
+	if len(filter.ProfileFields) == 0 {
+		return nil
+	}
+	fields, err := s.profileFieldsByName(ctx)
+	if err != nil {
+		return err
+	}
+	normalized := make(map[string]string, len(filter.ProfileFields))
+	for _, name := range sortedKeys(filter.ProfileFields) {
+		value := filter.ProfileFields[name]
+		field, ok := fields[name]
+		if !ok {
+			return user.UnknownProfileField(name)
+		}
+		if n, err := field.Normalize(value); err == nil {
+			value = n
+		}
+		normalized[name] = value
+	}
+	filter.ProfileFields = normalized
+	return nil
+}
+
+func (s *service) profileFieldsByName(ctx context.Context) (map[string]user.ProfileField, error) {
+	fields, err := s.Store.GetProfileFields(ctx)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	byName := make(map[string]user.ProfileField, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+	}
+	return byName, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunPurgeJob calls service.PurgeDeletedUsers every interval until ctx is done.
+func RunPurgeJob(ctx context.Context, service Service, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := service.PurgeDeletedUsers(ctx)
+			if err != nil {
+				log.Printf("Purge job failed: %s", err)
+				continue
+			}
+			if purged > 0 {
+				log.Printf("Purge job anonymized %d deleted users.", purged)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -1,34 +1,199 @@
 //go:generate mockgen -destination=user_mocks_test.go -package=user github.com/millbj92/nuboverflow-users/internal/user/service Service
+//go:generate mockgen -destination=store_mocks_test.go -package=user github.com/millbj92/nuboverflow-users/internal/repository Store
 package user
 
 import (
+	"context"
+	"errors"
 	"log"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/millbj92/nuboverflow-users/internal/avatar"
+	"github.com/millbj92/nuboverflow-users/internal/badges"
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/github"
 	"github.com/millbj92/nuboverflow-users/internal/repository"
 	"github.com/millbj92/nuboverflow-users/internal/user"
 )
 
 type Service interface {
-	GetAllUsers() ([]user.User, error)
-	GetUserByID(id int) (user.User, error)
-	GetUserByEmail(email string) (user.User, error)
-	CreateUser(user *user.User) (*user.User, error)
-	UpdateUser(user user.User) (user.User, error)
-	DeleteUser(id int) error
+	GetAllUsers(ctx context.Context) ([]user.User, error)
+	GetUserByID(ctx context.Context, id int) (user.User, error)
+	GetUsersByIDs(ctx context.Context, ids []int) (user.Batch, error)
+	GetUserByEmail(ctx context.Context, email string) (user.User, error)
+	GetUserByUserName(ctx context.Context, name string) (user.User, error)
+	FindUsers(ctx context.Context, filter user.Filter) ([]user.User, error)
+	SearchUsers(ctx context.Context, query user.SearchQuery) (user.SearchPage, error)
+	SuggestUsers(ctx context.Context, prefix string, limit int) ([]user.Suggestion, error)
+	CreateUser(ctx context.Context, user *user.User) (*user.User, error)
+	UpdateUser(ctx context.Context, user user.User) (user.User, error)
+	DeleteUser(ctx context.Context, id int) error
+	RestoreUser(ctx context.Context, id int) (user.User, error)
+	PurgeDeletedUsers(ctx context.Context) (int64, error)
+	UpdatePrivacy(ctx context.Context, id int, privacy user.Privacy) (user.User, error)
+	RenameUser(ctx context.Context, id int, name string) (user.User, error)
+	GetUserNameHistory(ctx context.Context, id int) ([]user.UserNameChange, error)
+	GetRenamedUser(ctx context.Context, oldName string) (user.User, error)
+	GetUserStats(ctx context.Context, ids []int) (map[int]user.Stats, error)
+	AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error)
+	GetReputationEvents(ctx context.Context, userID, page, perPage int) (user.ReputationPage, error)
+	RecalculateUserScores(ctx context.Context) (int, error)
+	GetPrivileges(ctx context.Context, id int) (user.Privileges, error)
+	CheckPrivilege(ctx context.Context, id int, name string) (user.PrivilegeCheck, error)
+	GetBadges(ctx context.Context) ([]user.Badge, error)
+	GetAwards(ctx context.Context, id int) ([]user.Award, error)
+	GetAwardsByUserIDs(ctx context.Context, ids []int) (map[int][]user.Award, error)
+	FollowUser(ctx context.Context, followerID, followeeID int) (bool, error)
+	UnfollowUser(ctx context.Context, followerID, followeeID int) (bool, error)
+	GetFollowers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error)
+	GetFollowing(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error)
+	GetRelationship(ctx context.Context, id, otherID int) (user.Relationship, error)
+	BlockUser(ctx context.Context, blockerID, blockedID int) (bool, error)
+	UnblockUser(ctx context.Context, blockerID, blockedID int) (bool, error)
+	GetBlockedUsers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error)
+	CheckBlocks(ctx context.Context, checks []user.BlockCheck) ([]user.BlockCheck, error)
+	MuteUser(ctx context.Context, muterID, mutedID int) (bool, error)
+	UnmuteUser(ctx context.Context, muterID, mutedID int) (bool, error)
+	GetMutedUsers(ctx context.Context, id int, cursor string, limit int) (user.UserPage, error)
+	SetAvatar(ctx context.Context, id int, data []byte) (user.User, error)
+	DeleteAvatar(ctx context.Context, id int) (user.User, error)
+	GetAvatar(ctx context.Context, id, size int, format avatar.Format) (avatar.Variant, error)
+	StartGithubVerification(ctx context.Context, id int) (user.GithubChallenge, error)
+	VerifyGithub(ctx context.Context, id int) (user.User, error)
+	GetProfileFields(ctx context.Context) ([]user.ProfileField, error)
+	SaveProfileField(ctx context.Context, field user.ProfileField) (user.ProfileField, error)
+	DeleteProfileField(ctx context.Context, name string) error
+	SetProfileFields(ctx context.Context, id int, values map[string]string) (user.User, error)
+	AddUserSkill(ctx context.Context, id int, name string) (bool, error)
+	RemoveUserSkill(ctx context.Context, id int, name string) (bool, error)
+	GetUserSkills(ctx context.Context, id int) ([]user.UserSkill, error)
+	GetSkillsByUserIDs(ctx context.Context, ids []int) (map[int][]user.UserSkill, error)
+	EndorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error)
+	UnendorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error)
+	GetSkills(ctx context.Context, prefix string, limit int) ([]user.SkillSummary, error)
+	GetTopUsersForSkill(ctx context.Context, name string, limit int) ([]user.SkilledUser, error)
 }
 
+const (
+	defaultSearchPerPage = 20
+	maxSearchPerPage     = 100
+
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+
+	// MaxBatchSize is the most users GetUsersByIDs looks up at once.
+	MaxBatchSize = 100
+
+	defaultReputationPerPage = 20
+	maxReputationPerPage     = 100
+)
+
+// DefaultRestoreWindow is how long a deleted user can be restored when no
+// other window is configured.
+const DefaultRestoreWindow = 30 * 24 * time.Hour
+
+const (
+	// DefaultRenameCooldown is how long a user has to wait between username
+	// changes when no other cooldown is configured.
+	DefaultRenameCooldown = 30 * 24 * time.Hour
+	// DefaultUserNameReservation is how long an old username stays reserved
+	// for its previous owner, and redirects to them, when no other period is
+	// configured.
+	DefaultUserNameReservation = 90 * 24 * time.Hour
+)
+
+var (
+	// ErrRestoreWindowPassed is returned when restoring a user deleted longer ago than the restore window.
+	ErrRestoreWindowPassed = domainerr.Conflict("restore window has passed")
+	// ErrUserNameChangeNotAllowed is returned when UpdateUser is asked to change a username.
+	ErrUserNameChangeNotAllowed = domainerr.Validation("usernames can only be changed by renaming the user")
+	// ErrUserNameReserved is returned when renaming to a username another user recently gave up.
+	ErrUserNameReserved = domainerr.Conflict("username was recently used by someone else and is still reserved")
+	// ErrIdempotencyKeyReused is returned when a reputation event reuses the
+	// idempotency key of a different event from the same source.
+	ErrIdempotencyKeyReused = domainerr.Conflict("idempotency key was already used for a different reputation event")
+)
+
 type service struct {
-	Store repository.Store
+	Store               repository.Store
+	restoreWindow       time.Duration
+	renameCooldown      time.Duration
+	userNameReservation time.Duration
+	privileges          []user.Privilege
+	badges              *badges.Engine
+	avatars             *avatar.Processor
+	avatarStore         avatar.BlobStore
+	githubVerifier      GithubVerifier
+	githubChallengeTTL  time.Duration
+}
+
+// Option configures optional behaviour of the service.
+type Option func(*service)
+
+// WithRestoreWindow sets how long after deletion a user can still be restored.
+func WithRestoreWindow(window time.Duration) Option {
+	return func(s *service) {
+		s.restoreWindow = window
+	}
+}
+
+// WithRenameCooldown sets how long a user has to wait between username changes.
+func WithRenameCooldown(cooldown time.Duration) Option {
+	return func(s *service) {
+		s.renameCooldown = cooldown
+	}
+}
+
+// WithUserNameReservation sets how long an old username stays reserved for
+// its previous owner and redirects to their new one.
+func WithUserNameReservation(reservation time.Duration) Option {
+	return func(s *service) {
+		s.userNameReservation = reservation
+	}
+}
+
+// WithPrivileges sets the privileges users unlock by reputation, replacing
+// user.DefaultPrivileges.
+func WithPrivileges(privileges []user.Privilege) Option {
+	return func(s *service) {
+		s.privileges = append([]user.Privilege{}, privileges...)
+		sort.SliceStable(s.privileges, func(i, j int) bool {
+			return s.privileges[i].Reputation < s.privileges[j].Reputation
+		})
+	}
+}
+
+// WithBadgeRules sets the rules badges are awarded by, replacing
+// badges.DefaultRules.
+func WithBadgeRules(rules ...badges.Rule) Option {
+	return func(s *service) {
+		s.badges = badges.NewEngine(rules...)
+	}
 }
 
-func NewService(store repository.Store) Service {
-	return &service{
-		Store: store,
+func NewService(store repository.Store, opts ...Option) Service {
+	s := &service{
+		Store:               store,
+		restoreWindow:       DefaultRestoreWindow,
+		renameCooldown:      DefaultRenameCooldown,
+		userNameReservation: DefaultUserNameReservation,
+		privileges:          user.DefaultPrivileges,
+		badges:              badges.NewEngine(badges.DefaultRules...),
+		avatars:             avatar.NewProcessor(),
+		githubVerifier:      github.NewVerifier(nil),
+		githubChallengeTTL:  DefaultGithubChallengeTTL,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *service) GetAllUsers() ([]user.User, error) {
-	users, err := s.Store.GetAllUsers()
+func (s *service) GetAllUsers(ctx context.Context) ([]user.User, error) {
+	users, err := s.Store.GetAllUsers(ctx)
 	if err != nil {
 		log.Printf("SERVICE ERROR: %s", err.Error())
 		return []user.User{}, err
@@ -36,42 +201,408 @@ func (s *service) GetAllUsers() ([]user.User, error) {
 	return users, nil
 }
 
-func (s *service) GetUserByID(id int) (user.User, error) {
-	usr, err := s.Store.GetUserByID(id)
+// GetUserByID looks a user up, as not found if they blocked the viewer set
+// with WithViewer.
+func (s *service) GetUserByID(ctx context.Context, id int) (user.User, error) {
+	usr, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if err := s.hideFromViewer(ctx, usr); err != nil {
+		return user.User{}, err
+	}
+	return usr, nil
+}
+
+// GetUsersByIDs looks up to MaxBatchSize users in one go. Repeated IDs are
+// only looked up, and returned, once. Users who blocked the viewer set with
+// WithViewer are reported as not found.
+func (s *service) GetUsersByIDs(ctx context.Context, ids []int) (user.Batch, error) {
+	unique := make([]int, 0, len(ids))
+	seen := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) > MaxBatchSize {
+		return user.Batch{}, domainerr.Validation("at most %d users can be looked up at once", MaxBatchSize)
+	}
+
+	users, err := s.Store.GetUsersByIDs(ctx, unique)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.Batch{}, err
+	}
+	hidden, err := s.blockersOfViewer(ctx, unique)
+	if err != nil {
+		return user.Batch{}, err
+	}
+	byID := make(map[int]user.User, len(users))
+	for _, usr := range users {
+		byID[usr.ID] = usr
+	}
+	batch := user.Batch{
+		Users:    make([]user.User, 0, len(users)),
+		NotFound: []int{},
+	}
+	for _, id := range unique {
+		if usr, ok := byID[id]; ok && !hidden[id] {
+			batch.Users = append(batch.Users, usr)
+		} else {
+			batch.NotFound = append(batch.NotFound, id)
+		}
+	}
+	return batch, nil
+}
+
+func (s *service) GetUserByEmail(ctx context.Context, email string) (user.User, error) {
+	usr, err := s.Store.GetUserByEmail(ctx, email)
 	if err != nil {
 		return user.User{}, err
 	}
 	return usr, nil
 }
 
-func (s *service) GetUserByEmail(email string) (user.User, error) {
-	usr, err := s.Store.GetUserByEmail(email)
+// GetUserByUserName is GetUserByID by username.
+func (s *service) GetUserByUserName(ctx context.Context, name string) (user.User, error) {
+	usr, err := s.Store.GetUserByUserName(ctx, name)
 	if err != nil {
 		return user.User{}, err
 	}
+	if err := s.hideFromViewer(ctx, usr); err != nil {
+		return user.User{}, err
+	}
 	return usr, nil
 }
 
-func (s *service) CreateUser(usr *user.User) (*user.User, error) {
-	created, err := s.Store.CreateUser(usr)
+// FindUsers returns the users matching filter, leaving out those who blocked
+// the viewer set with WithViewer.
+func (s *service) FindUsers(ctx context.Context, filter user.Filter) ([]user.User, error) {
+	normalizeFilterLinks(&filter)
+	if err := s.normalizeFilterProfileFields(ctx, &filter); err != nil {
+		return []user.User{}, err
+	}
+	users, err := s.Store.FindUsers(ctx, filter)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.User{}, err
+	}
+	return s.withoutBlockers(ctx, users)
+}
+
+// SearchUsers runs a ranked full-text search and attaches highlight snippets
+// to each result. Users who blocked the viewer set with WithViewer are left
+// out, and not counted in the Total.
+func (s *service) SearchUsers(ctx context.Context, query user.SearchQuery) (user.SearchPage, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 {
+		query.PerPage = defaultSearchPerPage
+	} else if query.PerPage > maxSearchPerPage {
+		query.PerPage = maxSearchPerPage
+	}
+
+	query.Viewer = viewerFrom(ctx)
+
+	page, err := s.Store.SearchUsers(ctx, query)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.SearchPage{}, err
+	}
+	terms := searchTerms(query.Query)
+	for i := range page.Results {
+		page.Results[i].Highlights = highlights(page.Results[i].User, terms)
+	}
+	return page, nil
+}
+
+// SuggestUsers returns the highest scoring users whose username starts with
+// prefix, leaving out those who blocked the viewer set with WithViewer.
+func (s *service) SuggestUsers(ctx context.Context, prefix string, limit int) ([]user.Suggestion, error) {
+	if limit < 1 {
+		limit = defaultSuggestLimit
+	} else if limit > maxSuggestLimit {
+		limit = maxSuggestLimit
+	}
+	suggestions, err := s.Store.SuggestUsers(ctx, prefix, limit)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.Suggestion{}, err
+	}
+	ids := make([]int, len(suggestions))
+	for i, suggestion := range suggestions {
+		ids[i] = suggestion.ID
+	}
+	hidden, err := s.blockersOfViewer(ctx, ids)
+	if err != nil {
+		return []user.Suggestion{}, err
+	}
+	visible := make([]user.Suggestion, 0, len(suggestions))
+	for _, suggestion := range suggestions {
+		if !hidden[suggestion.ID] {
+			visible = append(visible, suggestion)
+		}
+	}
+	return visible, nil
+}
+
+func (s *service) CreateUser(ctx context.Context, usr *user.User) (*user.User, error) {
+	if canonical.IsReserved(usr.UserName) {
+		return nil, reservedUserName(usr.UserName)
+	}
+	if err := normalizeLinks(usr); err != nil {
+		return nil, err
+	}
+	if err := s.ensureEmailAvailable(ctx, usr.Email); err != nil {
+		return nil, err
+	}
+	created, err := s.Store.CreateUser(ctx, usr)
 	if err != nil {
 		return nil, err
 	}
 	return created, nil
 }
 
-func (s *service) UpdateUser(usr user.User) (user.User, error) {
-	usr, err := s.Store.UpdateUser(usr)
+func (s *service) UpdateUser(ctx context.Context, usr user.User) (user.User, error) {
+	if usr.UserName != "" || usr.Github != "" || usr.Linkedin != "" {
+		current, err := s.Store.GetUserByID(ctx, usr.ID)
+		if err != nil {
+			return user.User{}, err
+		}
+		// Renames go through RenameUser, which keeps the username history.
+		if usr.UserName != "" && current.UserName != usr.UserName {
+			return user.User{}, ErrUserNameChangeNotAllowed
+		}
+		if err := normalizeChangedLinks(&usr, current); err != nil {
+			return user.User{}, err
+		}
+	}
+	usr, err := s.Store.UpdateUser(ctx, usr)
+	if err != nil {
+		return user.User{}, err
+	}
+	s.award(ctx, badges.Event{User: usr})
+	return usr, nil
+}
+
+// UpdatePrivacy replaces which of a user's optional fields are public.
+func (s *service) UpdatePrivacy(ctx context.Context, id int, privacy user.Privacy) (user.User, error) {
+	usr, err := s.Store.UpdatePrivacy(ctx, id, privacy)
 	if err != nil {
 		return user.User{}, err
 	}
 	return usr, nil
 }
 
-func (s *service) DeleteUser(id int) error {
-	err := s.Store.DeleteUser(id)
+func (s *service) DeleteUser(ctx context.Context, id int) error {
+	err := s.Store.DeleteUser(ctx, id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// RestoreUser undoes a soft delete, as long as it happened within the restore
+// window and nobody has since signed up with the same email.
+func (s *service) RestoreUser(ctx context.Context, id int) (user.User, error) {
+	deleted, err := s.Store.GetDeletedUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if deleted.PurgedAt != nil || time.Since(deleted.DeletedAt.Time) > s.restoreWindow {
+		return user.User{}, ErrRestoreWindowPassed
+	}
+	if err := s.ensureEmailAvailable(ctx, deleted.Email); err != nil {
+		return user.User{}, err
+	}
+	return s.Store.RestoreUser(ctx, id)
+}
+
+// reservedUserName is the error for choosing a username that canonical.IsReserved.
+func reservedUserName(name string) error {
+	return domainerr.Validation("username %q is reserved", name)
+}
+
+// ensureEmailAvailable returns a Conflict error if an active user already has email.
+func (s *service) ensureEmailAvailable(ctx context.Context, email string) error {
+	existing, err := s.Store.GetUserByEmail(ctx, email)
+	switch {
+	case err == nil && existing.ID > 0:
+		return domainerr.Conflict("a user with this email already exists")
+	case err != nil && !errors.Is(err, domainerr.ErrNotFound):
+		return err
+	}
+	return nil
+}
+
+// PurgeDeletedUsers anonymizes every user whose restore window has passed.
+func (s *service) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	purged, err := s.Store.PurgeDeletedUsers(ctx, time.Now().Add(-s.restoreWindow))
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return 0, err
+	}
+	return purged, nil
+}
+
+// RenameUser changes a user's username, at most once per rename cooldown.
+// Usernames other users gave up during the reservation period are off limits,
+// but users can go back to their own old names.
+func (s *service) RenameUser(ctx context.Context, id int, name string) (user.User, error) {
+	if canonical.IsReserved(name) {
+		return user.User{}, reservedUserName(name)
+	}
+	current, err := s.Store.GetUserByID(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if current.UserName == name {
+		return current, nil
+	}
+
+	changes, err := s.Store.GetUserNameChanges(ctx, id)
+	if err != nil {
+		return user.User{}, err
+	}
+	if len(changes) > 0 {
+		if next := changes[0].CreatedAt.Add(s.renameCooldown); time.Now().Before(next) {
+			return user.User{}, domainerr.Conflict("username was changed recently and can be changed again after %s", next.UTC().Format(time.RFC3339))
+		}
+	}
+
+	previous, err := s.Store.GetUserNameChangeByOldName(ctx, name, time.Now().Add(-s.userNameReservation))
+	switch {
+	case err == nil && previous.UserID != id:
+		return user.User{}, ErrUserNameReserved
+	case err != nil && !errors.Is(err, domainerr.ErrNotFound):
+		return user.User{}, err
+	}
+
+	renamed, err := s.Store.RenameUser(ctx, id, name)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.User{}, err
+	}
+	return renamed, nil
+}
+
+// GetUserNameHistory returns a user's username changes, newest first.
+func (s *service) GetUserNameHistory(ctx context.Context, id int) ([]user.UserNameChange, error) {
+	if _, err := s.Store.GetUserByID(ctx, id); err != nil {
+		return []user.UserNameChange{}, err
+	}
+	changes, err := s.Store.GetUserNameChanges(ctx, id)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.UserNameChange{}, err
+	}
+	return changes, nil
+}
+
+// GetRenamedUser returns the user who gave up oldName within the reservation
+// period, so links to it can be sent on to their current username.
+func (s *service) GetRenamedUser(ctx context.Context, oldName string) (user.User, error) {
+	change, err := s.Store.GetUserNameChangeByOldName(ctx, oldName, time.Now().Add(-s.userNameReservation))
+	if err != nil {
+		return user.User{}, err
+	}
+	return s.GetUserByID(ctx, change.UserID)
+}
+
+// GetUserStats returns the Stats of each of the given users, without
+// checking that they exist.
+func (s *service) GetUserStats(ctx context.Context, ids []int) (map[int]user.Stats, error) {
+	changes, err := s.Store.CountUserNameChanges(ctx, ids)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	awards, err := s.Store.CountAwards(ctx, ids)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	stats := make(map[int]user.Stats, len(ids))
+	for _, id := range ids {
+		stats[id] = user.Stats{
+			UserNameChanges: changes[id],
+			Badges:          awards[id],
+		}
+	}
+	return stats, nil
+}
+
+// AddReputationEvent records event and updates the user's UserScore. Posting
+// the same event again returns the first one, and false, without counting it
+// twice.
+func (s *service) AddReputationEvent(ctx context.Context, event user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	if event.Delta == 0 {
+		return user.ReputationEvent{}, false, domainerr.Validation("reputation events must change the score")
+	}
+	event.ID = 0
+	event.CreatedAt = time.Time{}
+	stored, added, err := s.Store.AddReputationEvent(ctx, event)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.ReputationEvent{}, false, err
+	}
+	if !added {
+		if !sameReputationEvent(stored, event) {
+			return user.ReputationEvent{}, false, ErrIdempotencyKeyReused
+		}
+		return stored, false, nil
+	}
+	if usr, err := s.Store.GetUserByID(ctx, stored.UserID); err == nil {
+		s.award(ctx, badges.Event{User: usr, Reputation: &stored})
+	} else {
+		log.Printf("SERVICE ERROR: loading user %d to award badges: %s", stored.UserID, err.Error())
+	}
+	return stored, true, nil
+}
+
+func sameReputationEvent(a, b user.ReputationEvent) bool {
+	return a.UserID == b.UserID &&
+		a.Reason == b.Reason &&
+		a.Delta == b.Delta &&
+		a.ReferenceType == b.ReferenceType &&
+		a.ReferenceID == b.ReferenceID
+}
+
+// GetReputationEvents returns a page of a user's reputation events, newest first.
+func (s *service) GetReputationEvents(ctx context.Context, userID, page, perPage int) (user.ReputationPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultReputationPerPage
+	} else if perPage > maxReputationPerPage {
+		perPage = maxReputationPerPage
+	}
+	if _, err := s.Store.GetUserByID(ctx, userID); err != nil {
+		return user.ReputationPage{}, err
+	}
+	events, err := s.Store.GetReputationEvents(ctx, userID, page, perPage)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return user.ReputationPage{}, err
+	}
+	return events, nil
+}
+
+// RecalculateUserScores rebuilds every UserScore from the reputation ledger
+// and returns how many were wrong.
+func (s *service) RecalculateUserScores(ctx context.Context) (int, error) {
+	fixed, err := s.Store.RecalculateUserScores(ctx)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return len(fixed), err
+	}
+	if len(fixed) > 0 {
+		log.Printf("Recalculated the reputation of %d users whose UserScore had drifted.", len(fixed))
+	}
+	return len(fixed), nil
+}
@@ -0,0 +1,189 @@
+package user
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/millbj92/nuboverflow-users/internal/canonical"
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// MaxSkills is the most skills a user can list.
+const MaxSkills = 50
+
+const (
+	// defaultSkillsLimit and maxSkillsLimit bound the lists of skills in the
+	// catalog and of the top users of a skill.
+	defaultSkillsLimit = 20
+	maxSkillsLimit     = 100
+)
+
+var (
+	// ErrSelfEndorsement is returned when users try to endorse themselves.
+	ErrSelfEndorsement = domainerr.Validation("users cannot endorse themselves")
+	// ErrTooManySkills is returned when a user who lists MaxSkills skills
+	// adds another.
+	ErrTooManySkills = domainerr.Validation("users can list at most %d skills", MaxSkills)
+)
+
+// AddUserSkill lists a skill among a user's. It returns false if they already
+// did.
+func (s *service) AddUserSkill(ctx context.Context, id int, name string) (bool, error) {
+	tag, err := canonical.Tag(name)
+	if err != nil {
+		return false, err
+	}
+	skills, err := s.Store.GetUserSkills(ctx, []int{id})
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	for _, skill := range skills {
+		if skill.Skill == tag {
+			return false, nil
+		}
+	}
+	if len(skills) >= MaxSkills {
+		return false, ErrTooManySkills
+	}
+	added, err := s.Store.AddUserSkill(ctx, id, tag)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return added, nil
+}
+
+// RemoveUserSkill takes a skill off a user's, along with its endorsements. It
+// returns false if they didn't list it.
+func (s *service) RemoveUserSkill(ctx context.Context, id int, name string) (bool, error) {
+	tag, err := canonical.Tag(name)
+	if err != nil {
+		return false, err
+	}
+	removed, err := s.Store.RemoveUserSkill(ctx, id, tag)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return removed, nil
+}
+
+// GetUserSkills returns the skills a user lists, most endorsed first.
+func (s *service) GetUserSkills(ctx context.Context, id int) ([]user.UserSkill, error) {
+	if _, err := s.Store.GetUserByID(ctx, id); err != nil {
+		return []user.UserSkill{}, err
+	}
+	skills, err := s.Store.GetUserSkills(ctx, []int{id})
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.UserSkill{}, err
+	}
+	return skills, nil
+}
+
+// GetSkillsByUserIDs returns the skills of each of the given users, most
+// endorsed first, without checking that they exist.
+func (s *service) GetSkillsByUserIDs(ctx context.Context, ids []int) (map[int][]user.UserSkill, error) {
+	skills, err := s.Store.GetUserSkills(ctx, ids)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return nil, err
+	}
+	byUser := make(map[int][]user.UserSkill, len(ids))
+	for _, id := range ids {
+		byUser[id] = []user.UserSkill{}
+	}
+	for _, skill := range skills {
+		byUser[skill.UserID] = append(byUser[skill.UserID], skill)
+	}
+	return byUser, nil
+}
+
+// EndorseSkill makes endorserID vouch for a skill the user with id lists. It
+// returns false if they already did.
+func (s *service) EndorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error) {
+	if endorserID == id {
+		return false, ErrSelfEndorsement
+	}
+	tag, err := canonical.Tag(name)
+	if err != nil {
+		return false, err
+	}
+	endorsed, err := s.Store.EndorseSkill(ctx, endorserID, id, tag)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return endorsed, nil
+}
+
+// UnendorseSkill takes back an endorsement. It returns false if there was
+// none.
+func (s *service) UnendorseSkill(ctx context.Context, endorserID, id int, name string) (bool, error) {
+	tag, err := canonical.Tag(name)
+	if err != nil {
+		return false, err
+	}
+	unendorsed, err := s.Store.UnendorseSkill(ctx, endorserID, id, tag)
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return false, err
+	}
+	return unendorsed, nil
+}
+
+// GetSkills returns the skills in the catalog starting with prefix, those
+// most users list first.
+func (s *service) GetSkills(ctx context.Context, prefix string, limit int) ([]user.SkillSummary, error) {
+	// Written the way tags are, so "Machine L" finds machine-learning.
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), "-"))
+	skills, err := s.Store.GetSkills(ctx, prefix, clampSkillsLimit(limit))
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.SkillSummary{}, err
+	}
+	return skills, nil
+}
+
+// GetTopUsersForSkill returns the users listing a skill, most endorsed for it
+// first and, among those endorsed as often, highest UserScore first. Users who
+// blocked the viewer set with WithViewer are left out.
+func (s *service) GetTopUsersForSkill(ctx context.Context, name string, limit int) ([]user.SkilledUser, error) {
+	tag, err := canonical.Tag(name)
+	if err != nil {
+		return []user.SkilledUser{}, err
+	}
+	users, err := s.Store.GetTopUsersForSkill(ctx, tag, clampSkillsLimit(limit))
+	if err != nil {
+		log.Printf("SERVICE ERROR: %s", err.Error())
+		return []user.SkilledUser{}, err
+	}
+	ids := make([]int, len(users))
+	for i, skilled := range users {
+		ids[i] = skilled.User.ID
+	}
+	hidden, err := s.blockersOfViewer(ctx, ids)
+	if err != nil {
+		return []user.SkilledUser{}, err
+	}
+	visible := make([]user.SkilledUser, 0, len(users))
+	for _, skilled := range users {
+		if !hidden[skilled.User.ID] {
+			visible = append(visible, skilled)
+		}
+	}
+	return visible, nil
+}
+
+func clampSkillsLimit(limit int) int {
+	if limit < 1 {
+		return defaultSkillsLimit
+	}
+	if limit > maxSkillsLimit {
+		return maxSkillsLimit
+	}
+	return limit
+}
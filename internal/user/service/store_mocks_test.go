@@ -0,0 +1,833 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/millbj92/nuboverflow-users/internal/repository (interfaces: Store)
+
+// Package user is a generated GoMock package.
+package user
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	gomock "github.com/golang/mock/gomock"
+	user "github.com/millbj92/nuboverflow-users/internal/user"
+)
+
+// MockStore is a mock of Store interface.
+type MockStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreMockRecorder
+}
+
+// MockStoreMockRecorder is the mock recorder for MockStore.
+type MockStoreMockRecorder struct {
+	mock *MockStore
+}
+
+// NewMockStore creates a new mock instance.
+func NewMockStore(ctrl *gomock.Controller) *MockStore {
+	mock := &MockStore{ctrl: ctrl}
+	mock.recorder = &MockStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStore) EXPECT() *MockStoreMockRecorder {
+	return m.recorder
+}
+
+// AddAwards mocks base method.
+func (m *MockStore) AddAwards(arg0 context.Context, arg1 []user.Award) ([]user.Award, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAwards", arg0, arg1)
+	ret0, _ := ret[0].([]user.Award)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddAwards indicates an expected call of AddAwards.
+func (mr *MockStoreMockRecorder) AddAwards(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAwards", reflect.TypeOf((*MockStore)(nil).AddAwards), arg0, arg1)
+}
+
+// AddReputationEvent mocks base method.
+func (m *MockStore) AddReputationEvent(arg0 context.Context, arg1 user.ReputationEvent) (user.ReputationEvent, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddReputationEvent", arg0, arg1)
+	ret0, _ := ret[0].(user.ReputationEvent)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// AddReputationEvent indicates an expected call of AddReputationEvent.
+func (mr *MockStoreMockRecorder) AddReputationEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddReputationEvent", reflect.TypeOf((*MockStore)(nil).AddReputationEvent), arg0, arg1)
+}
+
+// AddUserSkill mocks base method.
+func (m *MockStore) AddUserSkill(arg0 context.Context, arg1 int, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUserSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddUserSkill indicates an expected call of AddUserSkill.
+func (mr *MockStoreMockRecorder) AddUserSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUserSkill", reflect.TypeOf((*MockStore)(nil).AddUserSkill), arg0, arg1, arg2)
+}
+
+// BlockUser mocks base method.
+func (m *MockStore) BlockUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockUser indicates an expected call of BlockUser.
+func (mr *MockStoreMockRecorder) BlockUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUser", reflect.TypeOf((*MockStore)(nil).BlockUser), arg0, arg1, arg2)
+}
+
+// CountAwards mocks base method.
+func (m *MockStore) CountAwards(arg0 context.Context, arg1 []int) (map[int]user.BadgeCounts, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountAwards", arg0, arg1)
+	ret0, _ := ret[0].(map[int]user.BadgeCounts)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountAwards indicates an expected call of CountAwards.
+func (mr *MockStoreMockRecorder) CountAwards(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountAwards", reflect.TypeOf((*MockStore)(nil).CountAwards), arg0, arg1)
+}
+
+// CountUserNameChanges mocks base method.
+func (m *MockStore) CountUserNameChanges(arg0 context.Context, arg1 []int) (map[int]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUserNameChanges", arg0, arg1)
+	ret0, _ := ret[0].(map[int]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUserNameChanges indicates an expected call of CountUserNameChanges.
+func (mr *MockStoreMockRecorder) CountUserNameChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUserNameChanges", reflect.TypeOf((*MockStore)(nil).CountUserNameChanges), arg0, arg1)
+}
+
+// CreateUser mocks base method.
+func (m *MockStore) CreateUser(arg0 context.Context, arg1 *user.User) (*user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", arg0, arg1)
+	ret0, _ := ret[0].(*user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockStoreMockRecorder) CreateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockStore)(nil).CreateUser), arg0, arg1)
+}
+
+// DeleteProfileField mocks base method.
+func (m *MockStore) DeleteProfileField(arg0 context.Context, arg1 string) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteProfileField", arg0, arg1)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteProfileField indicates an expected call of DeleteProfileField.
+func (mr *MockStoreMockRecorder) DeleteProfileField(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteProfileField", reflect.TypeOf((*MockStore)(nil).DeleteProfileField), arg0, arg1)
+}
+
+// DeleteUser mocks base method.
+func (m *MockStore) DeleteUser(arg0 context.Context, arg1 int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUser indicates an expected call of DeleteUser.
+func (mr *MockStoreMockRecorder) DeleteUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUser", reflect.TypeOf((*MockStore)(nil).DeleteUser), arg0, arg1)
+}
+
+// EndorseSkill mocks base method.
+func (m *MockStore) EndorseSkill(arg0 context.Context, arg1, arg2 int, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EndorseSkill", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EndorseSkill indicates an expected call of EndorseSkill.
+func (mr *MockStoreMockRecorder) EndorseSkill(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EndorseSkill", reflect.TypeOf((*MockStore)(nil).EndorseSkill), arg0, arg1, arg2, arg3)
+}
+
+// FindBlocks mocks base method.
+func (m *MockStore) FindBlocks(arg0 context.Context, arg1 []user.Block) ([]user.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindBlocks", arg0, arg1)
+	ret0, _ := ret[0].([]user.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindBlocks indicates an expected call of FindBlocks.
+func (mr *MockStoreMockRecorder) FindBlocks(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindBlocks", reflect.TypeOf((*MockStore)(nil).FindBlocks), arg0, arg1)
+}
+
+// FindUsers mocks base method.
+func (m *MockStore) FindUsers(arg0 context.Context, arg1 user.Filter) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUsers", arg0, arg1)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUsers indicates an expected call of FindUsers.
+func (mr *MockStoreMockRecorder) FindUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockStore)(nil).FindUsers), arg0, arg1)
+}
+
+// Follow mocks base method.
+func (m *MockStore) Follow(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Follow", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Follow indicates an expected call of Follow.
+func (mr *MockStoreMockRecorder) Follow(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Follow", reflect.TypeOf((*MockStore)(nil).Follow), arg0, arg1, arg2)
+}
+
+// GetAllUsers mocks base method.
+func (m *MockStore) GetAllUsers(arg0 context.Context) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllUsers", arg0)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllUsers indicates an expected call of GetAllUsers.
+func (mr *MockStoreMockRecorder) GetAllUsers(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllUsers", reflect.TypeOf((*MockStore)(nil).GetAllUsers), arg0)
+}
+
+// GetAwards mocks base method.
+func (m *MockStore) GetAwards(arg0 context.Context, arg1 []int) ([]user.Award, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAwards", arg0, arg1)
+	ret0, _ := ret[0].([]user.Award)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAwards indicates an expected call of GetAwards.
+func (mr *MockStoreMockRecorder) GetAwards(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAwards", reflect.TypeOf((*MockStore)(nil).GetAwards), arg0, arg1)
+}
+
+// GetBlocks mocks base method.
+func (m *MockStore) GetBlocks(arg0 context.Context, arg1 int, arg2 user.Cursor, arg3 int) ([]user.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlocks", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]user.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlocks indicates an expected call of GetBlocks.
+func (mr *MockStoreMockRecorder) GetBlocks(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlocks", reflect.TypeOf((*MockStore)(nil).GetBlocks), arg0, arg1, arg2, arg3)
+}
+
+// GetDeletedUserByID mocks base method.
+func (m *MockStore) GetDeletedUserByID(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDeletedUserByID", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDeletedUserByID indicates an expected call of GetDeletedUserByID.
+func (mr *MockStoreMockRecorder) GetDeletedUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDeletedUserByID", reflect.TypeOf((*MockStore)(nil).GetDeletedUserByID), arg0, arg1)
+}
+
+// GetFollowers mocks base method.
+func (m *MockStore) GetFollowers(arg0 context.Context, arg1 int, arg2 user.Cursor, arg3 int) ([]user.Follow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowers", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]user.Follow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowers indicates an expected call of GetFollowers.
+func (mr *MockStoreMockRecorder) GetFollowers(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowers", reflect.TypeOf((*MockStore)(nil).GetFollowers), arg0, arg1, arg2, arg3)
+}
+
+// GetFollowing mocks base method.
+func (m *MockStore) GetFollowing(arg0 context.Context, arg1 int, arg2 user.Cursor, arg3 int) ([]user.Follow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowing", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]user.Follow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowing indicates an expected call of GetFollowing.
+func (mr *MockStoreMockRecorder) GetFollowing(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowing", reflect.TypeOf((*MockStore)(nil).GetFollowing), arg0, arg1, arg2, arg3)
+}
+
+// GetFollowsBetween mocks base method.
+func (m *MockStore) GetFollowsBetween(arg0 context.Context, arg1, arg2 int) ([]user.Follow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFollowsBetween", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.Follow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFollowsBetween indicates an expected call of GetFollowsBetween.
+func (mr *MockStoreMockRecorder) GetFollowsBetween(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFollowsBetween", reflect.TypeOf((*MockStore)(nil).GetFollowsBetween), arg0, arg1, arg2)
+}
+
+// GetGithubChallenge mocks base method.
+func (m *MockStore) GetGithubChallenge(arg0 context.Context, arg1 int) (user.GithubChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetGithubChallenge", arg0, arg1)
+	ret0, _ := ret[0].(user.GithubChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetGithubChallenge indicates an expected call of GetGithubChallenge.
+func (mr *MockStoreMockRecorder) GetGithubChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetGithubChallenge", reflect.TypeOf((*MockStore)(nil).GetGithubChallenge), arg0, arg1)
+}
+
+// GetMutes mocks base method.
+func (m *MockStore) GetMutes(arg0 context.Context, arg1 int, arg2 user.Cursor, arg3 int) ([]user.Mute, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetMutes", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]user.Mute)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetMutes indicates an expected call of GetMutes.
+func (mr *MockStoreMockRecorder) GetMutes(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMutes", reflect.TypeOf((*MockStore)(nil).GetMutes), arg0, arg1, arg2, arg3)
+}
+
+// GetProfileFields mocks base method.
+func (m *MockStore) GetProfileFields(arg0 context.Context) ([]user.ProfileField, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProfileFields", arg0)
+	ret0, _ := ret[0].([]user.ProfileField)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProfileFields indicates an expected call of GetProfileFields.
+func (mr *MockStoreMockRecorder) GetProfileFields(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProfileFields", reflect.TypeOf((*MockStore)(nil).GetProfileFields), arg0)
+}
+
+// GetReputationEvents mocks base method.
+func (m *MockStore) GetReputationEvents(arg0 context.Context, arg1, arg2, arg3 int) (user.ReputationPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReputationEvents", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(user.ReputationPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReputationEvents indicates an expected call of GetReputationEvents.
+func (mr *MockStoreMockRecorder) GetReputationEvents(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReputationEvents", reflect.TypeOf((*MockStore)(nil).GetReputationEvents), arg0, arg1, arg2, arg3)
+}
+
+// GetSkills mocks base method.
+func (m *MockStore) GetSkills(arg0 context.Context, arg1 string, arg2 int) ([]user.SkillSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSkills", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.SkillSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSkills indicates an expected call of GetSkills.
+func (mr *MockStoreMockRecorder) GetSkills(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSkills", reflect.TypeOf((*MockStore)(nil).GetSkills), arg0, arg1, arg2)
+}
+
+// GetTopUsersForSkill mocks base method.
+func (m *MockStore) GetTopUsersForSkill(arg0 context.Context, arg1 string, arg2 int) ([]user.SkilledUser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTopUsersForSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.SkilledUser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTopUsersForSkill indicates an expected call of GetTopUsersForSkill.
+func (mr *MockStoreMockRecorder) GetTopUsersForSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTopUsersForSkill", reflect.TypeOf((*MockStore)(nil).GetTopUsersForSkill), arg0, arg1, arg2)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockStore) GetUserByEmail(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockStoreMockRecorder) GetUserByEmail(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockStore)(nil).GetUserByEmail), arg0, arg1)
+}
+
+// GetUserByID mocks base method.
+func (m *MockStore) GetUserByID(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockStoreMockRecorder) GetUserByID(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockStore)(nil).GetUserByID), arg0, arg1)
+}
+
+// GetUserByUserName mocks base method.
+func (m *MockStore) GetUserByUserName(arg0 context.Context, arg1 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByUserName", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByUserName indicates an expected call of GetUserByUserName.
+func (mr *MockStoreMockRecorder) GetUserByUserName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByUserName", reflect.TypeOf((*MockStore)(nil).GetUserByUserName), arg0, arg1)
+}
+
+// GetUserNameChangeByOldName mocks base method.
+func (m *MockStore) GetUserNameChangeByOldName(arg0 context.Context, arg1 string, arg2 time.Time) (user.UserNameChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserNameChangeByOldName", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.UserNameChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserNameChangeByOldName indicates an expected call of GetUserNameChangeByOldName.
+func (mr *MockStoreMockRecorder) GetUserNameChangeByOldName(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserNameChangeByOldName", reflect.TypeOf((*MockStore)(nil).GetUserNameChangeByOldName), arg0, arg1, arg2)
+}
+
+// GetUserNameChanges mocks base method.
+func (m *MockStore) GetUserNameChanges(arg0 context.Context, arg1 int) ([]user.UserNameChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserNameChanges", arg0, arg1)
+	ret0, _ := ret[0].([]user.UserNameChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserNameChanges indicates an expected call of GetUserNameChanges.
+func (mr *MockStoreMockRecorder) GetUserNameChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserNameChanges", reflect.TypeOf((*MockStore)(nil).GetUserNameChanges), arg0, arg1)
+}
+
+// GetUserSkills mocks base method.
+func (m *MockStore) GetUserSkills(arg0 context.Context, arg1 []int) ([]user.UserSkill, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserSkills", arg0, arg1)
+	ret0, _ := ret[0].([]user.UserSkill)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserSkills indicates an expected call of GetUserSkills.
+func (mr *MockStoreMockRecorder) GetUserSkills(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserSkills", reflect.TypeOf((*MockStore)(nil).GetUserSkills), arg0, arg1)
+}
+
+// GetUsersByIDs mocks base method.
+func (m *MockStore) GetUsersByIDs(arg0 context.Context, arg1 []int) ([]user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUsersByIDs", arg0, arg1)
+	ret0, _ := ret[0].([]user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUsersByIDs indicates an expected call of GetUsersByIDs.
+func (mr *MockStoreMockRecorder) GetUsersByIDs(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUsersByIDs", reflect.TypeOf((*MockStore)(nil).GetUsersByIDs), arg0, arg1)
+}
+
+// IsBlocked mocks base method.
+func (m *MockStore) IsBlocked(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsBlocked", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsBlocked indicates an expected call of IsBlocked.
+func (mr *MockStoreMockRecorder) IsBlocked(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsBlocked", reflect.TypeOf((*MockStore)(nil).IsBlocked), arg0, arg1, arg2)
+}
+
+// MuteUser mocks base method.
+func (m *MockStore) MuteUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MuteUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MuteUser indicates an expected call of MuteUser.
+func (mr *MockStoreMockRecorder) MuteUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MuteUser", reflect.TypeOf((*MockStore)(nil).MuteUser), arg0, arg1, arg2)
+}
+
+// PurgeDeletedUsers mocks base method.
+func (m *MockStore) PurgeDeletedUsers(arg0 context.Context, arg1 time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeDeletedUsers", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PurgeDeletedUsers indicates an expected call of PurgeDeletedUsers.
+func (mr *MockStoreMockRecorder) PurgeDeletedUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeDeletedUsers", reflect.TypeOf((*MockStore)(nil).PurgeDeletedUsers), arg0, arg1)
+}
+
+// RecalculateUserScores mocks base method.
+func (m *MockStore) RecalculateUserScores(arg0 context.Context) ([]int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecalculateUserScores", arg0)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecalculateUserScores indicates an expected call of RecalculateUserScores.
+func (mr *MockStoreMockRecorder) RecalculateUserScores(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecalculateUserScores", reflect.TypeOf((*MockStore)(nil).RecalculateUserScores), arg0)
+}
+
+// RemoveUserSkill mocks base method.
+func (m *MockStore) RemoveUserSkill(arg0 context.Context, arg1 int, arg2 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemoveUserSkill", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RemoveUserSkill indicates an expected call of RemoveUserSkill.
+func (mr *MockStoreMockRecorder) RemoveUserSkill(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveUserSkill", reflect.TypeOf((*MockStore)(nil).RemoveUserSkill), arg0, arg1, arg2)
+}
+
+// RenameUser mocks base method.
+func (m *MockStore) RenameUser(arg0 context.Context, arg1 int, arg2 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameUser indicates an expected call of RenameUser.
+func (mr *MockStoreMockRecorder) RenameUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameUser", reflect.TypeOf((*MockStore)(nil).RenameUser), arg0, arg1, arg2)
+}
+
+// RestoreUser mocks base method.
+func (m *MockStore) RestoreUser(arg0 context.Context, arg1 int) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockStoreMockRecorder) RestoreUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockStore)(nil).RestoreUser), arg0, arg1)
+}
+
+// SaveGithubChallenge mocks base method.
+func (m *MockStore) SaveGithubChallenge(arg0 context.Context, arg1 user.GithubChallenge) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveGithubChallenge", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveGithubChallenge indicates an expected call of SaveGithubChallenge.
+func (mr *MockStoreMockRecorder) SaveGithubChallenge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveGithubChallenge", reflect.TypeOf((*MockStore)(nil).SaveGithubChallenge), arg0, arg1)
+}
+
+// SaveProfileField mocks base method.
+func (m *MockStore) SaveProfileField(arg0 context.Context, arg1 user.ProfileField) (user.ProfileField, []int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveProfileField", arg0, arg1)
+	ret0, _ := ret[0].(user.ProfileField)
+	ret1, _ := ret[1].([]int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SaveProfileField indicates an expected call of SaveProfileField.
+func (mr *MockStoreMockRecorder) SaveProfileField(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveProfileField", reflect.TypeOf((*MockStore)(nil).SaveProfileField), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockStore) SearchUsers(arg0 context.Context, arg1 user.SearchQuery) (user.SearchPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1)
+	ret0, _ := ret[0].(user.SearchPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockStoreMockRecorder) SearchUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockStore)(nil).SearchUsers), arg0, arg1)
+}
+
+// SetProfileFieldValues mocks base method.
+func (m *MockStore) SetProfileFieldValues(arg0 context.Context, arg1 int, arg2 map[string]string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProfileFieldValues", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetProfileFieldValues indicates an expected call of SetProfileFieldValues.
+func (mr *MockStoreMockRecorder) SetProfileFieldValues(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProfileFieldValues", reflect.TypeOf((*MockStore)(nil).SetProfileFieldValues), arg0, arg1, arg2)
+}
+
+// SuggestUsers mocks base method.
+func (m *MockStore) SuggestUsers(arg0 context.Context, arg1 string, arg2 int) ([]user.Suggestion, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SuggestUsers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]user.Suggestion)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SuggestUsers indicates an expected call of SuggestUsers.
+func (mr *MockStoreMockRecorder) SuggestUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SuggestUsers", reflect.TypeOf((*MockStore)(nil).SuggestUsers), arg0, arg1, arg2)
+}
+
+// UnblockUser mocks base method.
+func (m *MockStore) UnblockUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnblockUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnblockUser indicates an expected call of UnblockUser.
+func (mr *MockStoreMockRecorder) UnblockUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnblockUser", reflect.TypeOf((*MockStore)(nil).UnblockUser), arg0, arg1, arg2)
+}
+
+// UnendorseSkill mocks base method.
+func (m *MockStore) UnendorseSkill(arg0 context.Context, arg1, arg2 int, arg3 string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnendorseSkill", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnendorseSkill indicates an expected call of UnendorseSkill.
+func (mr *MockStoreMockRecorder) UnendorseSkill(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnendorseSkill", reflect.TypeOf((*MockStore)(nil).UnendorseSkill), arg0, arg1, arg2, arg3)
+}
+
+// Unfollow mocks base method.
+func (m *MockStore) Unfollow(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unfollow", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unfollow indicates an expected call of Unfollow.
+func (mr *MockStoreMockRecorder) Unfollow(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unfollow", reflect.TypeOf((*MockStore)(nil).Unfollow), arg0, arg1, arg2)
+}
+
+// UnmuteUser mocks base method.
+func (m *MockStore) UnmuteUser(arg0 context.Context, arg1, arg2 int) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnmuteUser", arg0, arg1, arg2)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnmuteUser indicates an expected call of UnmuteUser.
+func (mr *MockStoreMockRecorder) UnmuteUser(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnmuteUser", reflect.TypeOf((*MockStore)(nil).UnmuteUser), arg0, arg1, arg2)
+}
+
+// UpdateAvatar mocks base method.
+func (m *MockStore) UpdateAvatar(arg0 context.Context, arg1 int, arg2 string) (user.User, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAvatar", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// UpdateAvatar indicates an expected call of UpdateAvatar.
+func (mr *MockStoreMockRecorder) UpdateAvatar(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAvatar", reflect.TypeOf((*MockStore)(nil).UpdateAvatar), arg0, arg1, arg2)
+}
+
+// UpdatePrivacy mocks base method.
+func (m *MockStore) UpdatePrivacy(arg0 context.Context, arg1 int, arg2 user.Privacy) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePrivacy", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdatePrivacy indicates an expected call of UpdatePrivacy.
+func (mr *MockStoreMockRecorder) UpdatePrivacy(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePrivacy", reflect.TypeOf((*MockStore)(nil).UpdatePrivacy), arg0, arg1, arg2)
+}
+
+// UpdateUser mocks base method.
+func (m *MockStore) UpdateUser(arg0 context.Context, arg1 user.User) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUser", arg0, arg1)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateUser indicates an expected call of UpdateUser.
+func (mr *MockStoreMockRecorder) UpdateUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockStore)(nil).UpdateUser), arg0, arg1)
+}
+
+// VerifyGithub mocks base method.
+func (m *MockStore) VerifyGithub(arg0 context.Context, arg1 int, arg2 string) (user.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyGithub", arg0, arg1, arg2)
+	ret0, _ := ret[0].(user.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyGithub indicates an expected call of VerifyGithub.
+func (mr *MockStoreMockRecorder) VerifyGithub(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyGithub", reflect.TypeOf((*MockStore)(nil).VerifyGithub), arg0, arg1, arg2)
+}
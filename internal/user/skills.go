@@ -0,0 +1,50 @@
+package user
+
+import (
+	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+)
+
+// ErrEndorseBlocked is returned when endorsing a user who blocked you, or
+// whom you blocked.
+var ErrEndorseBlocked = domainerr.Forbidden("users cannot endorse users they blocked or were blocked by")
+
+// Skill is a tag in the catalog of skills users list, such as go or
+// kubernetes. Tags join the catalog when a user first lists them.
+type Skill struct {
+	ID        int       `json:"-"`
+	Name      string    `gorm:"size:35;not null;uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UserSkill records a user listing a skill, and how many other users
+// endorsed them for it. Its index serves ranking the users of a skill.
+type UserSkill struct {
+	UserID       int       `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	SkillID      int       `gorm:"primaryKey;autoIncrement:false;index:idx_user_skills_ranking,priority:1" json:"-"`
+	Endorsements int       `gorm:"not null;default:0;index:idx_user_skills_ranking,priority:2" json:"endorsements"`
+	CreatedAt    time.Time `json:"addedAt"`
+	// Skill is the name of the skill, loaded along with it.
+	Skill string `gorm:"-" json:"skill"`
+}
+
+// Endorsement records EndorserID vouching for UserID having a skill.
+type Endorsement struct {
+	UserID     int       `gorm:"primaryKey;autoIncrement:false" json:"userId"`
+	SkillID    int       `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	EndorserID int       `gorm:"primaryKey;autoIncrement:false;index" json:"endorserId"`
+	CreatedAt  time.Time `json:"endorsedAt"`
+}
+
+// SkillSummary is a skill in the catalog and how many users list it.
+type SkillSummary struct {
+	Name  string `json:"name"`
+	Users int64  `json:"users"`
+}
+
+// SkilledUser is a user listing a skill, with their endorsements for it.
+type SkilledUser struct {
+	User         User `json:"user"`
+	Endorsements int  `json:"endorsements"`
+}
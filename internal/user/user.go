@@ -2,8 +2,15 @@ package user
 
 import (
 	"time"
+
+	"github.com/millbj92/nuboverflow-users/internal/domainerr"
+	"gorm.io/gorm"
 )
 
+// ErrVersionMismatch is returned when updating a user that was modified since
+// the given Version was read.
+var ErrVersionMismatch = domainerr.PreconditionFailed("user was modified by someone else")
+
 type User struct {
 	ID         int
 	CreatedAt  time.Time 
@@ -13,8 +20,91 @@ type User struct {
 	Email      string
 	Github     string 
 	Linkedin   string 
+	// UserScore is the sum of the user's reputation events.
 	UserScore  int
 	Bio        string 
 	Profession string
 	WorkPlace  string 
-}
\ No newline at end of file
+	// FollowerCount and FollowingCount count the user's follows. They are
+	// kept by Follow, Unfollow and BlockUser and never written by UpdateUser.
+	FollowerCount  int `gorm:"not null;default:0"`
+	FollowingCount int `gorm:"not null;default:0"`
+	// AvatarHash identifies the user's uploaded avatar (see package avatar),
+	// or is empty if they have none. It is set by UpdateAvatar, not UpdateUser.
+	AvatarHash string `gorm:"size:64"`
+	// VerifiedGithub is the Github handle the user last proved they own (see
+	// GithubVerified). It is set by VerifyGithub, not UpdateUser.
+	VerifiedGithub string `gorm:"size:39" json:"-"`
+	// ProfileFields are the user's values for the fields admins added to
+	// profiles, ordered by field. They live in their own table and are set by
+	// SetProfileFieldValues, not UpdateUser.
+	ProfileFields []ProfileFieldValue `gorm:"-" json:"-"`
+	// Version is incremented on every update of the profile and guards
+	// against lost updates. The score and counts change without it.
+	Version int `gorm:"not null;default:1"`
+	// DeletedAt marks a soft-deleted user. gorm excludes these rows from normal queries.
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	// PurgedAt is set once a soft-deleted user has been anonymized and can no longer be restored.
+	PurgedAt *time.Time `json:"-"`
+	// NormalizedEmail and NormalizedUserName are the canonical forms of Email
+	// and UserName (see package canonical) that lookups go through. Each is
+	// unique among users that are not deleted.
+	NormalizedEmail    string  `gorm:"size:191" json:"-"`
+	NormalizedUserName string  `gorm:"size:191" json:"-"`
+	Privacy            Privacy `gorm:"embedded;embeddedPrefix:privacy_"`
+}
+
+// Privacy controls which optional profile fields other users can see. The
+// user and admins always see everything.
+//
+// Its defaults live in the database, so they only apply to new users, and
+// gorm skips its false fields in struct updates: change it with
+// UpdatePrivacy, not UpdateUser.
+type Privacy struct {
+	ShowEmail     bool `gorm:"not null;default:false" json:"showEmail"`
+	ShowGithub    bool `gorm:"not null;default:true" json:"showGithub"`
+	ShowLinkedin  bool `gorm:"not null;default:true" json:"showLinkedin"`
+	ShowWorkPlace bool `gorm:"not null;default:true" json:"showWorkPlace"`
+}
+
+// DefaultPrivacy is what new users start with: everything but Email is public.
+var DefaultPrivacy = Privacy{
+	ShowGithub:    true,
+	ShowLinkedin:  true,
+	ShowWorkPlace: true,
+}
+
+// Batch is the result of looking up many users by ID. Users are in the order
+// their IDs were asked for; NotFound lists the IDs with no user.
+type Batch struct {
+	Users    []User `json:"users"`
+	NotFound []int  `json:"notFound"`
+}
+
+// Filter holds exact-match criteria used when listing users.
+// Empty fields are ignored, so the zero Filter matches every user.
+type Filter struct {
+	Email      string
+	UserName   string
+	Github     string
+	Linkedin   string
+	Profession string
+	WorkPlace  string
+	// ProfileFields matches users whose profile fields have the given
+	// values, by field name.
+	ProfileFields map[string]string
+}
+
+
+// Suggestion is a lightweight user match returned by username autocomplete.
+type Suggestion struct {
+	ID        int    `json:"id"`
+	UserName  string `json:"username"`
+	UserScore int    `json:"userScore"`
+}
+
+// Stats counts things related to a user that are not part of the user itself.
+type Stats struct {
+	UserNameChanges int64       `json:"usernameChanges"`
+	Badges          BadgeCounts `json:"badges"`
+}
@@ -0,0 +1,17 @@
+package user
+
+import "time"
+
+// UserNameChange records a user giving up OldUserName for NewUserName. Old
+// names stay reserved for their previous owner, and links to them redirect,
+// for a while after the change.
+type UserNameChange struct {
+	ID          int       `json:"-"`
+	UserID      int       `gorm:"index" json:"userId"`
+	OldUserName string    `json:"oldUsername"`
+	NewUserName string    `json:"newUsername"`
+	CreatedAt   time.Time `json:"changedAt"`
+	// NormalizedOldUserName is the canonical form of OldUserName, which
+	// lookups of old names go through.
+	NormalizedOldUserName string `gorm:"size:191;index" json:"-"`
+}
@@ -0,0 +1,125 @@
+package user
+
+import "time"
+
+// PublicProfile is what anyone can see of a user. Email, Github, Linkedin
+// and WorkPlace are left out unless the user's Privacy settings show them,
+// and so are the profile fields admins made private.
+type PublicProfile struct {
+	ID         int
+	CreatedAt  time.Time
+	UserName   string
+	UserScore  int
+	Bio        string
+	Profession string
+	Email      string `json:",omitempty"`
+	Github     string `json:",omitempty"`
+	Linkedin   string `json:",omitempty"`
+	WorkPlace  string `json:",omitempty"`
+
+	FollowerCount  int
+	FollowingCount int
+	AvatarHash     string            `json:",omitempty"`
+	GithubVerified bool              `json:",omitempty"`
+	ProfileFields  map[string]string `json:",omitempty"`
+}
+
+// SelfProfile is what users see of themselves, including their privacy settings.
+type SelfProfile struct {
+	ID         int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserName   string
+	Email      string
+	Github     string
+	Linkedin   string
+	UserScore  int
+	Bio        string
+	Profession string
+	WorkPlace  string
+	Version    int
+	Privacy    Privacy
+
+	FollowerCount  int
+	FollowingCount int
+	AvatarHash     string            `json:",omitempty"`
+	GithubVerified bool              `json:",omitempty"`
+	ProfileFields  map[string]string `json:",omitempty"`
+}
+
+// AdminView is what admins see of a user: everything users see of
+// themselves, plus whether and when they were deleted.
+type AdminView struct {
+	SelfProfile
+	DeletedAt *time.Time `json:",omitempty"`
+	PurgedAt  *time.Time `json:",omitempty"`
+}
+
+// PublicProfile returns the public view of u.
+func (u User) PublicProfile() PublicProfile {
+	profile := PublicProfile{
+		ID:         u.ID,
+		CreatedAt:  u.CreatedAt,
+		UserName:   u.UserName,
+		UserScore:  u.UserScore,
+		Bio:        u.Bio,
+		Profession: u.Profession,
+
+		FollowerCount:  u.FollowerCount,
+		FollowingCount: u.FollowingCount,
+		AvatarHash:     u.AvatarHash,
+		ProfileFields:  u.profileFieldMap(false),
+	}
+	if u.Privacy.ShowEmail {
+		profile.Email = u.Email
+	}
+	if u.Privacy.ShowGithub {
+		profile.Github = u.Github
+		profile.GithubVerified = u.GithubVerified()
+	}
+	if u.Privacy.ShowLinkedin {
+		profile.Linkedin = u.Linkedin
+	}
+	if u.Privacy.ShowWorkPlace {
+		profile.WorkPlace = u.WorkPlace
+	}
+	return profile
+}
+
+// SelfProfile returns the view u has of themselves.
+func (u User) SelfProfile() SelfProfile {
+	return SelfProfile{
+		ID:         u.ID,
+		CreatedAt:  u.CreatedAt,
+		UpdatedAt:  u.UpdatedAt,
+		UserName:   u.UserName,
+		Email:      u.Email,
+		Github:     u.Github,
+		Linkedin:   u.Linkedin,
+		UserScore:  u.UserScore,
+		Bio:        u.Bio,
+		Profession: u.Profession,
+		WorkPlace:  u.WorkPlace,
+		Version:    u.Version,
+		Privacy:    u.Privacy,
+
+		FollowerCount:  u.FollowerCount,
+		FollowingCount: u.FollowingCount,
+		AvatarHash:     u.AvatarHash,
+		GithubVerified: u.GithubVerified(),
+		ProfileFields:  u.profileFieldMap(true),
+	}
+}
+
+// AdminView returns the admin view of u.
+func (u User) AdminView() AdminView {
+	view := AdminView{
+		SelfProfile: u.SelfProfile(),
+		PurgedAt:    u.PurgedAt,
+	}
+	if u.DeletedAt.Valid {
+		deletedAt := u.DeletedAt.Time
+		view.DeletedAt = &deletedAt
+	}
+	return view
+}
@@ -0,0 +1,185 @@
+// Package userclient lets other Nuboverflow services look users up through
+// the users API, batching lookups made close together into one request.
+package userclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by Loader.Load for an ID with no user, and by
+// HasPrivilege for a user or privilege that doesn't exist.
+var ErrNotFound = errors.New("userclient: user not found")
+
+// User is a user as returned by the users API.
+type User struct {
+	ID         int
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserName   string
+	Email      string
+	Github     string
+	Linkedin   string
+	UserScore  int
+	Bio        string
+	Profession string
+	WorkPlace  string
+	Version    int
+}
+
+// Batch is the result of looking up many users by ID. Users are in the order
+// their IDs were asked for; NotFound lists the IDs with no user.
+type Batch struct {
+	Users    []User `json:"users"`
+	NotFound []int  `json:"notFound"`
+}
+
+// MaxBatchSize is the most users the API looks up in one request.
+const MaxBatchSize = 100
+
+// BlockCheck asks whether the user with UserID is blocked by the one with
+// BlockedBy. CheckBlocks fills in Blocked.
+type BlockCheck struct {
+	UserID    int  `json:"userId"`
+	BlockedBy int  `json:"blockedBy"`
+	Blocked   bool `json:"blocked"`
+}
+
+// MaxBlockChecks is the most checks the API answers in one request.
+const MaxBlockChecks = 500
+
+// Client calls the users API at BaseURL, e.g. "http://users:3000".
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client for the users API at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// GetUsersByIDs looks up to MaxBatchSize users in one request.
+func (c *Client) GetUsersByIDs(ctx context.Context, ids []int) (Batch, error) {
+	body, err := json.Marshal(map[string][]int{"ids": ids})
+	if err != nil {
+		return Batch{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/v1/users/batch-get", bytes.NewReader(body))
+	if err != nil {
+		return Batch{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return Batch{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Batch{}, problemError("batch-get", resp)
+	}
+
+	var batch Batch
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return Batch{}, err
+	}
+	return batch, nil
+}
+
+// HasPrivilege reports whether the reputation of the user with id has
+// unlocked the privilege called name, such as "comment".
+func (c *Client) HasPrivilege(ctx context.Context, id int, name string) (bool, error) {
+	endpoint := fmt.Sprintf("%s/api/v1/users/%d/privileges/%s", c.BaseURL, id, url.PathEscape(name))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, problemError("privilege check", resp)
+	}
+
+	var check struct {
+		Granted bool `json:"granted"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&check); err != nil {
+		return false, err
+	}
+	return check.Granted, nil
+}
+
+// CheckBlocks answers up to MaxBlockChecks checks in one request, in order.
+// Blocks are private, so the request is made with the service role.
+func (c *Client) CheckBlocks(ctx context.Context, checks []BlockCheck) ([]BlockCheck, error) {
+	body, err := json.Marshal(map[string][]BlockCheck{"checks": checks})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/v1/blocks/check", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-Role", "service")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, problemError("block check", resp)
+	}
+
+	var answered struct {
+		Checks []BlockCheck `json:"checks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&answered); err != nil {
+		return nil, err
+	}
+	return answered.Checks, nil
+}
+
+// problemError turns the problem details response of a failed op into an
+// error, wrapping ErrNotFound for a 404.
+func problemError(op string, resp *http.Response) error {
+	var problem struct {
+		Detail string `json:"detail"`
+	}
+	json.NewDecoder(resp.Body).Decode(&problem)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, problem.Detail)
+	}
+	return fmt.Errorf("userclient: %s returned %s: %s", op, resp.Status, problem.Detail)
+}
+
+// Loader returns a Loader that fetches users through c.
+func (c *Client) Loader(opts ...LoaderOption) *Loader {
+	return NewLoader(func(ctx context.Context, ids []int) (map[int]User, error) {
+		batch, err := c.GetUsersByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		users := make(map[int]User, len(batch.Users))
+		for _, usr := range batch.Users {
+			users[usr.ID] = usr
+		}
+		return users, nil
+	}, opts...)
+}
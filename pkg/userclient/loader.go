@@ -0,0 +1,157 @@
+package userclient
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches the users with the given IDs, leaving out those that do not exist.
+type BatchFunc func(ctx context.Context, ids []int) (map[int]User, error)
+
+const defaultLoaderWait = 2 * time.Millisecond
+
+// Loader collects the IDs passed to Load over a short wait and fetches them
+// with a single BatchFunc call, in the style of DataLoader: code rendering a
+// thread can load each participant on its own and still make one request.
+// It is safe for concurrent use, and does not cache between batches.
+type Loader struct {
+	fetch    BatchFunc
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending *batch
+}
+
+// batch is a set of IDs waiting to be fetched together.
+type batch struct {
+	ctx   context.Context
+	ids   []int
+	seen  map[int]bool
+	once  sync.Once
+	done  chan struct{}
+	users map[int]User
+	err   error
+}
+
+// LoaderOption configures a Loader.
+type LoaderOption func(*Loader)
+
+// WithWait sets how long a Loader waits for more IDs after the first one of
+// a batch. The default is 2ms.
+func WithWait(wait time.Duration) LoaderOption {
+	return func(l *Loader) {
+		l.wait = wait
+	}
+}
+
+// WithMaxBatch sets the most IDs fetched together. A full batch is fetched
+// right away. The default is MaxBatchSize.
+func WithMaxBatch(maxBatch int) LoaderOption {
+	return func(l *Loader) {
+		l.maxBatch = maxBatch
+	}
+}
+
+// NewLoader returns a Loader fetching users with fetch.
+func NewLoader(fetch BatchFunc, opts ...LoaderOption) *Loader {
+	l := &Loader{
+		fetch:    fetch,
+		wait:     defaultLoaderWait,
+		maxBatch: MaxBatchSize,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Load returns the user with id, or ErrNotFound. A batch is fetched with the
+// context of the Load that started it.
+func (l *Loader) Load(ctx context.Context, id int) (User, error) {
+	b := l.add(ctx, id)
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return User{}, ctx.Err()
+	}
+	if b.err != nil {
+		return User{}, b.err
+	}
+	usr, ok := b.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return usr, nil
+}
+
+// LoadMany loads several users, in the order of ids. Users that do not exist are left out.
+func (l *Loader) LoadMany(ctx context.Context, ids []int) ([]User, error) {
+	type result struct {
+		usr User
+		err error
+	}
+	results := make([]result, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			usr, err := l.Load(ctx, id)
+			results[i] = result{usr, err}
+		}(i, id)
+	}
+	wg.Wait()
+
+	users := make([]User, 0, len(ids))
+	for _, r := range results {
+		switch r.err {
+		case nil:
+			users = append(users, r.usr)
+		case ErrNotFound:
+		default:
+			return nil, r.err
+		}
+	}
+	return users, nil
+}
+
+// add puts id in the pending batch, starting a new one if there is none.
+func (l *Loader) add(ctx context.Context, id int) *batch {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.pending
+	if b == nil {
+		b = &batch{
+			ctx:  ctx,
+			seen: map[int]bool{},
+			done: make(chan struct{}),
+		}
+		l.pending = b
+		time.AfterFunc(l.wait, func() { l.dispatch(b) })
+	}
+	if !b.seen[id] {
+		b.seen[id] = true
+		b.ids = append(b.ids, id)
+	}
+	if len(b.ids) >= l.maxBatch {
+		go l.dispatch(b)
+		l.pending = nil
+	}
+	return b
+}
+
+// dispatch fetches b. Both its timer and it filling up dispatch a batch,
+// but it is only fetched once.
+func (l *Loader) dispatch(b *batch) {
+	l.mu.Lock()
+	if l.pending == b {
+		l.pending = nil
+	}
+	l.mu.Unlock()
+	b.once.Do(func() {
+		b.users, b.err = l.fetch(b.ctx, b.ids)
+		close(b.done)
+	})
+}
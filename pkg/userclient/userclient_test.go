@@ -0,0 +1,141 @@
+package userclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoader(t *testing.T) {
+	t.Run("Tests loads made together are fetched in one batch", func(t *testing.T) {
+		var mu sync.Mutex
+		var batches [][]int
+		loader := NewLoader(func(ctx context.Context, ids []int) (map[int]User, error) {
+			mu.Lock()
+			batches = append(batches, ids)
+			mu.Unlock()
+			users := map[int]User{}
+			for _, id := range ids {
+				if id != 404 {
+					users[id] = User{ID: id}
+				}
+			}
+			return users, nil
+		}, WithWait(20*time.Millisecond))
+
+		users, err := loader.LoadMany(context.Background(), []int{3, 1, 404, 3, 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []User{{ID: 3}, {ID: 1}, {ID: 3}, {ID: 2}}, users)
+		assert.Len(t, batches, 1)
+		assert.ElementsMatch(t, []int{1, 2, 3, 404}, batches[0])
+
+		_, err = loader.Load(context.Background(), 404)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Tests full batches are fetched right away", func(t *testing.T) {
+		var mu sync.Mutex
+		var sizes []int
+		loader := NewLoader(func(ctx context.Context, ids []int) (map[int]User, error) {
+			mu.Lock()
+			sizes = append(sizes, len(ids))
+			mu.Unlock()
+			return map[int]User{}, nil
+		}, WithWait(time.Hour), WithMaxBatch(2))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, err := loader.LoadMany(ctx, []int{1, 2, 3, 4})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 2}, sizes)
+	})
+
+	t.Run("Tests fetch errors reach every load", func(t *testing.T) {
+		failure := errors.New("unavailable")
+		loader := NewLoader(func(ctx context.Context, ids []int) (map[int]User, error) {
+			return nil, failure
+		})
+		_, err := loader.LoadMany(context.Background(), []int{1, 2})
+		assert.Equal(t, failure, err)
+	})
+}
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/users/batch-get", r.URL.Path)
+		var body struct {
+			IDs []int `json:"ids"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"users":    []map[string]interface{}{{"ID": body.IDs[0], "UserName": "bob"}},
+			"notFound": body.IDs[1:],
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL + "/")
+	batch, err := client.GetUsersByIDs(context.Background(), []int{7, 8})
+	assert.NoError(t, err)
+	assert.Equal(t, []User{{ID: 7, UserName: "bob"}}, batch.Users)
+	assert.Equal(t, []int{8}, batch.NotFound)
+
+	usr, err := client.Loader().Load(context.Background(), 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "bob", usr.UserName)
+}
+
+func TestHasPrivilege(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/users/7/privileges/comment":
+			json.NewEncoder(w).Encode(map[string]interface{}{"granted": true})
+		case "/api/v1/users/7/privileges/edit":
+			json.NewEncoder(w).Encode(map[string]interface{}{"granted": false, "remaining": 1950})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{"detail": `privilege "fly" does not exist`})
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	granted, err := client.HasPrivilege(context.Background(), 7, "comment")
+	assert.NoError(t, err)
+	assert.True(t, granted)
+
+	granted, err = client.HasPrivilege(context.Background(), 7, "edit")
+	assert.NoError(t, err)
+	assert.False(t, granted)
+
+	_, err = client.HasPrivilege(context.Background(), 7, "fly")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCheckBlocks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/blocks/check", r.URL.Path)
+		assert.Equal(t, "service", r.Header.Get("X-User-Role"))
+		var body struct {
+			Checks []BlockCheck `json:"checks"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		for i := range body.Checks {
+			body.Checks[i].Blocked = body.Checks[i].BlockedBy == 9
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	checks, err := client.CheckBlocks(context.Background(), []BlockCheck{{UserID: 7, BlockedBy: 9}, {UserID: 7, BlockedBy: 8}})
+	assert.NoError(t, err)
+	assert.Equal(t, []BlockCheck{{UserID: 7, BlockedBy: 9, Blocked: true}, {UserID: 7, BlockedBy: 8}}, checks)
+}